@@ -0,0 +1,713 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"server/internal/activitypub"
+	"server/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxInboxBytes        = 1 << 20
+	apOutboxSize         = 20
+	apDeliveryAttempts   = 3
+	apDeliveryRetryDelay = 10 * time.Second
+	apDeliveryTimeout    = 30 * time.Second
+)
+
+// federating reports whether ActivityPub is enabled. Actor and object ids
+// must never change, so federation needs PUBLIC_URL rather than whatever
+// host a request happened to use.
+func (cfg *apiConfig) federating() bool {
+	return cfg.publicURL != ""
+}
+
+func (cfg *apiConfig) actorURI(userID uuid.UUID) string {
+	return cfg.publicURL + "/ap/users/" + userID.String()
+}
+
+func (cfg *apiConfig) noteURI(chirpID uuid.UUID) string {
+	return cfg.publicURL + "/ap/chirps/" + chirpID.String()
+}
+
+// allowedRemoteURL reports whether uri may be fetched or delivered to.
+// Plain HTTP is only allowed on the dev platform.
+func (cfg *apiConfig) allowedRemoteURL(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "https" || (u.Scheme == "http" && cfg.platform == "dev")
+}
+
+func respondWithActivity(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// actorSigner returns the key userID signs activities with, creating the
+// key pair the first time.
+func (cfg *apiConfig) actorSigner(ctx context.Context, userID uuid.UUID) (activitypub.Signer, string, error) {
+	key, err := cfg.dbQueries.GetActorKey(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		privatePEM, publicPEM, genErr := activitypub.GenerateKey()
+		if genErr != nil {
+			return activitypub.Signer{}, "", genErr
+		}
+		// Two requests may race to create the key; the first one wins and
+		// both use it.
+		err = cfg.dbQueries.CreateActorKey(ctx, database.CreateActorKeyParams{
+			UserID:        userID,
+			PublicKeyPem:  publicPEM,
+			PrivateKeyPem: privatePEM,
+		})
+		if err != nil {
+			return activitypub.Signer{}, "", err
+		}
+		key, err = cfg.dbQueries.GetActorKey(ctx, userID)
+	}
+	if err != nil {
+		return activitypub.Signer{}, "", err
+	}
+
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return activitypub.Signer{}, "", err
+	}
+	signer := activitypub.Signer{
+		KeyID: cfg.actorURI(userID) + "#main-key",
+		Key:   privateKey,
+	}
+	return signer, key.PublicKeyPem, nil
+}
+
+// federatedUser looks up the local user an ActivityPub path refers to.
+// Users without a handle cannot be addressed with WebFinger, so they are
+// not federated.
+func (cfg *apiConfig) federatedUser(ctx context.Context, id string) (database.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return database.User{}, sql.ErrNoRows
+	}
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return database.User{}, err
+	}
+	if !user.Handle.Valid {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// federatable reports whether chirp is shared with other servers. Only
+// public chirps are.
+func federatable(chirp database.Chirp) bool {
+	return chirp.Visibility == visibilityPublic && chirp.Status == chirpStatusPublished && !chirp.HiddenAt.Valid
+}
+
+func (cfg *apiConfig) note(chirp database.Chirp) activitypub.Note {
+	actor := cfg.actorURI(chirp.UserID)
+	note := activitypub.Note{
+		ID:           cfg.noteURI(chirp.ID),
+		Type:         "Note",
+		AttributedTo: actor,
+		Content:      activitypub.HTMLContent(chirp.Body),
+		Published:    chirp.CreatedAt.UTC(),
+		To:           []string{activitypub.Public},
+		Cc:           []string{actor + "/followers"},
+		URL:          cfg.publicURL + "/chirps/" + chirp.ID.String(),
+	}
+	if chirp.EditedAt.Valid {
+		updated := chirp.EditedAt.Time.UTC()
+		note.Updated = &updated
+	}
+	if chirp.InReplyTo.Valid {
+		note.InReplyTo = cfg.noteURI(chirp.InReplyTo.UUID)
+	}
+	return note
+}
+
+func (cfg *apiConfig) createActivity(chirp database.Chirp) (activitypub.Activity, error) {
+	note := cfg.note(chirp)
+	activity, err := activitypub.NewActivity(note.ID+"/activity", "Create", note.AttributedTo, note)
+	if err != nil {
+		return activitypub.Activity{}, err
+	}
+	activity.To = note.To
+	activity.Cc = note.Cc
+	activity.Published = &note.Published
+	return activity, nil
+}
+
+// federateChirp sends remote followers a Create or Delete for chirp,
+// depending on typ, in the background.
+func (cfg *apiConfig) federateChirp(typ string, chirp database.Chirp) {
+	if !cfg.federating() || !federatable(chirp) {
+		return
+	}
+
+	var activity activitypub.Activity
+	var err error
+	switch typ {
+	case chirpEventCreated:
+		activity, err = cfg.createActivity(chirp)
+	case chirpEventDeleted:
+		activity, err = activitypub.NewActivity(cfg.noteURI(chirp.ID)+"#delete", "Delete", cfg.actorURI(chirp.UserID),
+			activitypub.Tombstone{ID: cfg.noteURI(chirp.ID), Type: "Tombstone"})
+		activity.To = []string{activitypub.Public}
+	}
+	if err != nil {
+		fmt.Println("federating chirp:", err)
+		return
+	}
+
+	go cfg.deliverToFollowers(chirp.UserID, activity)
+}
+
+// federateChirpUpdate sends remote followers an Update carrying chirp's
+// current body, in the background.
+func (cfg *apiConfig) federateChirpUpdate(chirp database.Chirp) {
+	if !cfg.federating() || !federatable(chirp) {
+		return
+	}
+
+	note := cfg.note(chirp)
+	id := fmt.Sprintf("%s#update-%d", note.ID, chirp.UpdatedAt.Unix())
+	activity, err := activitypub.NewActivity(id, "Update", note.AttributedTo, note)
+	if err != nil {
+		fmt.Println("federating chirp:", err)
+		return
+	}
+	activity.To = note.To
+	activity.Cc = note.Cc
+
+	go cfg.deliverToFollowers(chirp.UserID, activity)
+}
+
+func (cfg *apiConfig) deliverToFollowers(userID uuid.UUID, activity activitypub.Activity) {
+	ctx := context.Background()
+	signer, _, err := cfg.actorSigner(ctx, userID)
+	if err != nil {
+		fmt.Println("delivering activity:", err)
+		return
+	}
+	inboxes, err := cfg.dbQueries.GetRemoteFollowerInboxes(ctx, userID)
+	if err != nil {
+		fmt.Println("delivering activity:", err)
+		return
+	}
+	for _, inbox := range inboxes {
+		go cfg.deliver(signer, inbox, activity)
+	}
+}
+
+// deliver posts activity to one inbox, retrying a few times. Deliveries
+// are best effort: an inbox that stays unreachable misses the activity.
+func (cfg *apiConfig) deliver(signer activitypub.Signer, inbox string, activity activitypub.Activity) {
+	if !cfg.allowedRemoteURL(inbox) {
+		return
+	}
+	var err error
+	for attempt := 1; attempt <= apDeliveryAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), apDeliveryTimeout)
+		err = cfg.apClient.Deliver(ctx, inbox, activity, signer)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt < apDeliveryAttempts {
+			time.Sleep(time.Duration(attempt) * apDeliveryRetryDelay)
+		}
+	}
+	fmt.Println("delivering activity:", err)
+}
+
+// remoteActor returns the sender of a signed request, fetching and caching
+// their actor document when the key is new to us or refresh is set.
+func (cfg *apiConfig) remoteActor(ctx context.Context, keyID string, refresh bool) (database.RemoteActor, error) {
+	if !refresh {
+		actor, err := cfg.dbQueries.GetRemoteActorByKeyID(ctx, keyID)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return actor, err
+		}
+	}
+
+	actorURI, _, _ := strings.Cut(keyID, "#")
+	if !cfg.allowedRemoteURL(actorURI) {
+		return database.RemoteActor{}, errors.New("key is not on an allowed URL")
+	}
+	fetched, err := cfg.apClient.FetchActor(ctx, actorURI, nil)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+	// The actor must vouch for the key and live on the same host, or any
+	// server could claim another's actor.
+	if fetched.PublicKey.ID != keyID || fetched.PublicKey.Owner != fetched.ID {
+		return database.RemoteActor{}, errors.New("actor does not own the key")
+	}
+	keyURL, _ := url.Parse(keyID)
+	actorURL, err := url.Parse(fetched.ID)
+	if err != nil || actorURL.Host != keyURL.Host {
+		return database.RemoteActor{}, errors.New("actor and key are on different hosts")
+	}
+
+	return cfg.dbQueries.UpsertRemoteActor(ctx, database.UpsertRemoteActorParams{
+		Uri:               fetched.ID,
+		KeyID:             keyID,
+		Inbox:             fetched.Inbox,
+		SharedInbox:       fetched.SharedInbox(),
+		PreferredUsername: fetched.PreferredUsername,
+		PublicKeyPem:      fetched.PublicKey.PublicKeyPem,
+	})
+}
+
+// verifyInboxRequest returns the remote actor that signed r. A signature
+// that fails against a cached key is checked again after refetching the
+// actor, in case they rotated their key.
+func (cfg *apiConfig) verifyInboxRequest(r *http.Request, body []byte) (database.RemoteActor, error) {
+	sig, err := activitypub.ParseSignature(r)
+	if err != nil {
+		return database.RemoteActor{}, err
+	}
+
+	for _, refresh := range []bool{false, true} {
+		actor, err := cfg.remoteActor(r.Context(), sig.KeyID, refresh)
+		if err != nil {
+			return database.RemoteActor{}, err
+		}
+		key, err := activitypub.ParsePublicKey(actor.PublicKeyPem)
+		if err != nil {
+			return database.RemoteActor{}, err
+		}
+		err = activitypub.VerifyRequest(r, body, key, time.Now())
+		if err == nil {
+			return actor, nil
+		}
+		if !errors.Is(err, activitypub.ErrBadSignature) {
+			return database.RemoteActor{}, err
+		}
+	}
+	return database.RemoteActor{}, activitypub.ErrBadSignature
+}
+
+func (cfg *apiConfig) handlerWebFinger(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	if !cfg.federating() {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	handle, host, err := activitypub.ParseAcct(r.URL.Query().Get("resource"))
+	if err != nil {
+		msg = "Invalid resource"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	public, _ := url.Parse(cfg.publicURL)
+	if !strings.EqualFold(host, public.Host) || !validHandle(handle) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := activitypub.JRD{
+		Subject: "acct:" + user.Handle.String + "@" + public.Host,
+		Aliases: []string{cfg.actorURI(user.ID)},
+		Links: []activitypub.JRDLink{{
+			Rel:  "self",
+			Type: activitypub.ContentType,
+			Href: cfg.actorURI(user.ID),
+		}},
+	}
+
+	data, _ := json.Marshal(respBody)
+	w.Header().Set("Content-Type", "application/jrd+json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func (cfg *apiConfig) handlerGetActor(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	if !cfg.federating() {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.federatedUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	signer, publicPEM, err := cfg.actorSigner(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	id := cfg.actorURI(user.ID)
+	actor := activitypub.Actor{
+		Context:                   activitypub.Context,
+		ID:                        id,
+		Type:                      "Person",
+		PreferredUsername:         user.Handle.String,
+		Name:                      displayName(user),
+		Summary:                   activitypub.HTMLContent(user.Bio),
+		URL:                       cfg.publicURL + profilePath(user),
+		Inbox:                     id + "/inbox",
+		Outbox:                    id + "/outbox",
+		Followers:                 id + "/followers",
+		ManuallyApprovesFollowers: user.IsPrivate,
+		PublicKey: activitypub.PublicKey{
+			ID:           signer.KeyID,
+			Owner:        id,
+			PublicKeyPem: publicPEM,
+		},
+	}
+	if user.AvatarUrl != "" {
+		actor.Icon = &activitypub.Image{Type: "Image", URL: user.AvatarUrl}
+	}
+
+	respondWithActivity(w, code, actor)
+}
+
+// handlerGetOutbox lists a user's latest public chirps as Create
+// activities.
+func (cfg *apiConfig) handlerGetOutbox(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	if !cfg.federating() {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.federatedUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetPublicUserChirps(r.Context(), database.GetPublicUserChirpsParams{
+		UserID:    user.ID,
+		PageLimit: apOutboxSize,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	total, err := cfg.dbQueries.CountUserChirps(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	outbox := activitypub.OrderedCollection{
+		Context:      activitypub.ActivityStreamsContext,
+		ID:           cfg.actorURI(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   total,
+		OrderedItems: []any{},
+	}
+	for _, chirp := range chirps {
+		activity, err := cfg.createActivity(chirp)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		activity.Context = nil
+		outbox.OrderedItems = append(outbox.OrderedItems, activity)
+	}
+
+	respondWithActivity(w, code, outbox)
+}
+
+// handlerGetFollowersCollection only reports how many followers a user
+// has, on this server and elsewhere; the followers themselves are not
+// listed.
+func (cfg *apiConfig) handlerGetFollowersCollection(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	if !cfg.federating() {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.federatedUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	local, err := cfg.dbQueries.CountFollowers(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	remote, err := cfg.dbQueries.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithActivity(w, code, activitypub.OrderedCollection{
+		Context:    activitypub.ActivityStreamsContext,
+		ID:         cfg.actorURI(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: local + remote,
+	})
+}
+
+func (cfg *apiConfig) handlerGetNote(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	if !cfg.federating() {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), uuid.Nil, chirpID)
+	if err == nil && !federatable(chirp) {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	note := cfg.note(chirp)
+	note.Context = activitypub.ActivityStreamsContext
+	respondWithActivity(w, code, note)
+}
+
+// handlerInbox receives activities for a user from other servers. Every
+// request must carry an HTTP Signature from the actor it claims to come
+// from. Follows are accepted straight away, except for private accounts,
+// which turn them down; Undo of a Follow and Delete of the actor itself
+// are honoured, and everything else is accepted and ignored.
+func (cfg *apiConfig) handlerInbox(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 202
+
+	if !cfg.federating() {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.federatedUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInboxBytes))
+	if err != nil {
+		msg = "Request body too large"
+		code = 413
+		respondWithError(w, code, msg)
+		return
+	}
+
+	remote, err := cfg.verifyInboxRequest(r, body)
+	if err != nil {
+		msg = "Invalid signature"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	activity := activitypub.Activity{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		msg = "Invalid activity"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	if activity.Actor != remote.Uri {
+		msg = "Activity was not signed by its actor"
+		code = 403
+		respondWithError(w, code, msg)
+		return
+	}
+
+	actorURI := cfg.actorURI(user.ID)
+
+	switch activity.Type {
+	case "Follow":
+		if activity.ObjectID() != actorURI {
+			break
+		}
+		reply := "Accept"
+		if user.IsPrivate {
+			reply = "Reject"
+		} else {
+			err = cfg.dbQueries.AddRemoteFollow(r.Context(), database.AddRemoteFollowParams{
+				RemoteActorID: remote.ID,
+				UserID:        user.ID,
+				ActivityID:    activity.ID,
+			})
+			if err != nil {
+				msg = "Something went wrong"
+				code = 500
+				respondWithError(w, code, msg)
+				return
+			}
+		}
+		response, err := activitypub.NewActivity(actorURI+"#"+strings.ToLower(reply)+"s/"+uuid.New().String(), reply, actorURI, activity)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		signer, _, err := cfg.actorSigner(r.Context(), user.ID)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		go cfg.deliver(signer, remote.Inbox, response)
+	case "Undo":
+		inner, err := activity.ObjectActivity()
+		if err != nil || inner.Type != "Follow" || inner.Actor != remote.Uri || inner.ObjectID() != actorURI {
+			break
+		}
+		_, err = cfg.dbQueries.RemoveRemoteFollow(r.Context(), database.RemoveRemoteFollowParams{
+			RemoteActorID: remote.ID,
+			UserID:        user.ID,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+	case "Delete":
+		// Remote accounts that are deleted take their follows with them.
+		if activity.ObjectID() != remote.Uri {
+			break
+		}
+		_, err = cfg.dbQueries.DeleteRemoteActor(r.Context(), remote.Uri)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	w.WriteHeader(code)
+}
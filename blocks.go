@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"server/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// mentionPattern matches @handle mentions. The handle part follows the same
+// rules as validHandle.
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_@])@([A-Za-z0-9_]{3,30})\b`)
+
+// mentionedHandles returns the distinct handles mentioned in body, in the
+// order they first appear.
+func mentionedHandles(body string) []string {
+	seen := map[string]bool{}
+	handles := []string{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			handles = append(handles, m[1])
+		}
+	}
+	return handles
+}
+
+// isBlocked reports whether either user has blocked the other. Anonymous
+// viewers are never blocked.
+func (cfg *apiConfig) isBlocked(ctx context.Context, a, b uuid.UUID) (bool, error) {
+	if a == uuid.Nil || b == uuid.Nil || a == b {
+		return false, nil
+	}
+	return cfg.dbQueries.IsBlockedEitherWay(ctx, database.IsBlockedEitherWayParams{
+		UserA: a,
+		UserB: b,
+	})
+}
+
+// checkMentions refuses a chirp body that mentions someone the author has
+// blocked or been blocked by. Handles that do not exist are ignored.
+func (cfg *apiConfig) checkMentions(ctx context.Context, authorID uuid.UUID, body string) error {
+	for _, handle := range mentionedHandles(body) {
+		user, err := cfg.dbQueries.GetUserByHandle(ctx, handle)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		blocked, err := cfg.isBlocked(ctx, authorID, user.ID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return &requestError{403, "You cannot mention @" + handle}
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerBlockUser(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	target, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if target.ID == userID {
+		msg = "You cannot block yourself"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Blocking ends any follow or follow request between the two users.
+	// handlerFollowUser locks the same pair, so a follow cannot be added
+	// after the old ones are removed.
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		_, err := q.LockUsers(r.Context(), []uuid.UUID{userID, target.ID})
+		if err != nil {
+			return err
+		}
+		_, err = q.BlockUser(r.Context(), database.BlockUserParams{
+			BlockerID: userID,
+			BlockedID: target.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return q.RemoveFollowsBetween(r.Context(), database.RemoveFollowsBetweenParams{
+			UserA: userID,
+			UserB: target.ID,
+		})
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	target, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerID: userID,
+		BlockedID: target.ID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerGetBlocks(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	users, err := cfg.dbQueries.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{
+		UserID:     userID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnPublicUser{}
+	for _, u := range users {
+		respBody = append(respBody, publicUser(u))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+// handlerMuteUser mutes a user, for duration_hours if given and otherwise
+// until they are unmuted. Muting again replaces the previous duration.
+func (cfg *apiConfig) handlerMuteUser(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		DurationHours int `json:"duration_hours"`
+	}
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// The body is optional; without one the mute never expires.
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	if params.DurationHours < 0 {
+		msg = "duration_hours must be positive"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	target, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if target.ID == userID {
+		msg = "You cannot mute yourself"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.DurationHours > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().UTC().Add(time.Duration(params.DurationHours) * time.Hour), Valid: true}
+	}
+
+	mute, err := cfg.dbQueries.MuteUser(r.Context(), database.MuteUserParams{
+		MuterID:   userID,
+		MutedID:   target.ID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, muteResponse(target, mute.ExpiresAt))
+}
+
+func (cfg *apiConfig) handlerUnmuteUser(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	target, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.UnmuteUser(r.Context(), database.UnmuteUserParams{
+		MuterID: userID,
+		MutedID: target.ID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerGetMutes(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	rows, err := cfg.dbQueries.GetMutes(r.Context(), database.GetMutesParams{
+		UserID:     userID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnMute{}
+	for _, row := range rows {
+		respBody = append(respBody, muteResponse(row.User, row.ExpiresAt))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func muteResponse(user database.User, expiresAt sql.NullTime) returnMute {
+	respMute := returnMute{
+		User: publicUser(user),
+	}
+	if expiresAt.Valid {
+		respMute.ExpiresAt = &expiresAt.Time
+	}
+	return respMute
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"server/internal/database"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func blockUser(t *testing.T, cfg *apiConfig, blocker, blocked uuid.UUID) {
+	t.Helper()
+	r := testRequest(t, cfg, "POST", "/api/users/"+blocked.String()+"/block", blocker, nil)
+	r.SetPathValue("id", blocked.String())
+	serve(t, cfg.handlerBlockUser, r, 204)
+}
+
+func followRequest(t *testing.T, cfg *apiConfig, follower, followee uuid.UUID) *httptest.ResponseRecorder {
+	t.Helper()
+	r := testRequest(t, cfg, "POST", "/api/users/"+followee.String()+"/follow", follower, nil)
+	r.SetPathValue("id", followee.String())
+	w := httptest.NewRecorder()
+	cfg.handlerFollowUser(w, r)
+	return w
+}
+
+func TestFollowingAcrossBlockIsRefused(t *testing.T) {
+	cfg := newTestConfig(t)
+	blocker := createTestUser(t, cfg)
+	blocked := createTestUser(t, cfg)
+	blockUser(t, cfg, blocker.ID, blocked.ID)
+
+	if w := followRequest(t, cfg, blocked.ID, blocker.ID); w.Code != 403 {
+		t.Errorf("blocked user following: got status %d, want 403", w.Code)
+	}
+	if w := followRequest(t, cfg, blocker.ID, blocked.ID); w.Code != 403 {
+		t.Errorf("blocker following: got status %d, want 403", w.Code)
+	}
+}
+
+func TestBlockingRemovesFollows(t *testing.T) {
+	cfg := newTestConfig(t)
+	blocker := createTestUser(t, cfg)
+	follower := createTestUser(t, cfg)
+	if w := followRequest(t, cfg, follower.ID, blocker.ID); w.Code != 200 {
+		t.Fatalf("following: got status %d, want 200", w.Code)
+	}
+
+	blockUser(t, cfg, blocker.ID, follower.ID)
+
+	following, err := cfg.dbQueries.IsFollowing(context.Background(), database.IsFollowingParams{
+		FollowerID: follower.ID,
+		FolloweeID: blocker.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if following {
+		t.Error("follow survived the block")
+	}
+}
+
+func TestFollowRacingBlockDoesNotSurvive(t *testing.T) {
+	cfg := newTestConfig(t)
+	ctx := context.Background()
+
+	for i := 0; i < 20; i++ {
+		blocker := createTestUser(t, cfg)
+		follower := createTestUser(t, cfg)
+
+		follow := testRequest(t, cfg, "POST", "/api/users/"+blocker.ID.String()+"/follow", follower.ID, nil)
+		follow.SetPathValue("id", blocker.ID.String())
+		block := testRequest(t, cfg, "POST", "/api/users/"+follower.ID.String()+"/block", blocker.ID, nil)
+		block.SetPathValue("id", follower.ID.String())
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cfg.handlerFollowUser(httptest.NewRecorder(), follow)
+		}()
+		go func() {
+			defer wg.Done()
+			cfg.handlerBlockUser(httptest.NewRecorder(), block)
+		}()
+		wg.Wait()
+
+		following, err := cfg.dbQueries.IsFollowing(ctx, database.IsFollowingParams{
+			FollowerID: follower.ID,
+			FolloweeID: blocker.ID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if following {
+			t.Fatal("follow made during a block survived it")
+		}
+	}
+}
+
+func TestUserLikesHiddenAcrossBlock(t *testing.T) {
+	cfg := newTestConfig(t)
+	liker := createTestUser(t, cfg)
+	blocker := createTestUser(t, cfg)
+	reader := createTestUser(t, cfg)
+	blockUser(t, cfg, blocker.ID, liker.ID)
+
+	listLikes := func(viewer uuid.UUID, want int) {
+		t.Helper()
+		r := testRequest(t, cfg, "GET", "/api/users/"+liker.ID.String()+"/likes", viewer, nil)
+		r.SetPathValue("id", liker.ID.String())
+		serve(t, cfg.handlerGetUserLikes, r, want)
+	}
+	listLikes(blocker.ID, 404)
+	listLikes(reader.ID, 200)
+
+	// The liker cannot see the likes of the user who blocked them either.
+	r := testRequest(t, cfg, "GET", "/api/users/"+blocker.ID.String()+"/likes", liker.ID, nil)
+	r.SetPathValue("id", blocker.ID.String())
+	serve(t, cfg.handlerGetUserLikes, r, 404)
+}
+
+func muteUser(t *testing.T, cfg *apiConfig, muter, muted uuid.UUID, durationHours int) {
+	t.Helper()
+	r := testRequest(t, cfg, "POST", "/api/users/"+muted.String()+"/mute", muter, map[string]any{"duration_hours": durationHours})
+	r.SetPathValue("id", muted.String())
+	serve(t, cfg.handlerMuteUser, r, 200)
+}
+
+func TestBlockedUserCannotSeeOrReply(t *testing.T) {
+	cfg := newTestConfig(t)
+	blocker := createTestUser(t, cfg)
+	blocked := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, blocker.ID, chirpInput{})
+	blockUser(t, cfg, blocker.ID, blocked.ID)
+
+	r := testRequest(t, cfg, "GET", "/api/chirps/"+chirp.ID.String(), blocked.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerGetChirp, r, 404)
+	if listAuthorChirps(t, cfg, blocked.ID, blocker.ID)[chirp.ID] {
+		t.Error("blocker's chirp listed for the blocked user")
+	}
+
+	_, err := cfg.createChirp(context.Background(), blocked.ID, chirpInput{Body: "hey", InReplyTo: &chirp.ID})
+	if requestErrorCode(err) != 404 {
+		t.Errorf("replying across a block: got %v, want a 404", err)
+	}
+}
+
+func TestMutingHidesChirpsFromListingsOnly(t *testing.T) {
+	cfg := newTestConfig(t)
+	muter := createTestUser(t, cfg)
+	muted := createTestUser(t, cfg)
+	reader := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, muted.ID, chirpInput{})
+	muteUser(t, cfg, muter.ID, muted.ID, 0)
+
+	if listChirps(t, cfg, muter.ID, "")[chirp.ID] {
+		t.Error("muted user's chirp listed for the muter")
+	}
+	if !listChirps(t, cfg, reader.ID, "")[chirp.ID] {
+		t.Error("mute hid the chirp from someone else")
+	}
+	// Unlike a block, a mute does not stop the muter opening the chirp.
+	if got := getChirp(t, cfg, muter.ID, chirp.ID); got.ID != chirp.ID {
+		t.Errorf("got %v, want %v", got.ID, chirp.ID)
+	}
+}
+
+func TestTimedMuteExpires(t *testing.T) {
+	cfg := newTestConfig(t)
+	muter := createTestUser(t, cfg)
+	muted := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, muted.ID, chirpInput{})
+	muteUser(t, cfg, muter.ID, muted.ID, 1)
+
+	if listChirps(t, cfg, muter.ID, "")[chirp.ID] {
+		t.Fatal("muted user's chirp listed during the mute")
+	}
+
+	_, err := cfg.db.Exec("UPDATE mutes SET expires_at = NOW() - INTERVAL '1 second' WHERE muter_id = $1", muter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !listChirps(t, cfg, muter.ID, "")[chirp.ID] {
+		t.Error("chirp still hidden after the mute expired")
+	}
+}
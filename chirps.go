@@ -1,19 +1,456 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"server/internal/auth"
 	"server/internal/database"
+	"server/internal/moderation"
 	"sort"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	chirpStatusPublished = "published"
+	// chirpStatusHeld chirps are waiting for a moderator and only their
+	// author can see them.
+	chirpStatusHeld = "held"
+	// chirpStatusScheduled chirps are published by the scheduler once
+	// their publish_at time arrives.
+	chirpStatusScheduled = "scheduled"
+)
+
+const (
+	visibilityPublic = "public"
+	// visibilityFollowers chirps are only shown to the author's followers.
+	visibilityFollowers = "followers"
+	// visibilityUnlisted chirps can be seen by anyone with the link but
+	// are left out of listings.
+	visibilityUnlisted = "unlisted"
+)
+
+var chirpVisibilities = map[string]bool{
+	visibilityPublic:    true,
+	visibilityFollowers: true,
+	visibilityUnlisted:  true,
+}
+
+// moderationOutcome maps a moderation verdict onto the status and reasons
+// stored with a chirp.
+func moderationOutcome(verdict moderation.Verdict) (string, []string) {
+	status := chirpStatusPublished
+	if verdict.Action == moderation.Hold {
+		status = chirpStatusHeld
+	}
+	return status, append([]string{}, verdict.Reasons...)
+}
+
+// canViewChirp reports whether viewer (uuid.Nil when anonymous) may see
+// chirp. Authors can always see their own chirps, even held or hidden ones,
+// and moderators can see hidden chirps. Nobody sees the chirps of a user
+// they have blocked or been blocked by. Listing queries apply the same
+// rules in SQL and also leave out unlisted chirps.
+func (cfg *apiConfig) canViewChirp(ctx context.Context, viewer uuid.UUID, chirp database.Chirp) (bool, error) {
+	if chirp.TombstonedAt.Valid || chirp.DeletedAt.Valid {
+		return false, nil
+	}
+	if chirp.UserID == viewer {
+		return true, nil
+	}
+	blocked, err := cfg.isBlocked(ctx, viewer, chirp.UserID)
+	if err != nil || blocked {
+		return false, err
+	}
+	if chirp.Status != chirpStatusPublished {
+		return false, nil
+	}
+	if chirp.Visibility == visibilityFollowers {
+		if viewer == uuid.Nil {
+			return false, nil
+		}
+		following, err := cfg.dbQueries.IsFollowing(ctx, database.IsFollowingParams{
+			FollowerID: viewer,
+			FolloweeID: chirp.UserID,
+		})
+		if err != nil || !following {
+			return false, err
+		}
+	}
+	if chirp.HiddenAt.Valid {
+		return cfg.isModerator(ctx, viewer)
+	}
+	return true, nil
+}
+
+// getVisibleChirp fetches a chirp, reporting sql.ErrNoRows both when it does
+// not exist and when viewer may not see it.
+func (cfg *apiConfig) getVisibleChirp(ctx context.Context, viewer, chirpID uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.dbQueries.GetChirp(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	ok, err := cfg.canViewChirp(ctx, viewer, chirp)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if !ok {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return chirp, nil
+}
+
+func chirpResponse(chirp database.Chirp) returnChirp {
+	if chirp.TombstonedAt.Valid {
+		return tombstoneResponse(chirp)
+	}
+	respChirp := returnChirp{
+		ID:             chirp.ID,
+		CreatedAt:      chirp.CreatedAt,
+		UpdatedAt:      chirp.UpdatedAt,
+		Body:           chirp.Body,
+		UserID:         chirp.UserID,
+		ConversationID: chirp.ConversationID,
+		Tombstone:      chirp.TombstonedAt.Valid,
+		LikeCount:      chirp.LikeCount,
+		RechirpCount:   chirp.RechirpCount,
+		Status:         chirp.Status,
+		Visibility:     chirp.Visibility,
+		Hidden:         chirp.HiddenAt.Valid,
+	}
+	if chirp.InReplyTo.Valid {
+		respChirp.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	if chirp.QuoteOf.Valid {
+		respChirp.QuoteOf = &chirp.QuoteOf.UUID
+	}
+	if chirp.EditedAt.Valid {
+		respChirp.Edited = true
+		respChirp.EditedAt = &chirp.EditedAt.Time
+	}
+	if chirp.PublishAt.Valid {
+		respChirp.PublishAt = &chirp.PublishAt.Time
+	}
+	return respChirp
+}
+
+// tombstoneResponse describes a chirp's place in a thread without giving
+// away anything it said.
+func tombstoneResponse(chirp database.Chirp) returnChirp {
+	respChirp := returnChirp{
+		ID:             chirp.ID,
+		CreatedAt:      chirp.CreatedAt,
+		UpdatedAt:      chirp.UpdatedAt,
+		UserID:         chirp.UserID,
+		Status:         chirpStatusPublished,
+		ConversationID: chirp.ConversationID,
+		Tombstone:      true,
+	}
+	if chirp.InReplyTo.Valid {
+		respChirp.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	return respChirp
+}
+
+// attachMediaResponses fills in the media of each chirp and of any chirp it
+// quotes, with a single query for the whole batch.
+func attachMediaResponses(ctx context.Context, q *database.Queries, respChirps []returnChirp) error {
+	ids := []uuid.UUID{}
+	for _, respChirp := range respChirps {
+		if !respChirp.Tombstone {
+			ids = append(ids, respChirp.ID)
+		}
+		if respChirp.QuotedChirp != nil {
+			ids = append(ids, respChirp.QuotedChirp.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	files, err := q.GetChirpsMedia(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	mediaByChirp := map[uuid.UUID][]returnMedia{}
+	for _, file := range files {
+		mediaByChirp[file.ChirpID.UUID] = append(mediaByChirp[file.ChirpID.UUID], mediaResponse(file))
+	}
+
+	for i := range respChirps {
+		if !respChirps[i].Tombstone {
+			respChirps[i].Media = mediaByChirp[respChirps[i].ID]
+		}
+		if respChirps[i].QuotedChirp != nil {
+			respChirps[i].QuotedChirp.Media = mediaByChirp[respChirps[i].QuotedChirp.ID]
+		}
+	}
+	return nil
+}
+
+// chirpResponses converts chirps for a particular viewer, embedding quoted
+// chirps and filling in the per-viewer fields with one query per batch
+// rather than one per chirp. Anonymous viewers (uuid.Nil) get the
+// per-viewer fields left out.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, viewer uuid.UUID, chirps []database.Chirp) ([]returnChirp, error) {
+	return cfg.chirpResponsesWith(ctx, cfg.dbQueries, viewer, chirps)
+}
+
+// chirpResponsesWith is chirpResponses reading the chirps' quotes, media
+// and per-viewer fields through q, so a transaction can describe chirps it
+// has not committed yet.
+func (cfg *apiConfig) chirpResponsesWith(ctx context.Context, q *database.Queries, viewer uuid.UUID, chirps []database.Chirp) ([]returnChirp, error) {
+	respChirps := make([]returnChirp, 0, len(chirps))
+	ids := make([]uuid.UUID, 0, len(chirps))
+	quotedIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		respChirps = append(respChirps, chirpResponse(chirp))
+		ids = append(ids, chirp.ID)
+		if chirp.QuoteOf.Valid {
+			quotedIDs = append(quotedIDs, chirp.QuoteOf.UUID)
+		}
+	}
+
+	if len(quotedIDs) > 0 {
+		quoted, err := q.GetChirpsByIDs(ctx, quotedIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		quotedByID := map[uuid.UUID]database.Chirp{}
+		for _, chirp := range quoted {
+			quotedByID[chirp.ID] = chirp
+		}
+
+		for i := range respChirps {
+			if respChirps[i].QuoteOf == nil {
+				continue
+			}
+			original, ok := quotedByID[*respChirps[i].QuoteOf]
+			if ok {
+				ok, err = cfg.canViewChirp(ctx, viewer, original)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if !ok {
+				respChirps[i].QuoteUnavailable = true
+				continue
+			}
+			quotedChirp := chirpResponse(original)
+			respChirps[i].QuotedChirp = &quotedChirp
+		}
+	}
+
+	if err := attachMediaResponses(ctx, q, respChirps); err != nil {
+		return nil, err
+	}
+
+	if viewer == uuid.Nil || len(chirps) == 0 {
+		return respChirps, nil
+	}
+
+	liked, err := q.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+		UserID:   viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rechirped, err := q.GetRechirpedChirpIDs(ctx, database.GetRechirpedChirpIDsParams{
+		UserID:   viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	likedSet := map[uuid.UUID]bool{}
+	for _, id := range liked {
+		likedSet[id] = true
+	}
+
+	rechirpedSet := map[uuid.UUID]bool{}
+	for _, id := range rechirped {
+		rechirpedSet[id] = true
+	}
+
+	for i := range respChirps {
+		likedByMe := likedSet[respChirps[i].ID]
+		respChirps[i].LikedByMe = &likedByMe
+		rechirpedByMe := rechirpedSet[respChirps[i].ID]
+		respChirps[i].RechirpedByMe = &rechirpedByMe
+	}
+
+	return respChirps, nil
+}
+
+// chirpInput is a chirp about to be created, either straight from a
+// request or from a draft.
+type chirpInput struct {
+	Body       string
+	InReplyTo  *uuid.UUID
+	QuoteOf    *uuid.UUID
+	MediaIDs   []uuid.UUID
+	PublishAt  *time.Time
+	Visibility string
+}
+
+// createChirp validates, moderates and stores a new chirp for userID.
+// Problems with the input are reported as a requestError.
+func (cfg *apiConfig) createChirp(ctx context.Context, userID uuid.UUID, in chirpInput) (database.Chirp, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return database.Chirp{}, &requestError{401, "Unauthorized"}
+	}
+
+	if isSuspended(user) {
+		return database.Chirp{}, &requestError{403, "Account is suspended"}
+	}
+
+	limits := cfg.limitsFor(user)
+	if chirpTooLong(in.Body, limits) {
+		return database.Chirp{}, &requestError{400, "Chirp is too long"}
+	}
+
+	if len(in.MediaIDs) > limits.MaxMedia {
+		return database.Chirp{}, &requestError{400, fmt.Sprintf("A chirp can have at most %d attachments", limits.MaxMedia)}
+	}
+
+	// The quota is checked before the insert rather than in it, so
+	// concurrent requests can each slip in under it. A chirp or two over
+	// the quota is accepted in exchange for not serialising every post.
+	if limits.DailyChirps > 0 {
+		used, err := cfg.chirpsToday(ctx, userID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if used >= int64(limits.DailyChirps) {
+			return database.Chirp{}, &requestError{429, "Daily chirp limit reached"}
+		}
+	}
+
+	if in.Visibility == "" {
+		in.Visibility = visibilityPublic
+	}
+	if !chirpVisibilities[in.Visibility] {
+		return database.Chirp{}, &requestError{400, "Visibility must be public, followers or unlisted"}
+	}
+
+	if in.PublishAt != nil && !in.PublishAt.After(time.Now()) {
+		return database.Chirp{}, &requestError{400, "publish_at must be in the future"}
+	}
+
+	verdict := cfg.moderator.Check(in.Body)
+	if verdict.Action == moderation.Reject {
+		return database.Chirp{}, &requestError{400, "Chirp was rejected by moderation"}
+	}
+
+	err = cfg.checkMentions(ctx, userID, verdict.Body)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	status, reasons := moderationOutcome(verdict)
+	args := database.CreateChirpParams{
+		Body:              verdict.Body,
+		UserID:            userID,
+		Status:            status,
+		ModerationReasons: reasons,
+		Visibility:        in.Visibility,
+	}
+
+	if in.PublishAt != nil {
+		args.PublishAt = sql.NullTime{Time: in.PublishAt.UTC(), Valid: true}
+		if status == chirpStatusPublished {
+			args.Status = chirpStatusScheduled
+		}
+	}
+
+	if in.InReplyTo != nil {
+		parent, err := cfg.getVisibleChirp(ctx, userID, *in.InReplyTo)
+		if err != nil || parent.Status != chirpStatusPublished {
+			return database.Chirp{}, &requestError{404, "Chirp being replied to does not exist"}
+		}
+		args.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	if in.QuoteOf != nil {
+		quoted, err := cfg.getVisibleChirp(ctx, userID, *in.QuoteOf)
+		if err != nil || quoted.Status != chirpStatusPublished {
+			return database.Chirp{}, &requestError{404, "Chirp being quoted does not exist"}
+		}
+		args.QuoteOf = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
+	if len(in.MediaIDs) > 0 {
+		ok, err := cfg.mediaAttachable(ctx, userID, in.MediaIDs)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		if !ok {
+			return database.Chirp{}, &requestError{400, "Invalid media IDs"}
+		}
+	}
+
+	// Webhook deliveries are queued with the chirp, so a chirp is never
+	// stored without them or they without it.
+	var chirp database.Chirp
+	webhooksQueued := false
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		chirp, err = q.CreateChirp(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		if len(in.MediaIDs) > 0 {
+			attached, err := q.AttachMediaToChirp(ctx, database.AttachMediaToChirpParams{
+				ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+				Ids:     in.MediaIDs,
+				UserID:  userID,
+			})
+			if err != nil || attached != int64(len(in.MediaIDs)) {
+				// Another chirp claimed some of the media first.
+				return &requestError{409, "Media is already attached to another chirp"}
+			}
+		}
+
+		webhooksQueued, err = cfg.queueWebhooks(ctx, q, chirpEventCreated, chirp)
+		return err
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if webhooksQueued {
+		cfg.wakeWebhookWorker()
+	}
+
+	if chirp.Status == chirpStatusHeld {
+		cfg.fileAutomatedReport(ctx, chirp)
+	}
+	if chirp.Status == chirpStatusPublished {
+		cfg.afterChirpPublished(ctx, chirp)
+	}
+
+	return chirp, nil
+}
+
 func (cfg *apiConfig) handlerPostChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body       string      `json:"body"`
+		InReplyTo  *uuid.UUID  `json:"in_reply_to"`
+		QuoteOf    *uuid.UUID  `json:"quote_of"`
+		MediaIDs   []uuid.UUID `json:"media_ids"`
+		PublishAt  *time.Time  `json:"publish_at"`
+		Visibility string      `json:"visibility"`
 	}
 	msg := ""
 	code := 201
@@ -24,12 +461,8 @@ func (cfg *apiConfig) handlerPostChirp(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		msg = "Something went wrong"
 		code = 500
-	}
-
-	if len(params.Body) > 140 {
-		msg = "Chirp is too long"
-		code = 400
 		respondWithError(w, code, msg)
+		return
 	}
 
 	token, err := auth.GetBearerToken(r.Header)
@@ -48,26 +481,27 @@ func (cfg *apiConfig) handlerPostChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	args := database.CreateChirpParams{
-		Body:   params.Body,
-		UserID: id,
+	chirp, err := cfg.createChirp(r.Context(), id, chirpInput{
+		Body:       params.Body,
+		InReplyTo:  params.InReplyTo,
+		QuoteOf:    params.QuoteOf,
+		MediaIDs:   params.MediaIDs,
+		PublishAt:  params.PublishAt,
+		Visibility: params.Visibility,
+	})
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
 	}
 
-	chirp, err := cfg.dbQueries.CreateChirp(r.Context(), args)
+	respChirps, err := cfg.chirpResponses(r.Context(), id, []database.Chirp{chirp})
 	if err != nil {
-		msg = "Something went wrong when creating chirp"
+		msg = "Something went wrong"
 		code = 500
 		respondWithError(w, code, msg)
 		return
 	}
-
-	respBody := returnChirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	}
+	respBody := respChirps[0]
 
 	data, _ := json.Marshal(respBody)
 
@@ -83,9 +517,33 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
 	id := r.URL.Query().Get("author_id")
 	s := r.URL.Query().Get("sort")
 
-	chirps, err := cfg.dbQueries.GetAllChirps(r.Context())
+	viewer := cfg.viewerID(r)
+
+	includeHidden, err := cfg.isModerator(r.Context(), viewer)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetAllChirps(r.Context(), database.GetAllChirpsParams{
+		IncludeHidden: includeHidden,
+		ViewerID:      viewer,
+	})
 	if id != "" {
-		chirps, err = cfg.dbQueries.GetAllUserChirps(r.Context(), uuid.MustParse(id))
+		authorID, parseErr := uuid.Parse(id)
+		if parseErr != nil {
+			msg = "Invalid author ID"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		chirps, err = cfg.dbQueries.GetAllUserChirps(r.Context(), database.GetAllUserChirpsParams{
+			UserID:        authorID,
+			IncludeHidden: includeHidden,
+			ViewerID:      viewer,
+		})
 	}
 	if err != nil {
 		msg = "Something went wrong"
@@ -93,16 +551,12 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, code, msg)
 		return
 	}
-	respBody := []returnChirp{}
-	for _, chirp := range chirps {
-		respChirp := returnChirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		}
-		respBody = append(respBody, respChirp)
+	respBody, err := cfg.chirpResponses(r.Context(), viewer, chirps)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
 	}
 
 	if s == "desc" {
@@ -120,9 +574,17 @@ func (cfg *apiConfig) handlerGetChirps(w http.ResponseWriter, r *http.Request) {
 func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 	msg := ""
 	code := 200
-	chirpID := uuid.MustParse(r.PathValue("chirpID"))
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
 
-	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	viewer := cfg.viewerID(r)
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
 
 	if err != nil {
 		msg = "Something went wrong"
@@ -131,13 +593,14 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respBody := returnChirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	respChirps, err := cfg.chirpResponses(r.Context(), viewer, []database.Chirp{chirp})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
 	}
+	respBody := respChirps[0]
 
 	data, _ := json.Marshal(respBody)
 
@@ -149,10 +612,16 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
 	msg := ""
 	code := 204
-	chirpID := uuid.MustParse(r.PathValue("chirpID"))
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
 
 	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || chirp.TombstonedAt.Valid || chirp.DeletedAt.Valid {
 		msg = "Something went wrong"
 		code = 404
 		respondWithError(w, code, msg)
@@ -179,14 +648,95 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.dbQueries.DeleteChirp(r.Context(), chirp.ID)
+	// The chirp disappears straight away but can be restored until the
+	// purge job removes it for good. Its webhooks are queued with the
+	// delete.
+	webhooksQueued := false
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		err := q.SoftDeleteChirp(r.Context(), chirp.ID)
+		if err != nil {
+			return err
+		}
+		webhooksQueued, err = cfg.queueWebhooks(r.Context(), q, chirpEventDeleted, chirp)
+		return err
+	})
 	if err != nil {
 		msg = "Something went wrong"
 		code = 500
 		respondWithError(w, code, msg)
 		return
 	}
+	if webhooksQueued {
+		cfg.wakeWebhookWorker()
+	}
+
+	cfg.recordChirpEvent(r.Context(), chirpEventDeleted, chirp)
+	cfg.federateChirp(chirpEventDeleted, chirp)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 }
+
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.UserID != userID || !chirp.DeletedAt.Valid {
+		msg = "Deleted chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err = cfg.dbQueries.RestoreChirp(r.Context(), database.RestoreChirpParams{
+		ID:           chirp.ID,
+		DeletedAfter: sql.NullTime{Time: time.Now().Add(-cfg.restoreWindow), Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "Chirp can no longer be restored"
+		code = 410
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// A restored chirp is announced again unless a moderator hid it in the
+	// meantime; the announcements skip chirps that are not published.
+	if !chirp.HiddenAt.Valid {
+		cfg.recordChirpEvent(r.Context(), chirpEventCreated, chirp)
+		cfg.federateChirp(chirpEventCreated, chirp)
+		cfg.enqueueWebhooks(r.Context(), chirpEventCreated, chirp)
+	}
+
+	respChirps, err := cfg.chirpResponses(r.Context(), userID, []database.Chirp{chirp})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respChirps[0])
+}
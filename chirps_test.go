@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"server/internal/database"
+	"server/internal/stream"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func deleteChirp(t *testing.T, cfg *apiConfig, userID uuid.UUID, chirp database.Chirp) {
+	t.Helper()
+	r := testRequest(t, cfg, "DELETE", "/api/chirps/"+chirp.ID.String(), userID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerDeleteChirp, r, 204)
+}
+
+func restoreChirp(t *testing.T, cfg *apiConfig, userID uuid.UUID, chirp database.Chirp) {
+	t.Helper()
+	r := testRequest(t, cfg, "POST", "/api/chirps/"+chirp.ID.String()+"/restore", userID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerRestoreChirp, r, 200)
+}
+
+// getChirp fetches chirpID as viewer, or anonymously if viewer is
+// uuid.Nil.
+func getChirp(t *testing.T, cfg *apiConfig, viewer, chirpID uuid.UUID) returnChirp {
+	t.Helper()
+	r := testRequest(t, cfg, "GET", "/api/chirps/"+chirpID.String(), viewer, nil)
+	r.SetPathValue("chirpID", chirpID.String())
+	w := serve(t, cfg.handlerGetChirp, r, 200)
+
+	var chirp returnChirp
+	err := json.NewDecoder(w.Body).Decode(&chirp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chirp
+}
+
+// createTestWebhookEndpoint subscribes a new endpoint of userID's to every
+// chirp event.
+func createTestWebhookEndpoint(t *testing.T, cfg *apiConfig, userID uuid.UUID) database.WebhookEndpoint {
+	t.Helper()
+	endpoint, err := cfg.dbQueries.CreateWebhookEndpoint(context.Background(), database.CreateWebhookEndpointParams{
+		UserID: userID,
+		Url:    "https://hooks.test.invalid/chirpy",
+		Events: webhookEvents,
+		Secret: "test-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return endpoint
+}
+
+// webhookEventsQueued returns the events queued for endpoint, oldest
+// first.
+func webhookEventsQueued(t *testing.T, cfg *apiConfig, endpoint database.WebhookEndpoint) []string {
+	t.Helper()
+	deliveries, err := cfg.dbQueries.GetWebhookDeliveries(context.Background(), database.GetWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		PageLimit:  100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []string{}
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		events = append(events, deliveries[i].Event)
+	}
+	return events
+}
+
+func TestRestoringChirpIsAnnounced(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	endpoint := createTestWebhookEndpoint(t, cfg, author.ID)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+
+	sub := cfg.hub.Subscribe(func(ev stream.Event) bool { return ev.ChirpID == chirp.ID })
+	defer sub.Close()
+
+	deleteChirp(t, cfg, author.ID, chirp)
+	restoreChirp(t, cfg, author.ID, chirp)
+
+	got := chirpEvents(sub)
+	if len(got) != 2 || got[0] != chirpEventDeleted || got[1] != chirpEventCreated {
+		t.Errorf("got events %v, want [%s %s]", got, chirpEventDeleted, chirpEventCreated)
+	}
+	queued := webhookEventsQueued(t, cfg, endpoint)
+	if len(queued) != 3 || queued[1] != chirpEventDeleted || queued[2] != chirpEventCreated {
+		t.Errorf("got webhooks %v, want [%s %s %s]", queued, chirpEventCreated, chirpEventDeleted, chirpEventCreated)
+	}
+}
+
+func TestRestoringHiddenChirpIsNotAnnounced(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	endpoint := createTestWebhookEndpoint(t, cfg, author.ID)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	takeAction(t, cfg, createTestReport(t, cfg, chirp.ID), actionHideChirp)
+	deleteChirp(t, cfg, author.ID, chirp)
+
+	sub := cfg.hub.Subscribe(func(ev stream.Event) bool { return ev.ChirpID == chirp.ID })
+	defer sub.Close()
+
+	restoreChirp(t, cfg, author.ID, chirp)
+
+	if got := chirpEvents(sub); len(got) != 0 {
+		t.Errorf("got events %v for a hidden chirp", got)
+	}
+	queued := webhookEventsQueued(t, cfg, endpoint)
+	if queued[len(queued)-1] == chirpEventCreated {
+		t.Errorf("got webhooks %v, want no chirp.created after the delete", queued)
+	}
+}
+
+// expireDeletion backdates chirpID's deletion past the restore window.
+func expireDeletion(t *testing.T, cfg *apiConfig, chirpID uuid.UUID) {
+	t.Helper()
+	_, err := cfg.db.Exec("UPDATE chirps SET deleted_at = $2 WHERE id = $1", chirpID, time.Now().Add(-cfg.restoreWindow-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeletedChirpIsHiddenUntilRestored(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	other := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	deleteChirp(t, cfg, author.ID, chirp)
+
+	// Even the author cannot read a deleted chirp, but they can restore it.
+	r := testRequest(t, cfg, "GET", "/api/chirps/"+chirp.ID.String(), author.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerGetChirp, r, 404)
+	if listAuthorChirps(t, cfg, author.ID, author.ID)[chirp.ID] {
+		t.Error("deleted chirp listed")
+	}
+
+	r = testRequest(t, cfg, "POST", "/api/chirps/"+chirp.ID.String()+"/restore", other.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerRestoreChirp, r, 404)
+
+	restoreChirp(t, cfg, author.ID, chirp)
+	if got := getChirp(t, cfg, other.ID, chirp.ID); got.ID != chirp.ID {
+		t.Errorf("got %v after restoring, want %v", got.ID, chirp.ID)
+	}
+}
+
+func TestRestoreWindowExpires(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	deleteChirp(t, cfg, author.ID, chirp)
+	expireDeletion(t, cfg, chirp.ID)
+
+	r := testRequest(t, cfg, "POST", "/api/chirps/"+chirp.ID.String()+"/restore", author.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerRestoreChirp, r, 410)
+}
+
+func TestPurgeRemovesExpiredChirps(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	ctx := context.Background()
+
+	lone := createTestChirp(t, cfg, author.ID, chirpInput{})
+	parent := createTestChirp(t, cfg, author.ID, chirpInput{})
+	createTestChirp(t, cfg, author.ID, chirpInput{InReplyTo: &parent.ID})
+	recent := createTestChirp(t, cfg, author.ID, chirpInput{})
+	for _, chirp := range []database.Chirp{lone, parent, recent} {
+		deleteChirp(t, cfg, author.ID, chirp)
+	}
+	expireDeletion(t, cfg, lone.ID)
+	expireDeletion(t, cfg, parent.ID)
+
+	cfg.purgeDeletedChirps(ctx)
+
+	if _, err := cfg.dbQueries.GetChirp(ctx, lone.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expired chirp without replies: got %v, want it gone", err)
+	}
+	purged, err := cfg.dbQueries.GetChirp(ctx, parent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !purged.TombstonedAt.Valid {
+		t.Error("expired chirp with replies was not tombstoned")
+	}
+	// Chirps still inside the window can be restored.
+	restoreChirp(t, cfg, author.ID, recent)
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"server/internal/database"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxConversationParticipants counts the user starting the
+	// conversation.
+	maxConversationParticipants = 10
+	maxMessageLength            = 1000
+)
+
+func messageResponse(message database.Message) returnMessage {
+	return returnMessage{
+		ID:             message.ID,
+		CreatedAt:      message.CreatedAt,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+	}
+}
+
+// conversationResponses converts conversations in one batch, loading the
+// users still taking part in each.
+func (cfg *apiConfig) conversationResponses(ctx context.Context, rows []database.GetUserConversationsRow) ([]returnConversation, error) {
+	ids := []uuid.UUID{}
+	for _, row := range rows {
+		ids = append(ids, row.Conversation.ID)
+	}
+
+	participantRows, err := cfg.dbQueries.GetConversationsParticipants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	participants := map[uuid.UUID][]returnPublicUser{}
+	for _, row := range participantRows {
+		participants[row.ConversationID] = append(participants[row.ConversationID], publicUser(row.User))
+	}
+
+	respBody := []returnConversation{}
+	for _, row := range rows {
+		respConversation := returnConversation{
+			ID:           row.Conversation.ID,
+			CreatedAt:    row.Conversation.CreatedAt,
+			UpdatedAt:    row.Conversation.UpdatedAt,
+			IsGroup:      row.Conversation.IsGroup,
+			Participants: participants[row.Conversation.ID],
+			UnreadCount:  row.UnreadCount,
+		}
+		if respConversation.Participants == nil {
+			respConversation.Participants = []returnPublicUser{}
+		}
+		if row.LastReadAt.Valid {
+			respConversation.LastReadAt = &row.LastReadAt.Time
+		}
+		respBody = append(respBody, respConversation)
+	}
+	return respBody, nil
+}
+
+// conversationResponse converts a single conversation as seen by userID.
+func (cfg *apiConfig) conversationResponse(ctx context.Context, userID uuid.UUID, conversation database.Conversation) (returnConversation, error) {
+	row, err := cfg.dbQueries.GetUserConversation(ctx, database.GetUserConversationParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return returnConversation{}, err
+	}
+	respBody, err := cfg.conversationResponses(ctx, []database.GetUserConversationsRow{
+		database.GetUserConversationsRow(row),
+	})
+	if err != nil {
+		return returnConversation{}, err
+	}
+	return respBody[0], nil
+}
+
+// getOwnConversation loads the conversation named in the path. Users who
+// are not taking part, including those who have left, are told it does
+// not exist.
+func (cfg *apiConfig) getOwnConversation(r *http.Request, userID uuid.UUID) (database.Conversation, error) {
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	_, err = cfg.dbQueries.GetConversationParticipant(r.Context(), database.GetConversationParticipantParams{
+		ConversationID: conversationID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return database.Conversation{}, err
+	}
+	return cfg.dbQueries.GetConversation(r.Context(), conversationID)
+}
+
+// checkMessage validates a message body before it is stored.
+func checkMessage(body string) error {
+	if body == "" {
+		return &requestError{400, "Message is empty"}
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		return &requestError{400, "Message is too long"}
+	}
+	return nil
+}
+
+// startConversation reuses the open one-to-one conversation between the
+// two users if there is one, and otherwise creates a new conversation. It
+// reports whether the conversation was created. Both users are locked
+// while looking for a one-to-one conversation, so two requests racing to
+// start one do not both create it.
+func (cfg *apiConfig) startConversation(ctx context.Context, userID uuid.UUID, others []uuid.UUID) (database.Conversation, bool, error) {
+	var conversation database.Conversation
+	created := false
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		if len(others) == 1 {
+			_, err := q.LockUsers(ctx, []uuid.UUID{userID, others[0]})
+			if err != nil {
+				return err
+			}
+			conversation, err = q.FindDirectConversation(ctx, database.FindDirectConversationParams{
+				UserA: userID,
+				UserB: others[0],
+			})
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		var err error
+		conversation, err = q.CreateConversation(ctx, database.CreateConversationParams{
+			CreatedBy: uuid.NullUUID{UUID: userID, Valid: true},
+			IsGroup:   len(others) > 1,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, id := range append([]uuid.UUID{userID}, others...) {
+			err = q.AddConversationParticipant(ctx, database.AddConversationParticipantParams{
+				ConversationID: conversation.ID,
+				UserID:         id,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return database.Conversation{}, false, err
+	}
+	return conversation, created, nil
+}
+
+// handlerStartConversation opens a conversation with participant_ids and
+// optionally sends body as its first message. Starting a one-to-one
+// conversation that already exists returns that conversation.
+func (cfg *apiConfig) handlerStartConversation(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ParticipantIDs []uuid.UUID `json:"participant_ids"`
+		Body           string      `json:"body"`
+	}
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if params.Body != "" {
+		err = checkMessage(params.Body)
+		if err != nil {
+			respondWithRequestError(w, err)
+			return
+		}
+	}
+
+	others := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{userID: true}
+	for _, id := range params.ParticipantIDs {
+		if !seen[id] {
+			seen[id] = true
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		msg = "A conversation needs at least one other participant"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	if len(others)+1 > maxConversationParticipants {
+		msg = "Too many participants"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	for _, id := range others {
+		_, err = cfg.dbQueries.GetUserByID(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			msg = "User not found"
+			code = 404
+			respondWithError(w, code, msg)
+			return
+		}
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+
+		blocked, err := cfg.isBlocked(r.Context(), userID, id)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		if blocked {
+			msg = "You cannot message this user"
+			code = 403
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	conversation, created, err := cfg.startConversation(r.Context(), userID, others)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if !created {
+		code = 200
+	}
+
+	if params.Body != "" {
+		_, err = cfg.dbQueries.CreateMessage(r.Context(), database.CreateMessageParams{
+			ConversationID: conversation.ID,
+			SenderID:       userID,
+			Body:           params.Body,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		conversation, err = cfg.dbQueries.GetConversation(r.Context(), conversation.ID)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	respBody, err := cfg.conversationResponse(r.Context(), userID, conversation)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetConversations(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	rows, err := cfg.dbQueries.GetUserConversations(r.Context(), database.GetUserConversationsParams{
+		UserID:     userID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody, err := cfg.conversationResponses(r.Context(), rows)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetConversation(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	conversation, err := cfg.getOwnConversation(r, userID)
+	if err != nil {
+		msg = "Conversation not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody, err := cfg.conversationResponse(r.Context(), userID, conversation)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+// handlerSendMessage posts a message to a conversation. In a one-to-one
+// conversation a block between the two users stops further messages; in
+// a group, blocked users simply do not see each other's messages.
+func (cfg *apiConfig) handlerSendMessage(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	conversation, err := cfg.getOwnConversation(r, userID)
+	if err != nil {
+		msg = "Conversation not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = checkMessage(params.Body)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	participants, err := cfg.dbQueries.GetConversationsParticipants(r.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if len(participants) < 2 {
+		msg = "Everyone else has left this conversation"
+		code = 409
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if !conversation.IsGroup {
+		for _, p := range participants {
+			blocked, err := cfg.isBlocked(r.Context(), userID, p.User.ID)
+			if err != nil {
+				msg = "Something went wrong"
+				code = 500
+				respondWithError(w, code, msg)
+				return
+			}
+			if blocked {
+				msg = "You cannot message this user"
+				code = 403
+				respondWithError(w, code, msg)
+				return
+			}
+		}
+	}
+
+	message, err := cfg.dbQueries.CreateMessage(r.Context(), database.CreateMessageParams{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           params.Body,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, messageResponse(message))
+}
+
+// handlerGetMessages pages backwards through a conversation, newest first.
+// Pass the returned next_cursor as before to get older messages.
+func (cfg *apiConfig) handlerGetMessages(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	conversation, err := cfg.getOwnConversation(r, userID)
+	if err != nil {
+		msg = "Conversation not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	before := uuid.NullUUID{}
+	if s := r.URL.Query().Get("before"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			msg = "Invalid cursor"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		before = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	limit, _ := paginationParams(r)
+
+	// Ask for one extra row so we know whether another page exists.
+	messages, err := cfg.dbQueries.GetMessages(r.Context(), database.GetMessagesParams{
+		ConversationID: conversation.ID,
+		ViewerID:       userID,
+		BeforeID:       before,
+		PageLimit:      limit + 1,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnMessages{
+		Messages: []returnMessage{},
+	}
+	if len(messages) > int(limit) {
+		messages = messages[:limit]
+		respBody.NextCursor = &messages[len(messages)-1].ID
+	}
+	for _, message := range messages {
+		respBody.Messages = append(respBody.Messages, messageResponse(message))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerMarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	conversation, err := cfg.getOwnConversation(r, userID)
+	if err != nil {
+		msg = "Conversation not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.dbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+// handlerLeaveConversation removes the user from a conversation. The
+// messages stay for everyone else.
+func (cfg *apiConfig) handlerLeaveConversation(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	conversation, err := cfg.getOwnConversation(r, userID)
+	if err != nil {
+		msg = "Conversation not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.LeaveConversation(r.Context(), database.LeaveConversationParams{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStartingDirectConversationTwiceReusesIt(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	ctx := context.Background()
+
+	ids := make([]uuid.UUID, 10)
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i := range ids {
+		starter, other := alice.ID, bob.ID
+		if i%2 == 1 {
+			starter, other = other, starter
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conversation, _, err := cfg.startConversation(ctx, starter, []uuid.UUID{other})
+			ids[i], errs[i] = conversation.ID, err
+		}()
+	}
+	wg.Wait()
+
+	for i := range ids {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if ids[i] != ids[0] {
+			t.Fatalf("got conversations %v and %v for the same pair", ids[0], ids[i])
+		}
+	}
+}
+
+func TestGroupConversationsAreNotReused(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	carol := createTestUser(t, cfg)
+	ctx := context.Background()
+
+	first, created, err := cfg.startConversation(ctx, alice.ID, []uuid.UUID{bob.ID, carol.ID})
+	if err != nil || !created {
+		t.Fatalf("first group: created %v, err %v", created, err)
+	}
+	second, created, err := cfg.startConversation(ctx, alice.ID, []uuid.UUID{bob.ID, carol.ID})
+	if err != nil || !created {
+		t.Fatalf("second group: created %v, err %v", created, err)
+	}
+	if first.ID == second.ID {
+		t.Error("group conversation reused")
+	}
+}
+
+// startConversationRequest has userID start a conversation with others
+// through POST /api/conversations.
+func startConversationRequest(t *testing.T, cfg *apiConfig, userID uuid.UUID, others []uuid.UUID, want int) returnConversation {
+	t.Helper()
+	r := testRequest(t, cfg, "POST", "/api/conversations", userID, map[string]any{"participant_ids": others})
+	w := serve(t, cfg.handlerStartConversation, r, want)
+
+	var conversation returnConversation
+	if want == 200 || want == 201 {
+		err := json.NewDecoder(w.Body).Decode(&conversation)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return conversation
+}
+
+// conversationRequest sends method to the conversation's path plus
+// suffix, e.g. "/messages", as userID.
+func conversationRequest(t *testing.T, cfg *apiConfig, handler http.HandlerFunc, method, suffix string, userID, conversationID uuid.UUID, body any, want int) *httptest.ResponseRecorder {
+	t.Helper()
+	r := testRequest(t, cfg, method, "/api/conversations/"+conversationID.String()+suffix, userID, body)
+	r.SetPathValue("conversationID", conversationID.String())
+	return serve(t, handler, r, want)
+}
+
+func sendMessage(t *testing.T, cfg *apiConfig, userID, conversationID uuid.UUID, body string, want int) {
+	t.Helper()
+	conversationRequest(t, cfg, cfg.handlerSendMessage, "POST", "/messages", userID, conversationID, map[string]any{"body": body}, want)
+}
+
+func getMessages(t *testing.T, cfg *apiConfig, userID, conversationID uuid.UUID, query string) returnMessages {
+	t.Helper()
+	w := conversationRequest(t, cfg, cfg.handlerGetMessages, "GET", "/messages"+query, userID, conversationID, nil, 200)
+
+	var messages returnMessages
+	err := json.NewDecoder(w.Body).Decode(&messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func TestMessagesAreOnlyForParticipants(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	eve := createTestUser(t, cfg)
+	conversation := startConversationRequest(t, cfg, alice.ID, []uuid.UUID{bob.ID}, 201)
+	sendMessage(t, cfg, alice.ID, conversation.ID, "hi bob", 201)
+
+	if got := getMessages(t, cfg, bob.ID, conversation.ID, ""); len(got.Messages) != 1 || got.Messages[0].Body != "hi bob" {
+		t.Errorf("bob got messages %+v, want alice's", got.Messages)
+	}
+	conversationRequest(t, cfg, cfg.handlerGetMessages, "GET", "/messages", eve.ID, conversation.ID, nil, 404)
+	sendMessage(t, cfg, eve.ID, conversation.ID, "hi all", 404)
+}
+
+func TestMessagesPageNewestFirst(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	conversation := startConversationRequest(t, cfg, alice.ID, []uuid.UUID{bob.ID}, 201)
+	for _, body := range []string{"one", "two", "three"} {
+		sendMessage(t, cfg, alice.ID, conversation.ID, body, 201)
+	}
+
+	first := getMessages(t, cfg, bob.ID, conversation.ID, "?limit=2")
+	if len(first.Messages) != 2 || first.Messages[0].Body != "three" || first.Messages[1].Body != "two" || first.NextCursor == nil {
+		t.Fatalf("first page: got %+v", first)
+	}
+	second := getMessages(t, cfg, bob.ID, conversation.ID, "?limit=2&before="+first.NextCursor.String())
+	if len(second.Messages) != 1 || second.Messages[0].Body != "one" || second.NextCursor != nil {
+		t.Errorf("second page: got %+v", second)
+	}
+}
+
+func TestMarkingConversationRead(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	conversation := startConversationRequest(t, cfg, alice.ID, []uuid.UUID{bob.ID}, 201)
+	sendMessage(t, cfg, alice.ID, conversation.ID, "one", 201)
+	sendMessage(t, cfg, alice.ID, conversation.ID, "two", 201)
+
+	unread := func() int64 {
+		t.Helper()
+		w := conversationRequest(t, cfg, cfg.handlerGetConversation, "GET", "", bob.ID, conversation.ID, nil, 200)
+		var got returnConversation
+		err := json.NewDecoder(w.Body).Decode(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.UnreadCount
+	}
+	if got := unread(); got != 2 {
+		t.Errorf("got %d unread, want 2", got)
+	}
+	conversationRequest(t, cfg, cfg.handlerMarkConversationRead, "POST", "/read", bob.ID, conversation.ID, nil, 204)
+	if got := unread(); got != 0 {
+		t.Errorf("got %d unread after reading, want 0", got)
+	}
+}
+
+func TestBlockStopsDirectMessages(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	conversation := startConversationRequest(t, cfg, alice.ID, []uuid.UUID{bob.ID}, 201)
+	blockUser(t, cfg, bob.ID, alice.ID)
+
+	sendMessage(t, cfg, alice.ID, conversation.ID, "hello?", 403)
+	startConversationRequest(t, cfg, alice.ID, []uuid.UUID{bob.ID}, 403)
+}
+
+func TestLeavingConversation(t *testing.T) {
+	cfg := newTestConfig(t)
+	alice := createTestUser(t, cfg)
+	bob := createTestUser(t, cfg)
+	conversation := startConversationRequest(t, cfg, alice.ID, []uuid.UUID{bob.ID}, 201)
+	sendMessage(t, cfg, bob.ID, conversation.ID, "bye", 201)
+
+	conversationRequest(t, cfg, cfg.handlerLeaveConversation, "POST", "/leave", bob.ID, conversation.ID, nil, 204)
+
+	conversationRequest(t, cfg, cfg.handlerGetMessages, "GET", "/messages", bob.ID, conversation.ID, nil, 404)
+	sendMessage(t, cfg, alice.ID, conversation.ID, "anyone?", 409)
+	// Bob's messages stay for Alice.
+	if got := getMessages(t, cfg, alice.ID, conversation.ID, ""); len(got.Messages) != 1 {
+		t.Errorf("got %d messages, want bob's to stay", len(got.Messages))
+	}
+}
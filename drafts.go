@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"server/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func draftResponse(draft database.Draft) returnDraft {
+	respDraft := returnDraft{
+		ID:        draft.ID,
+		CreatedAt: draft.CreatedAt,
+		UpdatedAt: draft.UpdatedAt,
+		Body:      draft.Body,
+	}
+	if draft.InReplyTo.Valid {
+		respDraft.InReplyTo = &draft.InReplyTo.UUID
+	}
+	if draft.QuoteOf.Valid {
+		respDraft.QuoteOf = &draft.QuoteOf.UUID
+	}
+	return respDraft
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+// errDraftTargetNotFound is returned whether a chirp a draft replies to or
+// quotes is missing or just hidden from its author, so drafts cannot be
+// used to probe for chirps.
+var errDraftTargetNotFound = &requestError{404, "Chirp being replied to or quoted does not exist"}
+
+// checkDraftTargets makes sure userID can see the chirps a draft replies
+// to and quotes.
+func (cfg *apiConfig) checkDraftTargets(ctx context.Context, userID uuid.UUID, ids ...*uuid.UUID) error {
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		_, err := cfg.getVisibleChirp(ctx, userID, *id)
+		if err != nil {
+			return errDraftTargetNotFound
+		}
+	}
+	return nil
+}
+
+// getOwnDraft loads the draft named in the path. Drafts are private, so
+// someone else's draft is reported as missing.
+func (cfg *apiConfig) getOwnDraft(r *http.Request, userID uuid.UUID) (database.Draft, error) {
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		return database.Draft{}, err
+	}
+	draft, err := cfg.dbQueries.GetDraft(r.Context(), draftID)
+	if err != nil {
+		return database.Draft{}, err
+	}
+	if draft.UserID != userID {
+		return database.Draft{}, errors.New("draft belongs to another user")
+	}
+	return draft, nil
+}
+
+func (cfg *apiConfig) handlerCreateDraft(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
+	}
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limits, err := cfg.userLimits(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if chirpTooLong(params.Body, limits) {
+		msg = "Chirp is too long"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.checkDraftTargets(r.Context(), userID, params.InReplyTo, params.QuoteOf)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	draft, err := cfg.dbQueries.CreateDraft(r.Context(), database.CreateDraftParams{
+		UserID:    userID,
+		Body:      params.Body,
+		InReplyTo: nullUUID(params.InReplyTo),
+		QuoteOf:   nullUUID(params.QuoteOf),
+	})
+	if isForeignKeyViolation(err) {
+		respondWithRequestError(w, errDraftTargetNotFound)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, draftResponse(draft))
+}
+
+func (cfg *apiConfig) handlerGetDrafts(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	drafts, err := cfg.dbQueries.GetUserDrafts(r.Context(), database.GetUserDraftsParams{
+		UserID:     userID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnDraft{}
+	for _, draft := range drafts {
+		respBody = append(respBody, draftResponse(draft))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetDraft(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	draft, err := cfg.getOwnDraft(r, userID)
+	if err != nil {
+		msg = "Draft not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, draftResponse(draft))
+}
+
+func (cfg *apiConfig) handlerUpdateDraft(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
+	}
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	draft, err := cfg.getOwnDraft(r, userID)
+	if err != nil {
+		msg = "Draft not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limits, err := cfg.userLimits(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if chirpTooLong(params.Body, limits) {
+		msg = "Chirp is too long"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.checkDraftTargets(r.Context(), userID, params.InReplyTo, params.QuoteOf)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	draft, err = cfg.dbQueries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+		ID:        draft.ID,
+		Body:      params.Body,
+		InReplyTo: nullUUID(params.InReplyTo),
+		QuoteOf:   nullUUID(params.QuoteOf),
+	})
+	if isForeignKeyViolation(err) {
+		respondWithRequestError(w, errDraftTargetNotFound)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, draftResponse(draft))
+}
+
+func (cfg *apiConfig) handlerDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	draft, err := cfg.getOwnDraft(r, userID)
+	if err != nil {
+		msg = "Draft not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.dbQueries.DeleteDraft(r.Context(), draft.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+// handlerPublishDraft turns a draft into a chirp, straight away or at
+// publish_at, and removes the draft.
+func (cfg *apiConfig) handlerPublishDraft(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MediaIDs   []uuid.UUID `json:"media_ids"`
+		PublishAt  *time.Time  `json:"publish_at"`
+		Visibility string      `json:"visibility"`
+	}
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// The body is optional here; an empty one publishes immediately.
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Deleting the draft up front claims it, so two requests racing to
+	// publish the same draft cannot both post it. It is put back if the
+	// chirp cannot be created.
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		msg = "Draft not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	draft, err := cfg.dbQueries.ClaimDraft(r.Context(), database.ClaimDraftParams{
+		ID:     draftID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "Draft not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	in := chirpInput{
+		Body:       draft.Body,
+		MediaIDs:   params.MediaIDs,
+		PublishAt:  params.PublishAt,
+		Visibility: params.Visibility,
+	}
+	if draft.InReplyTo.Valid {
+		in.InReplyTo = &draft.InReplyTo.UUID
+	}
+	if draft.QuoteOf.Valid {
+		in.QuoteOf = &draft.QuoteOf.UUID
+	}
+
+	chirp, err := cfg.createChirp(r.Context(), userID, in)
+	if err != nil {
+		restoreErr := cfg.dbQueries.RestoreDraft(r.Context(), database.RestoreDraftParams{
+			ID:        draft.ID,
+			CreatedAt: draft.CreatedAt,
+			UpdatedAt: draft.UpdatedAt,
+			UserID:    draft.UserID,
+			Body:      draft.Body,
+			InReplyTo: draft.InReplyTo,
+			QuoteOf:   draft.QuoteOf,
+		})
+		if restoreErr != nil {
+			fmt.Println("restoring draft:", restoreErr)
+		}
+		respondWithRequestError(w, err)
+		return
+	}
+
+	respChirps, err := cfg.chirpResponses(r.Context(), userID, []database.Chirp{chirp})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respChirps[0])
+}
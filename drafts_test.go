@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func createTestDraft(t *testing.T, cfg *apiConfig, userID uuid.UUID, body string) returnDraft {
+	t.Helper()
+	r := testRequest(t, cfg, "POST", "/api/drafts", userID, map[string]any{"body": body})
+	w := serve(t, cfg.handlerCreateDraft, r, 201)
+
+	var draft returnDraft
+	err := json.NewDecoder(w.Body).Decode(&draft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return draft
+}
+
+func TestDraftsArePrivate(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	other := createTestUser(t, cfg)
+	draft := createTestDraft(t, cfg, author.ID, "not yet")
+
+	getDraft := func(userID uuid.UUID, want int) {
+		t.Helper()
+		r := testRequest(t, cfg, "GET", "/api/drafts/"+draft.ID.String(), userID, nil)
+		r.SetPathValue("draftID", draft.ID.String())
+		serve(t, cfg.handlerGetDraft, r, want)
+	}
+	getDraft(author.ID, 200)
+	getDraft(other.ID, 404)
+}
+
+func TestPublishingDraftPostsItOnce(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	draft := createTestDraft(t, cfg, author.ID, "ready now")
+
+	publish := func(want int) {
+		t.Helper()
+		r := testRequest(t, cfg, "POST", "/api/drafts/"+draft.ID.String()+"/publish", author.ID, nil)
+		r.SetPathValue("draftID", draft.ID.String())
+		serve(t, cfg.handlerPublishDraft, r, want)
+	}
+	publish(201)
+	publish(404)
+
+	r := testRequest(t, cfg, "GET", "/api/drafts/"+draft.ID.String(), author.ID, nil)
+	r.SetPathValue("draftID", draft.ID.String())
+	serve(t, cfg.handlerGetDraft, r, 404)
+}
+
+func TestSchedulingInThePastIsRefused(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	past := time.Now().Add(-time.Minute)
+
+	_, err := cfg.createChirp(context.Background(), author.ID, chirpInput{Body: "too late", PublishAt: &past})
+	if requestErrorCode(err) != 400 {
+		t.Errorf("got %v, want a 400", err)
+	}
+}
+
+func TestScheduledChirpIsPublishedExactlyOnce(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	reader := createTestUser(t, cfg)
+	endpoint := createTestWebhookEndpoint(t, cfg, author.ID)
+	ctx := context.Background()
+
+	later := time.Now().Add(time.Hour)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{PublishAt: &later})
+	if _, err := cfg.getVisibleChirp(ctx, reader.ID, chirp.ID); err == nil {
+		t.Fatal("scheduled chirp visible before it is published")
+	}
+
+	_, err := cfg.db.Exec("UPDATE chirps SET publish_at = NOW() - INTERVAL '1 second' WHERE id = $1", chirp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Several schedulers racing, as with several server instances.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.publishDueChirps(ctx)
+		}()
+	}
+	wg.Wait()
+
+	published, err := cfg.getVisibleChirp(ctx, reader.ID, chirp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published.Status != chirpStatusPublished {
+		t.Errorf("got status %q, want %q", published.Status, chirpStatusPublished)
+	}
+	queued := webhookEventsQueued(t, cfg, endpoint)
+	if len(queued) != 1 || queued[0] != chirpEventCreated {
+		t.Errorf("got webhooks %v, want a single %s", queued, chirpEventCreated)
+	}
+}
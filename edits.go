@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"server/internal/database"
+	"server/internal/moderation"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerEditChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.TombstonedAt.Valid || chirp.DeletedAt.Valid {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if chirp.UserID != userID {
+		msg = "Only the author can edit a chirp"
+		code = 403
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if isSuspended(user) {
+		msg = "Account is suspended"
+		code = 403
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limits := cfg.limitsFor(user)
+
+	// Scheduled chirps have not been seen by anyone yet, so they can be
+	// edited right up until they are published.
+	if chirp.Status != chirpStatusScheduled && time.Since(chirp.CreatedAt) > time.Duration(limits.EditWindow) {
+		msg = "Chirp can no longer be edited"
+		code = 403
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if chirpTooLong(params.Body, limits) {
+		msg = "Chirp is too long"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	verdict := cfg.moderator.Check(params.Body)
+	if verdict.Action == moderation.Reject {
+		msg = "Chirp was rejected by moderation"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.checkMentions(r.Context(), userID, verdict.Body)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	// Saving the same text again should not clutter the history.
+	if verdict.Body != chirp.Body {
+		before := chirp
+		status, reasons := moderationOutcome(verdict)
+		if status == chirpStatusPublished && chirp.PublishAt.Valid && chirp.PublishAt.Time.After(time.Now()) {
+			status = chirpStatusScheduled
+		}
+		chirp, err = cfg.dbQueries.EditChirp(r.Context(), database.EditChirpParams{
+			ID:                chirp.ID,
+			Body:              verdict.Body,
+			Status:            status,
+			ModerationReasons: reasons,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+
+		switch {
+		case chirp.Status == chirpStatusHeld && before.Status != chirpStatusHeld:
+			cfg.fileAutomatedReport(r.Context(), chirp)
+			if announced(before) {
+				cfg.afterChirpWithdrawn(r.Context(), before)
+			}
+		case chirp.Status == chirpStatusPublished && before.Status == chirpStatusHeld:
+			cfg.afterChirpReleased(r.Context(), chirp)
+		case chirp.Status == chirpStatusPublished && announced(before):
+			cfg.afterChirpEdited(r.Context(), chirp)
+		}
+	}
+
+	respChirps, err := cfg.chirpResponses(r.Context(), userID, []database.Chirp{chirp})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respChirps[0])
+}
+
+func (cfg *apiConfig) handlerGetChirpHistory(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	viewer := cfg.viewerID(r)
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Bodies that were held or scheduled were never public, so only the
+	// author and moderators see them.
+	includeUnpublished := chirp.UserID == viewer
+	if !includeUnpublished {
+		includeUnpublished, err = cfg.isModerator(r.Context(), viewer)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+	revisions, err := cfg.dbQueries.GetChirpRevisions(r.Context(), database.GetChirpRevisionsParams{
+		ChirpID:            chirp.ID,
+		IncludeUnpublished: includeUnpublished,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respChirps, err := cfg.chirpResponses(r.Context(), viewer, []database.Chirp{chirp})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnChirpHistory{
+		Chirp:     respChirps[0],
+		Revisions: []returnChirpRevision{},
+	}
+	for _, revision := range revisions {
+		respBody.Revisions = append(respBody.Revisions, returnChirpRevision{
+			Body:       revision.Body,
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+		})
+	}
+
+	respondWithJSON(w, code, respBody)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"server/internal/database"
+	"server/internal/moderation"
+	"server/internal/stream"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// holdPattern has cfg hold every chirp matching pattern for review.
+func holdPattern(t *testing.T, cfg *apiConfig, pattern string) {
+	t.Helper()
+	config := moderation.DefaultConfig()
+	config.Patterns = append(config.Patterns, moderation.PatternRuleConfig{Name: "test", Pattern: pattern, Action: "hold"})
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "moderation.json")
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.moderator, err = moderation.NewModerator(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func editChirp(t *testing.T, cfg *apiConfig, userID uuid.UUID, chirp database.Chirp, body string) {
+	t.Helper()
+	r := testRequest(t, cfg, "PUT", "/api/chirps/"+chirp.ID.String(), userID, map[string]string{"body": body})
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerEditChirp, r, 200)
+}
+
+// chirpEvents returns the types of the events sub has received so far.
+func chirpEvents(sub *stream.Subscription) []string {
+	types := []string{}
+	for {
+		select {
+		case ev := <-sub.C:
+			types = append(types, ev.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestEditingPublishedChirpIsAnnounced(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Body: "first draft"})
+
+	sub := cfg.hub.Subscribe(func(ev stream.Event) bool { return ev.ChirpID == chirp.ID })
+	defer sub.Close()
+
+	editChirp(t, cfg, author.ID, chirp, "second draft")
+
+	got := chirpEvents(sub)
+	if len(got) != 1 || got[0] != chirpEventUpdated {
+		t.Fatalf("got events %v, want [%s]", got, chirpEventUpdated)
+	}
+}
+
+func TestEditIntoHeldWithdrawsChirp(t *testing.T) {
+	cfg := newTestConfig(t)
+	holdPattern(t, cfg, "suspicious")
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Body: "harmless"})
+
+	sub := cfg.hub.Subscribe(func(ev stream.Event) bool { return ev.ChirpID == chirp.ID })
+	defer sub.Close()
+
+	editChirp(t, cfg, author.ID, chirp, "suspicious")
+	held, err := cfg.dbQueries.GetChirp(context.Background(), chirp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held.Status != chirpStatusHeld {
+		t.Fatalf("got status %q, want %q", held.Status, chirpStatusHeld)
+	}
+
+	editChirp(t, cfg, author.ID, held, "harmless again")
+
+	got := chirpEvents(sub)
+	if len(got) != 2 || got[0] != chirpEventDeleted || got[1] != chirpEventCreated {
+		t.Fatalf("got events %v, want [%s %s]", got, chirpEventDeleted, chirpEventCreated)
+	}
+}
+
+func TestHistoryHidesHeldRevisions(t *testing.T) {
+	cfg := newTestConfig(t)
+	holdPattern(t, cfg, "suspicious")
+	author := createTestUser(t, cfg)
+	reader := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Body: "harmless"})
+
+	editChirp(t, cfg, author.ID, chirp, "suspicious")
+	held, err := cfg.dbQueries.GetChirp(context.Background(), chirp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editChirp(t, cfg, author.ID, held, "harmless again")
+
+	revisions := func(viewer uuid.UUID) int {
+		t.Helper()
+		r := testRequest(t, cfg, "GET", "/api/chirps/"+chirp.ID.String()+"/history", viewer, nil)
+		r.SetPathValue("chirpID", chirp.ID.String())
+		w := serve(t, cfg.handlerGetChirpHistory, r, 200)
+		var history returnChirpHistory
+		err := json.NewDecoder(w.Body).Decode(&history)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(history.Revisions)
+	}
+
+	if got := revisions(author.ID); got != 2 {
+		t.Errorf("author sees %d revisions, want 2", got)
+	}
+	if got := revisions(reader.ID); got != 1 {
+		t.Errorf("reader sees %d revisions, want 1", got)
+	}
+}
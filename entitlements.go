@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"server/internal/database"
+	"server/internal/entitlements"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) limitsFor(user database.User) entitlements.Limits {
+	return cfg.entitlements.For(entitlements.TierFor(user.IsChirpyRed))
+}
+
+func (cfg *apiConfig) userLimits(ctx context.Context, userID uuid.UUID) (entitlements.Limits, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return entitlements.Limits{}, err
+	}
+	return cfg.limitsFor(user), nil
+}
+
+// chirpTooLong counts characters rather than bytes, so non-Latin scripts
+// get the same room as everyone else.
+func chirpTooLong(body string, limits entitlements.Limits) bool {
+	return utf8.RuneCountInString(body) > limits.MaxChirpLength
+}
+
+// chirpsToday counts the chirps a user has created in the last 24 hours.
+// A chirp counts from the moment it is created, whether it is published,
+// held or scheduled, and keeps counting if it is deleted.
+func (cfg *apiConfig) chirpsToday(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return cfg.dbQueries.CountUserChirpsSince(ctx, database.CountUserChirpsSinceParams{
+		UserID: userID,
+		Since:  time.Now().Add(-24 * time.Hour),
+	})
+}
+
+// canGoLive reports whether another of userID's chirps may be published
+// now. A scheduled chirp was counted on the day it was created, but it can
+// fall due on a day the author has already used up; only chirps that went
+// live in the last 24 hours are counted against it.
+func (cfg *apiConfig) canGoLive(ctx context.Context, q *database.Queries, userID uuid.UUID) (bool, error) {
+	user, err := q.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	limits := cfg.limitsFor(user)
+	if limits.DailyChirps <= 0 {
+		return true, nil
+	}
+	live, err := q.CountUserLiveChirpsSince(ctx, database.CountUserLiveChirpsSinceParams{
+		UserID: userID,
+		Since:  time.Now().Add(-24 * time.Hour),
+	})
+	if err != nil {
+		return false, err
+	}
+	return live < int64(limits.DailyChirps), nil
+}
+
+func (cfg *apiConfig) handlerGetEntitlements(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	used, err := cfg.chirpsToday(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limits := cfg.limitsFor(user)
+	respBody := returnEntitlements{
+		Tier:           string(entitlements.TierFor(user.IsChirpyRed)),
+		MaxChirpLength: limits.MaxChirpLength,
+		MaxMedia:       limits.MaxMedia,
+		EditWindow:     time.Duration(limits.EditWindow).String(),
+		ChirpsToday:    used,
+	}
+	if limits.DailyChirps > 0 {
+		daily := limits.DailyChirps
+		remaining := max(int64(daily)-used, 0)
+		respBody.DailyChirps = &daily
+		respBody.ChirpsRemaining = &remaining
+	}
+
+	respondWithJSON(w, code, respBody)
+}
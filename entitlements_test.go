@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"server/internal/database"
+	"server/internal/entitlements"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// withDailyQuota gives free users a quota of n chirps a day.
+func withDailyQuota(cfg *apiConfig, n int) {
+	limits := cfg.entitlements[entitlements.Free]
+	limits.DailyChirps = n
+	cfg.entitlements = entitlements.Config{
+		entitlements.Free: limits,
+		entitlements.Red:  cfg.entitlements[entitlements.Red],
+	}
+}
+
+func TestScheduledChirpsCountAgainstQuota(t *testing.T) {
+	cfg := newTestConfig(t)
+	withDailyQuota(cfg, 2)
+	user := createTestUser(t, cfg)
+
+	later := time.Now().Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		chirp := createTestChirp(t, cfg, user.ID, chirpInput{PublishAt: &later})
+		if chirp.Status != chirpStatusScheduled {
+			t.Fatalf("got status %q, want %q", chirp.Status, chirpStatusScheduled)
+		}
+	}
+
+	_, err := cfg.createChirp(context.Background(), user.ID, chirpInput{Body: "one too many", PublishAt: &later})
+	if code := requestErrorCode(err); code != 429 {
+		t.Fatalf("scheduling past the quota: got %v, want a 429", err)
+	}
+	_, err = cfg.createChirp(context.Background(), user.ID, chirpInput{Body: "one too many"})
+	if code := requestErrorCode(err); code != 429 {
+		t.Fatalf("posting past the quota: got %v, want a 429", err)
+	}
+}
+
+func TestDeletedChirpsCountAgainstQuota(t *testing.T) {
+	cfg := newTestConfig(t)
+	withDailyQuota(cfg, 1)
+	user := createTestUser(t, cfg)
+
+	chirp := createTestChirp(t, cfg, user.ID, chirpInput{})
+
+	r := testRequest(t, cfg, "DELETE", "/api/chirps/"+chirp.ID.String(), user.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerDeleteChirp, r, 204)
+
+	_, err := cfg.createChirp(context.Background(), user.ID, chirpInput{Body: "again"})
+	if code := requestErrorCode(err); code != 429 {
+		t.Fatalf("posting after a delete: got %v, want a 429", err)
+	}
+}
+
+// dueScheduledChirp stores a scheduled chirp that has already fallen due,
+// bypassing the quota check on creation.
+func dueScheduledChirp(t *testing.T, cfg *apiConfig, userID uuid.UUID) database.Chirp {
+	t.Helper()
+	chirp, err := cfg.dbQueries.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:              "scheduled chirp",
+		UserID:            userID,
+		Status:            chirpStatusScheduled,
+		ModerationReasons: []string{},
+		PublishAt:         sql.NullTime{Time: time.Now().Add(-time.Minute).UTC(), Valid: true},
+		Visibility:        visibilityPublic,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chirp
+}
+
+func TestSchedulerPostponesChirpsOverQuota(t *testing.T) {
+	cfg := newTestConfig(t)
+	withDailyQuota(cfg, 1)
+	user := createTestUser(t, cfg)
+	ctx := context.Background()
+
+	createTestChirp(t, cfg, user.ID, chirpInput{})
+	scheduled := dueScheduledChirp(t, cfg, user.ID)
+
+	cfg.publishDueChirps(ctx)
+
+	chirp, err := cfg.dbQueries.GetChirp(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chirp.Status != chirpStatusScheduled {
+		t.Fatalf("got status %q, want %q", chirp.Status, chirpStatusScheduled)
+	}
+	if !chirp.PublishAt.Time.After(time.Now()) {
+		t.Fatalf("publish_at %v was not put off", chirp.PublishAt.Time)
+	}
+}
+
+func TestSchedulerPublishesChirpsWithinQuota(t *testing.T) {
+	cfg := newTestConfig(t)
+	withDailyQuota(cfg, 2)
+	user := createTestUser(t, cfg)
+	ctx := context.Background()
+
+	createTestChirp(t, cfg, user.ID, chirpInput{})
+	scheduled := dueScheduledChirp(t, cfg, user.ID)
+
+	cfg.publishDueChirps(ctx)
+
+	chirp, err := cfg.dbQueries.GetChirp(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chirp.Status != chirpStatusPublished {
+		t.Fatalf("got status %q, want %q", chirp.Status, chirpStatusPublished)
+	}
+}
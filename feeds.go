@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"path"
+	"server/internal/database"
+	"server/internal/feed"
+
+	"github.com/google/uuid"
+)
+
+// feedSize is how many of the latest chirps a feed lists.
+const feedSize = 20
+
+// chirpURL is the public link to a chirp's page.
+func (cfg *apiConfig) chirpURL(r *http.Request, chirpID uuid.UUID) string {
+	return cfg.baseURL(r) + "/chirps/" + chirpID.String()
+}
+
+func (cfg *apiConfig) feedItem(r *http.Request, chirp database.Chirp, author database.User) feed.Item {
+	return feed.Item{
+		URL:       cfg.chirpURL(r, chirp.ID),
+		Content:   chirp.Body,
+		Author:    displayName(author),
+		Published: chirp.CreatedAt,
+		Updated:   chirp.UpdatedAt,
+	}
+}
+
+// serveFeed renders f in the format named by the request path's extension.
+// The ETag is a hash of the rendered feed, so it changes whenever anything
+// in it does, including chirps that were deleted; Last-Modified is the
+// latest change to a listed chirp. http.ServeContent answers conditional
+// requests with 304 Not Modified.
+func serveFeed(w http.ResponseWriter, r *http.Request, f feed.Feed) {
+	render, contentType := feed.RSS, feed.RSSContentType
+	switch path.Ext(r.URL.Path) {
+	case ".atom":
+		render, contentType = feed.Atom, feed.AtomContentType
+	case ".json":
+		render, contentType = feed.JSON, feed.JSONContentType
+	}
+
+	body, err := render(f)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", feed.ETag(body))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(body))
+}
+
+// handlerUserFeed serves a user's latest public chirps as RSS, Atom or
+// JSON Feed. Feed readers are anonymous, so followers-only chirps are left
+// out.
+func (cfg *apiConfig) handlerUserFeed(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	// Feed readers need absolute links.
+	if cfg.baseURL(r) == "" {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetPublicUserChirps(r.Context(), database.GetPublicUserChirpsParams{
+		UserID:    user.ID,
+		PageLimit: feedSize,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	title := displayName(user)
+	if user.Handle.Valid && user.DisplayName != "" {
+		title += " (@" + user.Handle.String + ")"
+	}
+	f := feed.Feed{
+		Title:       title,
+		Description: "Chirps by " + title,
+		Link:        cfg.baseURL(r) + profilePath(user),
+		FeedURL:     cfg.baseURL(r) + r.URL.Path,
+		Updated:     user.UpdatedAt,
+	}
+	for _, chirp := range chirps {
+		f.Items = append(f.Items, cfg.feedItem(r, chirp, user))
+		if chirp.UpdatedAt.After(f.Updated) {
+			f.Updated = chirp.UpdatedAt
+		}
+	}
+
+	serveFeed(w, r, f)
+}
+
+// handlerTagFeed serves the latest public chirps tagged with a hashtag.
+func (cfg *apiConfig) handlerTagFeed(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	// Feed readers need absolute links.
+	if cfg.baseURL(r) == "" {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	tag := normalizeTag(r.PathValue("tag"))
+	if !validTag(tag) {
+		msg = "Invalid tag"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetPublicChirpsByTag(r.Context(), database.GetPublicChirpsByTagParams{
+		Tag:       tag,
+		PageLimit: feedSize,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	authorIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		authorIDs = append(authorIDs, chirp.UserID)
+	}
+	authors, err := cfg.dbQueries.GetUsersByIDs(r.Context(), authorIDs)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	authorsByID := map[uuid.UUID]database.User{}
+	for _, author := range authors {
+		authorsByID[author.ID] = author
+	}
+
+	f := feed.Feed{
+		Title:       "#" + tag + " on Chirpy",
+		Description: "Public chirps tagged #" + tag,
+		Link:        cfg.baseURL(r) + "/api/chirps",
+		FeedURL:     cfg.baseURL(r) + r.URL.Path,
+	}
+	for _, chirp := range chirps {
+		f.Items = append(f.Items, cfg.feedItem(r, chirp, authorsByID[chirp.UserID]))
+		if chirp.UpdatedAt.After(f.Updated) {
+			f.Updated = chirp.UpdatedAt
+		}
+	}
+
+	serveFeed(w, r, f)
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"server/internal/database"
+	"server/internal/notifications"
+
+	"github.com/google/uuid"
+)
+
+const (
+	followStatusPending  = "pending"
+	followStatusAccepted = "accepted"
+)
+
+func followResponse(follow database.Follow) returnFollow {
+	respFollow := returnFollow{
+		FollowerID: follow.FollowerID,
+		FolloweeID: follow.FolloweeID,
+		Status:     follow.Status,
+		CreatedAt:  follow.CreatedAt,
+	}
+	if follow.AcceptedAt.Valid {
+		respFollow.AcceptedAt = &follow.AcceptedAt.Time
+	}
+	return respFollow
+}
+
+// canSeeConnections reports whether viewer may list who user follows and
+// is followed by. Private accounts only show them to accepted followers.
+func (cfg *apiConfig) canSeeConnections(ctx context.Context, viewer uuid.UUID, user database.User) (bool, error) {
+	if !user.IsPrivate || viewer == user.ID {
+		return true, nil
+	}
+	if viewer == uuid.Nil {
+		return false, nil
+	}
+	return cfg.dbQueries.IsFollowing(ctx, database.IsFollowingParams{
+		FollowerID: viewer,
+		FolloweeID: user.ID,
+	})
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	target, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if target.ID == userID {
+		msg = "You cannot follow yourself"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	status := followStatusAccepted
+	if target.IsPrivate {
+		status = followStatusPending
+	}
+
+	// Both users are locked so a block between them, which removes their
+	// follows in a transaction that takes the same locks, cannot slip in
+	// between the check and the insert.
+	var follow database.Follow
+	isNew := false
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		_, err := q.LockUsers(r.Context(), []uuid.UUID{userID, target.ID})
+		if err != nil {
+			return err
+		}
+
+		blocked, err := q.IsBlockedEitherWay(r.Context(), database.IsBlockedEitherWayParams{
+			UserA: userID,
+			UserB: target.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return &requestError{403, "You cannot follow this user"}
+		}
+
+		_, err = q.GetFollow(r.Context(), database.GetFollowParams{
+			FollowerID: userID,
+			FolloweeID: target.ID,
+		})
+		isNew = errors.Is(err, sql.ErrNoRows)
+
+		// Following again returns the existing follow or request unchanged.
+		follow, err = q.FollowUser(r.Context(), database.FollowUserParams{
+			FollowerID: userID,
+			FolloweeID: target.ID,
+			Status:     status,
+		})
+		return err
+	})
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	if isNew {
+		typ := notifications.Follow
+		if follow.Status == followStatusPending {
+			typ = notifications.FollowRequest
+		}
+		cfg.notify(r.Context(), target.ID, typ, userID, uuid.NullUUID{})
+	}
+
+	respondWithJSON(w, code, followResponse(follow))
+}
+
+// handlerUnfollowUser also withdraws a pending follow request.
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	target, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: target.ID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, r *http.Request) {
+	cfg.listConnections(w, r, cfg.dbQueries.GetFollowers)
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, r *http.Request) {
+	cfg.listConnections(w, r, func(ctx context.Context, arg database.GetFollowersParams) ([]database.User, error) {
+		return cfg.dbQueries.GetFollowing(ctx, database.GetFollowingParams(arg))
+	})
+}
+
+func (cfg *apiConfig) listConnections(w http.ResponseWriter, r *http.Request, list func(context.Context, database.GetFollowersParams) ([]database.User, error)) {
+	msg := ""
+	code := 200
+
+	user, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	ok, err := cfg.canSeeConnections(r.Context(), cfg.viewerID(r), user)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if !ok {
+		msg = "This account is private"
+		code = 403
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	users, err := list(r.Context(), database.GetFollowersParams{
+		UserID:     user.ID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnPublicUser{}
+	for _, u := range users {
+		respBody = append(respBody, publicUser(u))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetFollowRequests(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	users, err := cfg.dbQueries.GetFollowRequests(r.Context(), database.GetFollowRequestsParams{
+		UserID:     userID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnPublicUser{}
+	for _, u := range users {
+		respBody = append(respBody, publicUser(u))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerApproveFollowRequest(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	followerID, err := uuid.Parse(r.PathValue("followerID"))
+	if err != nil {
+		msg = "Invalid user ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	follow, err := cfg.dbQueries.AcceptFollow(r.Context(), database.AcceptFollowParams{
+		FollowerID: followerID,
+		FolloweeID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "Follow request not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, followResponse(follow))
+}
+
+// handlerRejectFollowRequest turns down a pending request. It also removes
+// an accepted follower, so private accounts can drop followers too.
+func (cfg *apiConfig) handlerRejectFollowRequest(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	followerID, err := uuid.Parse(r.PathValue("followerID"))
+	if err != nil {
+		msg = "Invalid user ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	removed, err := cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: userID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if removed == 0 {
+		msg = "Follow request not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
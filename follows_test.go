@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// followUser has follower follow followee and returns the follow.
+func followUser(t *testing.T, cfg *apiConfig, follower, followee uuid.UUID) returnFollow {
+	t.Helper()
+	w := followRequest(t, cfg, follower, followee)
+	if w.Code != 200 {
+		t.Fatalf("following: got status %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp returnFollow
+	err := json.NewDecoder(w.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// listConnectionIDs lists userID's followers or following (which) as
+// viewer, failing unless the response is want.
+func listConnectionIDs(t *testing.T, cfg *apiConfig, which string, viewer, userID uuid.UUID, query string, want int) []uuid.UUID {
+	t.Helper()
+	r := testRequest(t, cfg, "GET", "/api/users/"+userID.String()+"/"+which+query, viewer, nil)
+	r.SetPathValue("id", userID.String())
+	handler := cfg.handlerGetFollowers
+	if which == "following" {
+		handler = cfg.handlerGetFollowing
+	}
+	w := serve(t, handler, r, want)
+	if want != 200 {
+		return nil
+	}
+
+	var users []returnPublicUser
+	err := json.NewDecoder(w.Body).Decode(&users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []uuid.UUID{}
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+func TestFollowersAndFollowing(t *testing.T) {
+	cfg := newTestConfig(t)
+	star := createTestUser(t, cfg)
+	first := createTestUser(t, cfg)
+	second := createTestUser(t, cfg)
+	followUser(t, cfg, first.ID, star.ID)
+	followUser(t, cfg, second.ID, star.ID)
+
+	// Following again changes nothing.
+	if got := followUser(t, cfg, first.ID, star.ID); got.Status != followStatusAccepted {
+		t.Errorf("got status %q, want %q", got.Status, followStatusAccepted)
+	}
+
+	followers := listConnectionIDs(t, cfg, "followers", uuid.Nil, star.ID, "", 200)
+	if len(followers) != 2 {
+		t.Errorf("got followers %v, want both followers", followers)
+	}
+	following := listConnectionIDs(t, cfg, "following", uuid.Nil, first.ID, "", 200)
+	if len(following) != 1 || following[0] != star.ID {
+		t.Errorf("got following %v, want %v", following, star.ID)
+	}
+
+	page := listConnectionIDs(t, cfg, "followers", uuid.Nil, star.ID, "?limit=1", 200)
+	next := listConnectionIDs(t, cfg, "followers", uuid.Nil, star.ID, "?limit=1&offset=1", 200)
+	if len(page) != 1 || len(next) != 1 || page[0] == next[0] {
+		t.Errorf("got pages %v and %v, want one follower on each", page, next)
+	}
+
+	r := testRequest(t, cfg, "GET", "/api/users/"+star.ID.String(), uuid.Nil, nil)
+	r.SetPathValue("handle", star.ID.String())
+	w := serve(t, cfg.handlerGetProfile, r, 200)
+	var profile returnProfile
+	err := json.NewDecoder(w.Body).Decode(&profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.FollowerCount != 2 || profile.FollowingCount != 0 {
+		t.Errorf("got %d followers and %d following, want 2 and 0", profile.FollowerCount, profile.FollowingCount)
+	}
+}
+
+func TestFollowingYourselfIsRefused(t *testing.T) {
+	cfg := newTestConfig(t)
+	user := createTestUser(t, cfg)
+
+	if w := followRequest(t, cfg, user.ID, user.ID); w.Code != 400 {
+		t.Errorf("got status %d, want 400", w.Code)
+	}
+}
+
+func TestPrivateAccountsApproveFollowers(t *testing.T) {
+	cfg := newTestConfig(t)
+	private := createTestUser(t, cfg)
+	fan := createTestUser(t, cfg)
+	updateProfile(t, cfg, private.ID, map[string]any{"is_private": true}, 200)
+
+	if got := followUser(t, cfg, fan.ID, private.ID); got.Status != followStatusPending {
+		t.Fatalf("got status %q, want %q", got.Status, followStatusPending)
+	}
+	// Until approved, the request does not make the fan a follower.
+	listConnectionIDs(t, cfg, "followers", fan.ID, private.ID, "", 403)
+
+	r := testRequest(t, cfg, "POST", "/api/users/me/follow-requests/"+fan.ID.String()+"/approve", private.ID, nil)
+	r.SetPathValue("followerID", fan.ID.String())
+	serve(t, cfg.handlerApproveFollowRequest, r, 200)
+
+	followers := listConnectionIDs(t, cfg, "followers", fan.ID, private.ID, "", 200)
+	if len(followers) != 1 || followers[0] != fan.ID {
+		t.Errorf("got followers %v, want the approved fan", followers)
+	}
+}
@@ -12,3 +12,5 @@ require (
 )
 
 require github.com/golang-jwt/jwt/v5 v5.2.2
+
+require github.com/gorilla/websocket v1.5.3
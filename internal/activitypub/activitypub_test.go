@@ -0,0 +1,245 @@
+package activitypub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testSigner(t *testing.T) (Signer, string) {
+	t.Helper()
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Signer{KeyID: "https://chirpy.example/ap/users/1#main-key", Key: key}, publicPEM
+}
+
+func signedRequest(t *testing.T, signer Signer, body []byte, now time.Time) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "https://remote.example/inbox", strings.NewReader(string(body)))
+	err := SignRequest(req, body, signer.KeyID, signer.Key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, publicPEM := testSigner(t)
+	publicKey, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`{"type":"Follow"}`)
+	now := time.Now()
+
+	req := signedRequest(t, signer, body, now)
+	sig, err := ParseSignature(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.KeyID != signer.KeyID {
+		t.Fatalf("keyId = %q, want %q", sig.KeyID, signer.KeyID)
+	}
+	err = VerifyRequest(req, body, publicKey, now)
+	if err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	// A different body no longer matches the signed digest.
+	err = VerifyRequest(req, []byte(`{"type":"Delete"}`), publicKey, now)
+	if !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("tampered body: err = %v", err)
+	}
+
+	// A signed header changed after signing breaks the signature.
+	req.Header.Set("Date", now.Add(time.Minute).UTC().Format(http.TimeFormat))
+	err = VerifyRequest(req, body, publicKey, now)
+	if !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("tampered date: err = %v", err)
+	}
+}
+
+func TestVerifyRejectsOldAndForeignSignatures(t *testing.T) {
+	signer, publicPEM := testSigner(t)
+	publicKey, _ := ParsePublicKey(publicPEM)
+	_, otherPEM := testSigner(t)
+	otherKey, _ := ParsePublicKey(otherPEM)
+	body := []byte(`{}`)
+	now := time.Now()
+
+	old := signedRequest(t, signer, body, now.Add(-2*MaxClockSkew))
+	if err := VerifyRequest(old, body, publicKey, now); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("stale date: err = %v", err)
+	}
+
+	// A captured request can only be replayed for a few minutes.
+	replayed := signedRequest(t, signer, body, now.Add(-30*time.Minute))
+	if err := VerifyRequest(replayed, body, publicKey, now); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("half-hour-old date: err = %v", err)
+	}
+	recent := signedRequest(t, signer, body, now.Add(-time.Minute))
+	if err := VerifyRequest(recent, body, publicKey, now); err != nil {
+		t.Fatalf("minute-old date: err = %v", err)
+	}
+
+	req := signedRequest(t, signer, body, now)
+	if err := VerifyRequest(req, body, otherKey, now); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("wrong key: err = %v", err)
+	}
+
+	unsigned := httptest.NewRequest(http.MethodPost, "/inbox", nil)
+	if err := VerifyRequest(unsigned, body, publicKey, now); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("unsigned: err = %v", err)
+	}
+}
+
+func TestActivityObject(t *testing.T) {
+	follow, err := NewActivity("https://remote.example/follows/1", "Follow", "https://remote.example/users/bob", "https://chirpy.example/ap/users/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	undo, err := NewActivity("https://remote.example/undo/1", "Undo", follow.Actor, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := follow.ObjectID(); got != "https://chirpy.example/ap/users/1" {
+		t.Fatalf("link object id = %q", got)
+	}
+	if got := undo.ObjectID(); got != follow.ID {
+		t.Fatalf("embedded object id = %q", got)
+	}
+	if got := undo.ObjectType(); got != "Follow" {
+		t.Fatalf("embedded object type = %q", got)
+	}
+	inner, err := undo.ObjectActivity()
+	if err != nil || inner.ObjectID() != "https://chirpy.example/ap/users/1" {
+		t.Fatalf("inner activity = %+v, %v", inner, err)
+	}
+}
+
+func TestHTMLContent(t *testing.T) {
+	got := HTMLContent("hi <b>there</b>\nsecond line\n\nnew & paragraph")
+	want := "<p>hi &lt;b&gt;there&lt;/b&gt;<br>second line</p><p>new &amp; paragraph</p>"
+	if got != want {
+		t.Fatalf("HTMLContent = %q, want %q", got, want)
+	}
+}
+
+func TestParseAcct(t *testing.T) {
+	user, host, err := ParseAcct("acct:alice@chirpy.example")
+	if err != nil || user != "alice" || host != "chirpy.example" {
+		t.Fatalf("got %q, %q, %v", user, host, err)
+	}
+	for _, bad := range []string{"alice@chirpy.example", "acct:alice", "acct:@chirpy.example"} {
+		if _, _, err := ParseAcct(bad); err == nil {
+			t.Fatalf("ParseAcct(%q) succeeded", bad)
+		}
+	}
+}
+
+// fakeRemote is an in-process fediverse server with one actor, whose inbox
+// only accepts activities signed by the key it was told to trust.
+type fakeRemote struct {
+	*httptest.Server
+	trusted    Signer
+	trustedPEM string
+
+	mu       sync.Mutex
+	received []Activity
+}
+
+func newFakeRemote(t *testing.T, trusted Signer, trustedPEM string) *fakeRemote {
+	remote := &fakeRemote{trusted: trusted, trustedPEM: trustedPEM}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/bob", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		json.NewEncoder(w).Encode(Actor{
+			Context:           Context,
+			ID:                remote.URL + "/users/bob",
+			Type:              "Person",
+			PreferredUsername: "bob",
+			Inbox:             remote.URL + "/users/bob/inbox",
+			Endpoints:         &Endpoints{SharedInbox: remote.URL + "/inbox"},
+			PublicKey: PublicKey{
+				ID:           remote.URL + "/users/bob#main-key",
+				Owner:        remote.URL + "/users/bob",
+				PublicKeyPem: trustedPEM,
+			},
+		})
+	})
+	mux.HandleFunc("POST /inbox", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		key, _ := ParsePublicKey(remote.trustedPEM)
+		sig, err := ParseSignature(r)
+		if err != nil || sig.KeyID != remote.trusted.KeyID || VerifyRequest(r, body, key, time.Now()) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		activity := Activity{}
+		json.Unmarshal(body, &activity)
+		remote.mu.Lock()
+		remote.received = append(remote.received, activity)
+		remote.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	})
+	remote.Server = httptest.NewServer(mux)
+	t.Cleanup(remote.Close)
+	return remote
+}
+
+func TestClientAgainstFakeRemote(t *testing.T) {
+	signer, publicPEM := testSigner(t)
+	remote := newFakeRemote(t, signer, publicPEM)
+	client := &Client{HTTP: remote.Client(), UserAgent: "chirpy-test"}
+	ctx := context.Background()
+
+	actor, err := client.FetchActor(ctx, remote.URL+"/users/bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actor.PreferredUsername != "bob" || actor.SharedInbox() != remote.URL+"/inbox" {
+		t.Fatalf("actor = %+v", actor)
+	}
+	if _, err := ParsePublicKey(actor.PublicKey.PublicKeyPem); err != nil {
+		t.Fatal(err)
+	}
+
+	note := Note{ID: "https://chirpy.example/ap/chirps/1", Type: "Note", Content: HTMLContent("hello"), To: []string{Public}}
+	create, err := NewActivity(note.ID+"/activity", "Create", "https://chirpy.example/ap/users/1", note)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Deliver(ctx, actor.SharedInbox(), create, signer)
+	if err != nil {
+		t.Fatalf("delivery failed: %v", err)
+	}
+
+	remote.mu.Lock()
+	received := remote.received
+	remote.mu.Unlock()
+	if len(received) != 1 || received[0].Type != "Create" || received[0].ObjectID() != note.ID {
+		t.Fatalf("remote received %+v", received)
+	}
+
+	// An activity signed by a key the remote does not trust is refused.
+	stranger, _ := testSigner(t)
+	stranger.KeyID = "https://chirpy.example/ap/users/2#main-key"
+	err = client.Deliver(ctx, actor.SharedInbox(), create, stranger)
+	if err == nil {
+		t.Fatal("delivery with an untrusted key succeeded")
+	}
+}
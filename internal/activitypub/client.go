@@ -0,0 +1,93 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxDocumentBytes bounds the documents read from remote servers.
+const maxDocumentBytes = 1 << 20
+
+// Signer is the actor key requests are signed with.
+type Signer struct {
+	KeyID string
+	Key   *rsa.PrivateKey
+}
+
+// Client fetches documents from and delivers activities to remote
+// servers.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+}
+
+func (c *Client) do(req *http.Request, body []byte, signer *Signer) (*http.Response, error) {
+	req.Header.Set("User-Agent", c.UserAgent)
+	if signer != nil {
+		err := SignRequest(req, body, signer.KeyID, signer.Key, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.HTTP.Do(req)
+}
+
+// FetchActor retrieves the actor document at uri. Servers that require
+// signed fetches are sent a request signed by signer, which may be nil.
+func (c *Client) FetchActor(ctx context.Context, uri string, signer *Signer) (Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", AcceptHeader)
+
+	resp, err := c.do(req, nil, signer)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, fmt.Errorf("fetching %s: %s", uri, resp.Status)
+	}
+
+	actor := Actor{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxDocumentBytes)).Decode(&actor)
+	if err != nil {
+		return Actor{}, fmt.Errorf("fetching %s: %w", uri, err)
+	}
+	if actor.ID == "" || actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" {
+		return Actor{}, fmt.Errorf("fetching %s: incomplete actor", uri)
+	}
+	return actor, nil
+}
+
+// Deliver posts a signed activity to an inbox. Any 2xx response counts as
+// delivered.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity any, signer Signer) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	resp, err := c.do(req, body, &signer)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("delivering to %s: %s", inbox, resp.Status)
+	}
+	return nil
+}
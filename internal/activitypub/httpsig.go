@@ -0,0 +1,170 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxClockSkew is how far a signed request's Date header may be from the
+// current time before the signature is refused, which limits replays.
+const MaxClockSkew = 5 * time.Minute
+
+var (
+	ErrNoSignature  = errors.New("request is not signed")
+	ErrBadSignature = errors.New("invalid request signature")
+)
+
+// Digest returns the Digest header value for a request body.
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// signingString builds the text covered by a signature over headers, as
+// described by draft-cavage-http-signatures.
+func signingString(req *http.Request, headers []string) (string, error) {
+	lines := []string{}
+	for _, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, h+": "+strings.ToLower(req.Method)+" "+req.URL.RequestURI())
+		case "host":
+			lines = append(lines, "host: "+requestHost(req))
+		default:
+			values := req.Header.Values(h)
+			if len(values) == 0 {
+				return "", fmt.Errorf("signed header %q is missing", h)
+			}
+			lines = append(lines, h+": "+strings.Join(values, ", "))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// SignRequest signs req with the actor key keyID, setting its Date,
+// Digest (when there is a body) and Signature headers.
+func SignRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey, now time.Time) error {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", Digest(body))
+		headers = append(headers, "digest")
+	}
+
+	text, err := signingString(req, headers)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(text))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Signature is a parsed Signature header.
+type Signature struct {
+	KeyID     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// ParseSignature reads req's Signature header.
+func ParseSignature(req *http.Request) (Signature, error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return Signature{}, ErrNoSignature
+	}
+
+	params := map[string]string{}
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Signature{}, ErrBadSignature
+		}
+		params[name] = strings.Trim(value, `"`)
+	}
+
+	sig := Signature{
+		KeyID:     params["keyId"],
+		Algorithm: params["algorithm"],
+		Headers:   strings.Fields(strings.ToLower(params["headers"])),
+	}
+	if len(sig.Headers) == 0 {
+		sig.Headers = []string{"date"}
+	}
+	raw, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || sig.KeyID == "" || len(raw) == 0 {
+		return Signature{}, ErrBadSignature
+	}
+	sig.Signature = raw
+	return sig, nil
+}
+
+// VerifyRequest checks req's signature against key. The signature must
+// cover the request target, host and date, and for requests with a body
+// also a digest that matches it. The date must be within MaxClockSkew of
+// now.
+func VerifyRequest(req *http.Request, body []byte, key *rsa.PublicKey, now time.Time) error {
+	sig, err := ParseSignature(req)
+	if err != nil {
+		return err
+	}
+	if sig.Algorithm != "" && sig.Algorithm != "rsa-sha256" && sig.Algorithm != "hs2019" {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrBadSignature, sig.Algorithm)
+	}
+
+	required := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(sig.Headers, h) {
+			return fmt.Errorf("%w: %s is not signed", ErrBadSignature, h)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("%w: invalid date", ErrBadSignature)
+	}
+	if date.Before(now.Add(-MaxClockSkew)) || date.After(now.Add(MaxClockSkew)) {
+		return fmt.Errorf("%w: date is too far from now", ErrBadSignature)
+	}
+
+	if body != nil && req.Header.Get("Digest") != Digest(body) {
+		return fmt.Errorf("%w: digest does not match body", ErrBadSignature)
+	}
+
+	text, err := signingString(req, sig.Headers)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	hash := sha256.Sum256([]byte(text))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig.Signature)
+	if err != nil {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Package activitypub implements the parts of ActivityPub, WebFinger and
+// HTTP Signatures that Chirpy needs to let users be followed from other
+// fediverse servers: actor and note documents, signed delivery of
+// activities, and verification of the activities delivered to us.
+package activitypub
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"strings"
+	"time"
+)
+
+const (
+	// ContentType is the media type activities are sent with.
+	ContentType = "application/activity+json"
+	// AcceptHeader asks remote servers for ActivityPub documents.
+	AcceptHeader = `application/activity+json, application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	SecurityContext        = "https://w3id.org/security/v1"
+
+	// Public is the special collection addressing an object to everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Context is the JSON-LD context sent with actors and activities.
+var Context = []string{ActivityStreamsContext, SecurityContext}
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Image struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type Actor struct {
+	Context                   any        `json:"@context,omitempty"`
+	ID                        string     `json:"id"`
+	Type                      string     `json:"type"`
+	PreferredUsername         string     `json:"preferredUsername"`
+	Name                      string     `json:"name,omitempty"`
+	Summary                   string     `json:"summary,omitempty"`
+	URL                       string     `json:"url,omitempty"`
+	Inbox                     string     `json:"inbox"`
+	Outbox                    string     `json:"outbox,omitempty"`
+	Followers                 string     `json:"followers,omitempty"`
+	Endpoints                 *Endpoints `json:"endpoints,omitempty"`
+	ManuallyApprovesFollowers bool       `json:"manuallyApprovesFollowers"`
+	PublicKey                 PublicKey  `json:"publicKey"`
+	Icon                      *Image     `json:"icon,omitempty"`
+}
+
+// SharedInbox returns the inbox to deliver public activities to, which is
+// the actor's shared inbox when it has one.
+func (a Actor) SharedInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+type Note struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	AttributedTo string     `json:"attributedTo"`
+	Content      string     `json:"content"`
+	Published    time.Time  `json:"published"`
+	Updated      *time.Time `json:"updated,omitempty"`
+	To           []string   `json:"to"`
+	Cc           []string   `json:"cc,omitempty"`
+	InReplyTo    string     `json:"inReplyTo,omitempty"`
+	URL          string     `json:"url,omitempty"`
+}
+
+type Tombstone struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Activity is any activity. Object stays raw because it may be a link or
+// an embedded object of any type; see ObjectID and ObjectType.
+type Activity struct {
+	Context   any             `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+	Published *time.Time      `json:"published,omitempty"`
+}
+
+// NewActivity wraps object in an activity with the default context.
+func NewActivity(id, typ, actor string, object any) (Activity, error) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		return Activity{}, err
+	}
+	return Activity{
+		Context: Context,
+		ID:      id,
+		Type:    typ,
+		Actor:   actor,
+		Object:  raw,
+	}, nil
+}
+
+// ObjectID returns the id of an activity's object, whether it was given as
+// a link or embedded.
+func (a Activity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var obj struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(a.Object, &obj)
+	return obj.ID
+}
+
+// ObjectType returns the type of an embedded object, or "" for links.
+func (a Activity) ObjectType() string {
+	var obj struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(a.Object, &obj)
+	return obj.Type
+}
+
+// ObjectActivity decodes an embedded object that is itself an activity,
+// such as the Follow inside an Undo.
+func (a Activity) ObjectActivity() (Activity, error) {
+	inner := Activity{}
+	err := json.Unmarshal(a.Object, &inner)
+	return inner, err
+}
+
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	TotalItems   int64  `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// HTMLContent turns plain chirp text into the HTML a Note carries.
+func HTMLContent(text string) string {
+	paragraphs := []string{}
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		paragraphs = append(paragraphs, "<p>"+strings.ReplaceAll(html.EscapeString(p), "\n", "<br>")+"</p>")
+	}
+	return strings.Join(paragraphs, "")
+}
+
+// JRD is a WebFinger response.
+type JRD struct {
+	Subject string    `json:"subject"`
+	Aliases []string  `json:"aliases,omitempty"`
+	Links   []JRDLink `json:"links"`
+}
+
+type JRDLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// ParseAcct splits a WebFinger resource such as acct:alice@example.com
+// into its user and host.
+func ParseAcct(resource string) (string, string, error) {
+	acct, ok := strings.CutPrefix(resource, "acct:")
+	if !ok {
+		return "", "", errors.New("resource is not an acct: URI")
+	}
+	acct = strings.TrimPrefix(acct, "@")
+	user, host, ok := strings.Cut(acct, "@")
+	if !ok || user == "" || host == "" {
+		return "", "", errors.New("malformed acct: URI")
+	}
+	return user, host, nil
+}
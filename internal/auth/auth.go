@@ -33,16 +33,32 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	userID, _, err := ParseJWT(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ParseJWT validates a token like ValidateJWT and also returns when it
+// expires, for connections that outlive a single request.
+func ParseJWT(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil })
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
 	}
 
 	subject, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+
+	expiresAt, err := token.Claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return uuid.Nil, time.Time{}, fmt.Errorf("token has no expiry")
 	}
-	return uuid.Parse(subject)
+	return userID, expiresAt.Time, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -81,6 +81,31 @@ func TestValidateJWT_WrongSecret(t *testing.T) {
 	}
 }
 
+func TestParseJWT_ReturnsExpiry(t *testing.T) {
+	userID := uuid.New()
+	tokenSecret := "test-secret"
+	expiresIn := time.Hour
+
+	token, err := MakeJWT(userID, tokenSecret, expiresIn)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	parsedUserID, expiresAt, err := ParseJWT(token, tokenSecret)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if parsedUserID != userID {
+		t.Fatalf("Expected user ID %v, got %v", userID, parsedUserID)
+	}
+
+	// JWT expiry times have one-second precision.
+	want := time.Now().Add(expiresIn)
+	if expiresAt.Before(want.Add(-2*time.Second)) || expiresAt.After(want.Add(time.Second)) {
+		t.Fatalf("Expected expiry around %v, got %v", want, expiresAt)
+	}
+}
+
 func TestValidateJWT_InvalidToken(t *testing.T) {
 	tokenSecret := "test-secret"
 	invalidToken := "invalid.token.here"
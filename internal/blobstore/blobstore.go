@@ -0,0 +1,37 @@
+// Package blobstore stores uploaded files behind a small interface so the
+// server can keep them on local disk or in an S3-compatible bucket.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no blob has the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore holds opaque blobs addressed by slash-separated keys. Deleting
+// a key that does not exist is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape the store's root, such as
+// absolute paths or ones containing "..".
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+var errInvalidKey = errors.New("invalid blob key")
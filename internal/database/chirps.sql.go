@@ -7,29 +7,151 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const claimDueChirps = `-- name: ClaimDueChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps
+WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+ORDER BY publish_at
+LIMIT $1::int
+FOR UPDATE SKIP LOCKED
+`
+
+func (q *Queries) ClaimDueChirps(ctx context.Context, batchSize int32) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueChirps, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countChirpReplies = `-- name: CountChirpReplies :one
+SELECT COUNT(*) FROM chirps WHERE in_reply_to = $1
+`
+
+func (q *Queries) CountChirpReplies(ctx context.Context, inReplyTo uuid.NullUUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpReplies, inReplyTo)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserChirps = `-- name: CountUserChirps :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND hidden_at IS NULL AND visibility = 'public'
+`
+
+func (q *Queries) CountUserChirps(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserChirps, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserChirpsSince = `-- name: CountUserChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND created_at >= $2
+`
+
+type CountUserChirpsSinceParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+}
+
+func (q *Queries) CountUserChirpsSince(ctx context.Context, arg CountUserChirpsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserChirpsSince, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserLiveChirpsSince = `-- name: CountUserLiveChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = $1 AND created_at >= $2 AND status <> 'scheduled'
+`
+
+type CountUserLiveChirpsSinceParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+}
+
+func (q *Queries) CountUserLiveChirpsSince(ctx context.Context, arg CountUserLiveChirpsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserLiveChirpsSince, arg.UserID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
-    gen_random_uuid(),
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, quote_of, status, moderation_reasons, publish_at, visibility)
+SELECT
+    new_chirp.id,
     NOW(),
     NOW(),
-    $1,
-    $2
-)
-RETURNING id, created_at, updated_at, body, user_id
+    $1::text,
+    $2::uuid,
+    $3::uuid,
+    COALESCE((SELECT conversation_id FROM chirps WHERE id = $3::uuid), new_chirp.id),
+    $4::uuid,
+    $5::text,
+    $6::text[],
+    $7::timestamp,
+    $8::text
+FROM (SELECT gen_random_uuid() AS id) AS new_chirp
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body              string
+	UserID            uuid.UUID
+	InReplyTo         uuid.NullUUID
+	QuoteOf           uuid.NullUUID
+	Status            string
+	ModerationReasons []string
+	PublishAt         sql.NullTime
+	Visibility        string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.InReplyTo, arg.QuoteOf, arg.Status, pq.Array(arg.ModerationReasons), arg.PublishAt, arg.Visibility)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +159,19 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.Status,
+		pq.Array(&i.ModerationReasons),
+		&i.HiddenAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -51,11 +186,24 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps
+WHERE tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND (hidden_at IS NULL OR $1::bool)
+AND (visibility = 'public' OR user_id = $2
+    OR (visibility = 'followers' AND EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $2 AND f.followee_id = chirps.user_id AND f.status = 'accepted')))
+AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = $2 AND b.blocked_id = chirps.user_id)
+    OR (b.blocker_id = chirps.user_id AND b.blocked_id = $2))
+AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = $2 AND m.muted_id = chirps.user_id
+    AND (m.expires_at IS NULL OR m.expires_at > NOW()))
+ORDER BY created_at ASC
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+type GetAllChirpsParams struct {
+	IncludeHidden bool
+	ViewerID      uuid.UUID
+}
+
+func (q *Queries) GetAllChirps(ctx context.Context, arg GetAllChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirps, arg.IncludeHidden, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +217,19 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -84,11 +245,23 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getAllUserChirps = `-- name: GetAllUserChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at ASC
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps
+WHERE user_id = $1 AND tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND (hidden_at IS NULL OR $2::bool)
+AND (visibility = 'public' OR user_id = $3
+    OR (visibility = 'followers' AND EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $3 AND f.followee_id = chirps.user_id AND f.status = 'accepted')))
+AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = $3 AND b.blocked_id = chirps.user_id)
+    OR (b.blocker_id = chirps.user_id AND b.blocked_id = $3))
+ORDER BY created_at ASC
 `
 
-func (q *Queries) GetAllUserChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllUserChirps, userID)
+type GetAllUserChirpsParams struct {
+	UserID        uuid.UUID
+	IncludeHidden bool
+	ViewerID      uuid.UUID
+}
+
+func (q *Queries) GetAllUserChirps(ctx context.Context, arg GetAllUserChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserChirps, arg.UserID, arg.IncludeHidden, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +275,19 @@ func (q *Queries) GetAllUserChirps(ctx context.Context, userID uuid.UUID) ([]Chi
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -117,7 +303,7 @@ func (q *Queries) GetAllUserChirps(ctx context.Context, userID uuid.UUID) ([]Chi
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps WHERE id = $1
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -129,6 +315,509 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.Status,
+		pq.Array(&i.ModerationReasons),
+		&i.HiddenAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT p.id, p.in_reply_to, 1 AS depth FROM chirps p WHERE p.id = (SELECT in_reply_to FROM chirps WHERE id = $1)
+    UNION ALL
+    SELECT p.id, p.in_reply_to, a.depth + 1 FROM chirps p INNER JOIN ancestors a ON p.id = a.in_reply_to
+)
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.conversation_id, c.tombstoned_at, c.like_count, c.rechirp_count, c.quote_of, c.edited_at, c.status, c.moderation_reasons, c.hidden_at, c.publish_at, c.visibility, c.deleted_at FROM chirps c INNER JOIN ancestors a ON c.id = a.id ORDER BY a.depth DESC
+`
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT r.id, 1 AS depth, ARRAY[to_char(r.created_at, 'YYYYMMDDHH24MISSUS') || r.id::text] AS path
+    FROM chirps r WHERE r.in_reply_to = $1::uuid
+    UNION ALL
+    SELECT r.id, d.depth + 1, d.path || (to_char(r.created_at, 'YYYYMMDDHH24MISSUS') || r.id::text)
+    FROM chirps r INNER JOIN descendants d ON r.in_reply_to = d.id
+),
+-- A reply the viewer may not see (e.g. deleted or held after an edit) is
+-- still returned when a reply below it is visible, so the tree stays intact.
+visible AS (
+    SELECT d.id, d.depth, d.path, (c.status = 'published' AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+        AND (c.visibility <> 'followers' OR c.user_id = $2
+            OR EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = $2 AND f.followee_id = c.user_id AND f.status = 'accepted'))
+        AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = $2 AND b.blocked_id = c.user_id)
+            OR (b.blocker_id = c.user_id AND b.blocked_id = $2)))::boolean AS visible
+    FROM chirps c INNER JOIN descendants d ON c.id = d.id
+)
+SELECT c.id, c.created_at, c.updated_at, c.body, c.user_id, c.in_reply_to, c.conversation_id, c.tombstoned_at, c.like_count, c.rechirp_count, c.quote_of, c.edited_at, c.status, c.moderation_reasons, c.hidden_at, c.publish_at, c.visibility, c.deleted_at, d.depth, d.visible FROM chirps c INNER JOIN visible d ON c.id = d.id
+WHERE d.visible OR EXISTS (SELECT 1 FROM visible v WHERE v.visible AND v.depth > d.depth AND v.path[1:d.depth] = d.path)
+ORDER BY d.path
+LIMIT $3::int OFFSET $4::int
+`
+
+type GetChirpDescendantsParams struct {
+	ChirpID    uuid.UUID
+	ViewerID   uuid.UUID
+	PageLimit  int32
+	PageOffset int32
+}
+
+type GetChirpDescendantsRow struct {
+	Chirp   Chirp
+	Depth   int32
+	Visible bool
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.ChirpID, arg.ViewerID, arg.PageLimit, arg.PageOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.ConversationID,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.LikeCount,
+			&i.Chirp.RechirpCount,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.EditedAt,
+			&i.Chirp.Status,
+			&i.Chirp.ModerationReasons,
+			&i.Chirp.HiddenAt,
+			&i.Chirp.PublishAt,
+			&i.Chirp.Visibility,
+			&i.Chirp.DeletedAt,
+			&i.Depth,
+			&i.Visible,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredDeletedChirps = `-- name: GetExpiredDeletedChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps WHERE deleted_at <= $1
+ORDER BY deleted_at
+LIMIT $2::int
+`
+
+type GetExpiredDeletedChirpsParams struct {
+	DeletedBefore sql.NullTime
+	BatchSize     int32
+}
+
+func (q *Queries) GetExpiredDeletedChirps(ctx context.Context, arg GetExpiredDeletedChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getExpiredDeletedChirps, arg.DeletedBefore, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublicChirpsByTag = `-- name: GetPublicChirpsByTag :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps
+WHERE tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND hidden_at IS NULL AND visibility = 'public'
+AND body ~* ('(^|[^A-Za-z0-9_#&])#' || $1::text || '([^A-Za-z0-9_]|$)')
+ORDER BY created_at DESC
+LIMIT $2::int
+`
+
+type GetPublicChirpsByTagParams struct {
+	Tag       string
+	PageLimit int32
+}
+
+func (q *Queries) GetPublicChirpsByTag(ctx context.Context, arg GetPublicChirpsByTagParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicChirpsByTag, arg.Tag, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPublicUserChirps = `-- name: GetPublicUserChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps
+WHERE user_id = $1 AND tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND hidden_at IS NULL AND visibility = 'public'
+ORDER BY created_at DESC
+LIMIT $2::int
+`
+
+type GetPublicUserChirpsParams struct {
+	UserID    uuid.UUID
+	PageLimit int32
+}
+
+func (q *Queries) GetPublicUserChirps(ctx context.Context, arg GetPublicUserChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getPublicUserChirps, arg.UserID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserScheduledChirps = `-- name: GetUserScheduledChirps :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at FROM chirps
+WHERE user_id = $1 AND status = 'scheduled' AND tombstoned_at IS NULL AND deleted_at IS NULL
+ORDER BY publish_at ASC
+`
+
+func (q *Queries) GetUserScheduledChirps(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUserScheduledChirps, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.ConversationID,
+			&i.TombstonedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.Status,
+			pq.Array(&i.ModerationReasons),
+			&i.HiddenAt,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const postponeScheduledChirp = `-- name: PostponeScheduledChirp :exec
+UPDATE chirps SET publish_at = $1, updated_at = NOW()
+WHERE id = $2
+`
+
+type PostponeScheduledChirpParams struct {
+	PublishAt sql.NullTime
+	ID        uuid.UUID
+}
+
+func (q *Queries) PostponeScheduledChirp(ctx context.Context, arg PostponeScheduledChirpParams) error {
+	_, err := q.db.ExecContext(ctx, postponeScheduledChirp, arg.PublishAt, arg.ID)
+	return err
+}
+
+const publishScheduledChirp = `-- name: PublishScheduledChirp :one
+UPDATE chirps SET status = 'published', created_at = publish_at, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at
+`
+
+func (q *Queries) PublishScheduledChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, publishScheduledChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.Status,
+		pq.Array(&i.ModerationReasons),
+		&i.HiddenAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const restoreChirp = `-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL
+WHERE id = $1 AND deleted_at > $2
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, tombstoned_at, like_count, rechirp_count, quote_of, edited_at, status, moderation_reasons, hidden_at, publish_at, visibility, deleted_at
+`
+
+type RestoreChirpParams struct {
+	ID           uuid.UUID
+	DeletedAfter sql.NullTime
+}
+
+func (q *Queries) RestoreChirp(ctx context.Context, arg RestoreChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, restoreChirp, arg.ID, arg.DeletedAfter)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.ConversationID,
+		&i.TombstonedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.Status,
+		pq.Array(&i.ModerationReasons),
+		&i.HiddenAt,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.DeletedAt,
 	)
 	return i, err
 }
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps SET deleted_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps SET body = '', updated_at = NOW(), tombstoned_at = NOW(), deleted_at = NULL WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
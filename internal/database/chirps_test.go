@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublicUserChirpsNewestFirst(t *testing.T) {
+	q := testQueries(t)
+	ctx := context.Background()
+
+	author := testUser(t, q)
+	public := []uuid.UUID{}
+	for i := 0; i < 5; i++ {
+		public = append(public, testChirp(t, q, author, "public"))
+	}
+	testChirp(t, q, author, "followers")
+
+	chirps, err := q.GetPublicUserChirps(ctx, GetPublicUserChirpsParams{
+		UserID:    author,
+		PageLimit: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uuid.UUID{public[4], public[3], public[2]}
+	if len(chirps) != len(want) {
+		t.Fatalf("got %d chirps, want %d", len(chirps), len(want))
+	}
+	for i, chirp := range chirps {
+		if chirp.ID != want[i] {
+			t.Errorf("chirp %d: got %v, want %v", i, chirp.ID, want[i])
+		}
+	}
+}
@@ -11,12 +11,182 @@ import (
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Body              string
+	UserID            uuid.UUID
+	InReplyTo         uuid.NullUUID
+	ConversationID    uuid.UUID
+	TombstonedAt      sql.NullTime
+	LikeCount         int32
+	RechirpCount      int32
+	QuoteOf           uuid.NullUUID
+	EditedAt          sql.NullTime
+	Status            string
+	ModerationReasons []string
+	HiddenAt          sql.NullTime
+	PublishAt         sql.NullTime
+	Visibility        string
+	DeletedAt         sql.NullTime
+}
+
+type ChirpEvent struct {
+	ID             int64
+	CreatedAt      time.Time
+	Type           string
+	ChirpID        uuid.UUID
+	UserID         uuid.UUID
+	Tags           []string
+	Visibility     string
+	ConversationID uuid.NullUUID
+	RechirpedBy    uuid.NullUUID
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+	Status     string
+}
+
+type Conversation struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	CreatedBy uuid.NullUUID
+	IsGroup   bool
+}
+
+type ConversationParticipant struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     sql.NullTime
+	LeftAt         sql.NullTime
+}
+
+type Draft struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
 	Body      string
+	InReplyTo uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	Status     string
+	CreatedAt  time.Time
+	AcceptedAt sql.NullTime
+}
+
+type Like struct {
 	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type MediaFile struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UserID       uuid.UUID
+	ContentType  string
+	SizeBytes    int64
+	Width        int32
+	Height       int32
+	StorageKey   string
+	ThumbnailKey string
+	ChirpID      uuid.NullUUID
+	Position     int32
+}
+
+type Message struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+}
+
+type ModerationAction struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ModeratorID  uuid.NullUUID
+	ReportID     uuid.NullUUID
+	ChirpID      uuid.NullUUID
+	TargetUserID uuid.UUID
+	Action       string
+	Note         string
+	ChirpBody    string
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt sql.NullTime
+}
+
+type Notification struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Type       string
+	GroupKey   string
+	ChirpID    uuid.NullUUID
+	ActorCount int32
+	ReadAt     sql.NullTime
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type PolkaEvent struct {
+	EventID   string
+	CreatedAt time.Time
+	Event     string
+}
+
+type PolkaWebhook struct {
+	ID         uuid.UUID
+	ReceivedAt time.Time
+	EventID    string
+	Event      string
+	Signature  string
+	Body       []byte
+	Result     string
+	StatusCode int32
+	Error      string
+}
+
+type Rechirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -28,6 +198,57 @@ type RefreshToken struct {
 	UserID    uuid.UUID
 }
 
+type RemoteActor struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Uri               string
+	KeyID             string
+	Inbox             string
+	SharedInbox       string
+	PreferredUsername string
+	PublicKeyPem      string
+}
+
+type RemoteFollow struct {
+	RemoteActorID uuid.UUID
+	UserID        uuid.UUID
+	ActivityID    string
+	CreatedAt     time.Time
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ChirpID    uuid.UUID
+	ReporterID uuid.NullUUID
+	Reason     string
+	Details    string
+	Status     string
+	ResolvedBy uuid.NullUUID
+	ResolvedAt sql.NullTime
+	Resolution string
+}
+
+type Subscription struct {
+	UserID           uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Status           string
+	CurrentPeriodEnd sql.NullTime
+}
+
+type SubscriptionEvent struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UserID           uuid.UUID
+	Event            string
+	Status           string
+	CurrentPeriodEnd sql.NullTime
+	PolkaEventID     string
+}
+
 type User struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
@@ -35,4 +256,50 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarUrl      string
+	IsModerator    bool
+	SuspendedUntil sql.NullTime
+	IsPrivate      bool
+}
+
+type WebhookAttempt struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	DeliveryID   uuid.UUID
+	Attempt      int32
+	StatusCode   int32
+	ResponseBody string
+	Error        string
+	DurationMs   int32
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	EndpointID     uuid.UUID
+	EventID        uuid.UUID
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode int32
+	LastError      string
+	DeliveredAt    sql.NullTime
+	RedeliveryOf   uuid.NullUUID
+}
+
+type WebhookEndpoint struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Url        string
+	Events     []string
+	Secret     string
+	VerifiedAt sql.NullTime
 }
@@ -64,7 +64,7 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 }
 
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT id, u.created_at, u.updated_at, email, hashed_password, is_chirpy_red, token, r.created_at, r.updated_at, expires_at, revoked_at, user_id FROM users u INNER JOIN refresh_tokens r ON u.id = r.user_id WHERE r.token = $1
+SELECT id, u.created_at, u.updated_at, email, hashed_password, is_chirpy_red, handle, display_name, bio, avatar_url, is_moderator, suspended_until, is_private, token, r.created_at, r.updated_at, expires_at, revoked_at, user_id FROM users u INNER JOIN refresh_tokens r ON u.id = r.user_id WHERE r.token = $1
 `
 
 type GetUserFromRefreshTokenRow struct {
@@ -74,6 +74,13 @@ type GetUserFromRefreshTokenRow struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarUrl      string
+	IsModerator    bool
+	SuspendedUntil sql.NullTime
+	IsPrivate      bool
 	Token          string
 	CreatedAt_2    time.Time
 	UpdatedAt_2    time.Time
@@ -92,6 +99,13 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Ge
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.IsPrivate,
 		&i.Token,
 		&i.CreatedAt_2,
 		&i.UpdatedAt_2,
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testSubscription(t *testing.T, q *Queries, userID uuid.UUID, periodEnd sql.NullTime) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := q.UpsertSubscription(ctx, UpsertSubscriptionParams{
+		UserID:           userID,
+		Status:           "active",
+		CurrentPeriodEnd: periodEnd,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.SetChirpyRed(ctx, SetChirpyRedParams{ID: userID, IsChirpyRed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpireSubscriptions(t *testing.T) {
+	q := testQueries(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	lapsed := testUser(t, q)
+	testSubscription(t, q, lapsed, sql.NullTime{Time: now.Add(-time.Hour), Valid: true})
+	current := testUser(t, q)
+	testSubscription(t, q, current, sql.NullTime{Time: now.Add(time.Hour), Valid: true})
+	endless := testUser(t, q)
+	testSubscription(t, q, endless, sql.NullTime{})
+
+	expired, err := q.ExpireSubscriptions(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(expired, lapsed) {
+		t.Error("lapsed membership not expired")
+	}
+	if slices.Contains(expired, current) || slices.Contains(expired, endless) {
+		t.Error("running membership expired")
+	}
+
+	user, err := q.GetUserByID(ctx, lapsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsChirpyRed {
+		t.Error("expired member kept Chirpy Red")
+	}
+}
+
+// TestExpireSubscriptionsWaitsForRenewal renews a lapsed membership the
+// way the Polka webhook does, locking the user and then the membership,
+// while the expiry job runs.
+func TestExpireSubscriptionsWaitsForRenewal(t *testing.T) {
+	db := testDB(t)
+	q := New(db)
+	ctx := context.Background()
+
+	userID := testUser(t, q)
+	testSubscription(t, q, userID, sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true})
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	qtx := q.WithTx(tx)
+	_, err = qtx.LockUser(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		expired []uuid.UUID
+		err     error
+	}
+	done := make(chan result)
+	go func() {
+		expired, err := q.ExpireSubscriptions(ctx, time.Now())
+		done <- result{expired, err}
+	}()
+
+	// Give the expiry job time to reach the locked user.
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = qtx.GetSubscriptionForUpdate(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = qtx.UpsertSubscription(ctx, UpsertSubscriptionParams{
+		UserID:           userID,
+		Status:           "active",
+		CurrentPeriodEnd: sql.NullTime{Time: time.Now().Add(30 * 24 * time.Hour), Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if slices.Contains(res.expired, userID) {
+		t.Error("renewed membership expired")
+	}
+	sub, err := q.GetSubscription(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Status != "active" {
+		t.Errorf("got status %q, want active", sub.Status)
+	}
+}
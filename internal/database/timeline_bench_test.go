@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Seeded dataset for the timeline benchmarks: the viewer follows half of
+// the authors, every author has a history of chirps, and each followed
+// author has rechirped a few chirps by people the viewer does not follow.
+const (
+	benchAuthors         = 300
+	benchFollowed        = 150
+	benchChirpsPerAuthor = 30
+	benchRechirpsEach    = 2
+)
+
+type timelineDataset struct {
+	queries *Queries
+	viewer  uuid.UUID
+}
+
+var (
+	timelineOnce    sync.Once
+	timelineData    timelineDataset
+	timelineErr     error
+	timelineCleanup = func() {}
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	timelineCleanup()
+	os.Exit(code)
+}
+
+// seededTimeline returns the benchmark dataset, seeding it the first time.
+// The testing package runs each benchmark several times while it settles
+// on b.N, so the dataset is shared rather than rebuilt by every run. It
+// needs TEST_DB_URL to point at a migrated database.
+func seededTimeline(b *testing.B) timelineDataset {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		b.Skip("TEST_DB_URL is not set")
+	}
+	timelineOnce.Do(func() {
+		timelineData, timelineErr = seedTimeline(url)
+	})
+	if timelineErr != nil {
+		b.Fatal(timelineErr)
+	}
+	return timelineData
+}
+
+// seedTimeline adds its own users and arranges for TestMain to remove them
+// (and, by cascade, everything else it made) once all benchmarks are done.
+func seedTimeline(url string) (timelineDataset, error) {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return timelineDataset{}, err
+	}
+
+	ctx := context.Background()
+	q := New(db)
+
+	users := []uuid.UUID{}
+	timelineCleanup = func() {
+		db.Exec("DELETE FROM users WHERE id = ANY($1::uuid[])", pq.Array(users))
+		db.Close()
+	}
+
+	newUser := func() (uuid.UUID, error) {
+		user, err := q.CreateUser(ctx, CreateUserParams{
+			Email:          uuid.NewString() + "@bench.invalid",
+			HashedPassword: "unused",
+		})
+		if err != nil {
+			return uuid.Nil, err
+		}
+		users = append(users, user.ID)
+		return user.ID, nil
+	}
+
+	viewer, err := newUser()
+	if err != nil {
+		return timelineDataset{}, err
+	}
+
+	chirps := map[uuid.UUID][]uuid.UUID{}
+	for i := 0; i < benchAuthors; i++ {
+		author, err := newUser()
+		if err != nil {
+			return timelineDataset{}, err
+		}
+
+		for j := 0; j < benchChirpsPerAuthor; j++ {
+			chirp, err := q.CreateChirp(ctx, CreateChirpParams{
+				Body:              "benchmark chirp",
+				UserID:            author,
+				Status:            "published",
+				ModerationReasons: []string{},
+				Visibility:        "public",
+			})
+			if err != nil {
+				return timelineDataset{}, err
+			}
+			chirps[author] = append(chirps[author], chirp.ID)
+		}
+
+		if i < benchFollowed {
+			_, err = q.FollowUser(ctx, FollowUserParams{
+				FollowerID: viewer,
+				FolloweeID: author,
+				Status:     "accepted",
+			})
+			if err != nil {
+				return timelineDataset{}, err
+			}
+		}
+	}
+
+	for i := 0; i < benchFollowed; i++ {
+		rechirper := users[1+i]
+		for j := 0; j < benchRechirpsEach; j++ {
+			unfollowed := users[1+benchFollowed+(i+j)%(benchAuthors-benchFollowed)]
+			_, err = q.Rechirp(ctx, RechirpParams{
+				UserID:  rechirper,
+				ChirpID: chirps[unfollowed][j],
+			})
+			if err != nil {
+				return timelineDataset{}, err
+			}
+		}
+	}
+
+	return timelineDataset{queries: q, viewer: viewer}, nil
+}
+
+func BenchmarkTimelineFirstPage(b *testing.B) {
+	data := seededTimeline(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries, err := data.queries.GetTimelineEntries(ctx, GetTimelineEntriesParams{
+			ViewerID:  data.viewer,
+			PageLimit: 50,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(entries) != 50 {
+			b.Fatalf("got %d entries, want 50", len(entries))
+		}
+	}
+}
+
+// BenchmarkTimelineDeepPage pages 20 pages in, where the cursor has to
+// skip most of each followed user's recent chirps.
+func BenchmarkTimelineDeepPage(b *testing.B) {
+	data := seededTimeline(b)
+	ctx := context.Background()
+
+	args := GetTimelineEntriesParams{
+		ViewerID:  data.viewer,
+		PageLimit: 50,
+	}
+	for page := 0; page < 20; page++ {
+		entries, err := data.queries.GetTimelineEntries(ctx, args)
+		if err != nil {
+			b.Fatal(err)
+		}
+		last := entries[len(entries)-1]
+		args.BeforeAt = sql.NullTime{Time: last.SortAt, Valid: true}
+		args.BeforeChirpID = uuid.NullUUID{UUID: last.ChirpID, Valid: true}
+		args.BeforeRechirpedBy = uuid.NullUUID{UUID: last.RechirpedBy, Valid: true}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := data.queries.GetTimelineEntries(ctx, args)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testDB needs TEST_DB_URL to point at a migrated database, like the
+// timeline benchmarks.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testQueries(t *testing.T) *Queries {
+	t.Helper()
+	return New(testDB(t))
+}
+
+// testUser creates a user that is removed, with everything it made, when
+// the test ends.
+func testUser(t *testing.T, q *Queries) uuid.UUID {
+	t.Helper()
+	user, err := q.CreateUser(context.Background(), CreateUserParams{
+		Email:          uuid.NewString() + "@test.invalid",
+		HashedPassword: "unused",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		q.db.ExecContext(context.Background(), "DELETE FROM users WHERE id = $1", user.ID)
+	})
+	return user.ID
+}
+
+func testChirp(t *testing.T, q *Queries, author uuid.UUID, visibility string) uuid.UUID {
+	t.Helper()
+	chirp, err := q.CreateChirp(context.Background(), CreateChirpParams{
+		Body:              "test chirp",
+		UserID:            author,
+		Status:            "published",
+		ModerationReasons: []string{},
+		Visibility:        visibility,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chirp.ID
+}
+
+func TestTimelineLeavesOutRechirpedUnlistedChirps(t *testing.T) {
+	q := testQueries(t)
+	ctx := context.Background()
+
+	viewer := testUser(t, q)
+	followed := testUser(t, q)
+	stranger := testUser(t, q)
+
+	_, err := q.FollowUser(ctx, FollowUserParams{
+		FollowerID: viewer,
+		FolloweeID: followed,
+		Status:     "accepted",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := testChirp(t, q, stranger, "public")
+	unlisted := testChirp(t, q, stranger, "unlisted")
+	for _, chirpID := range []uuid.UUID{public, unlisted} {
+		_, err = q.Rechirp(ctx, RechirpParams{UserID: followed, ChirpID: chirpID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := q.GetTimelineEntries(ctx, GetTimelineEntriesParams{
+		ViewerID:  viewer,
+		PageLimit: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[uuid.UUID]bool{}
+	for _, entry := range entries {
+		got[entry.ChirpID] = true
+	}
+	if !got[public] {
+		t.Error("rechirped public chirp missing from the timeline")
+	}
+	if got[unlisted] {
+		t.Error("rechirped unlisted chirp on the timeline")
+	}
+}
+
+func TestTimelineLeavesOutUnlistedChirps(t *testing.T) {
+	q := testQueries(t)
+	ctx := context.Background()
+
+	viewer := testUser(t, q)
+	followed := testUser(t, q)
+
+	_, err := q.FollowUser(ctx, FollowUserParams{
+		FollowerID: viewer,
+		FolloweeID: followed,
+		Status:     "accepted",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := testChirp(t, q, followed, "public")
+	followers := testChirp(t, q, followed, "followers")
+	unlisted := testChirp(t, q, followed, "unlisted")
+
+	entries, err := q.GetTimelineEntries(ctx, GetTimelineEntriesParams{
+		ViewerID:  viewer,
+		PageLimit: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[uuid.UUID]bool{}
+	for _, entry := range entries {
+		got[entry.ChirpID] = true
+	}
+	if !got[public] || !got[followers] {
+		t.Error("followed user's chirps missing from the timeline")
+	}
+	if got[unlisted] {
+		t.Error("unlisted chirp on the timeline")
+	}
+}
@@ -0,0 +1,134 @@
+// Package entitlements decides what each membership tier is allowed to do:
+// how long chirps may be, how many can be posted a day, how many images
+// they can carry and how long they stay editable.
+package entitlements
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type Tier string
+
+const (
+	Free Tier = "free"
+	Red  Tier = "red"
+)
+
+// Limits are the entitlements of one tier. A DailyChirps of zero means
+// there is no daily quota.
+type Limits struct {
+	MaxChirpLength int      `json:"max_chirp_length"`
+	DailyChirps    int      `json:"daily_chirps"`
+	MaxMedia       int      `json:"max_media"`
+	EditWindow     Duration `json:"edit_window"`
+}
+
+// Duration is a time.Duration written in JSON as a Go duration string
+// such as "15m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Config holds the limits for every tier, e.g.
+//
+//	{
+//	  "free": {"max_chirp_length": 140, "daily_chirps": 0, "max_media": 4, "edit_window": "15m"},
+//	  "red": {"max_chirp_length": 500, "daily_chirps": 0, "max_media": 8, "edit_window": "1h"}
+//	}
+type Config map[Tier]Limits
+
+// DefaultConfig is used when no config file is given. Free users keep the
+// limits Chirpy has always had, so they have no daily quota unless one is
+// configured.
+func DefaultConfig() Config {
+	return Config{
+		Free: {
+			MaxChirpLength: 140,
+			DailyChirps:    0,
+			MaxMedia:       4,
+			EditWindow:     Duration(15 * time.Minute),
+		},
+		Red: {
+			MaxChirpLength: 500,
+			DailyChirps:    0,
+			MaxMedia:       8,
+			EditWindow:     Duration(time.Hour),
+		},
+	}
+}
+
+// WithEditWindow returns a copy of c with every tier's edit window set to
+// d.
+func (c Config) WithEditWindow(d time.Duration) Config {
+	config := Config{}
+	for tier, limits := range c {
+		limits.EditWindow = Duration(d)
+		config[tier] = limits
+	}
+	return config
+}
+
+// LoadConfig reads a config file. Tiers the file leaves out keep their
+// limits from defaults.
+func LoadConfig(path string, defaults Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	loaded := Config{}
+	err = json.Unmarshal(data, &loaded)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	config := Config{}
+	for tier, limits := range defaults {
+		config[tier] = limits
+	}
+	for tier, limits := range loaded {
+		if tier != Free && tier != Red {
+			return nil, fmt.Errorf("parsing %s: unknown tier %q", path, tier)
+		}
+		if limits.MaxChirpLength <= 0 || limits.DailyChirps < 0 || limits.MaxMedia < 0 || limits.EditWindow < 0 {
+			return nil, fmt.Errorf("parsing %s: invalid limits for tier %q", path, tier)
+		}
+		config[tier] = limits
+	}
+	return config, nil
+}
+
+// For returns the limits of tier, falling back to the free tier's.
+func (c Config) For(tier Tier) Limits {
+	limits, ok := c[tier]
+	if !ok {
+		return c[Free]
+	}
+	return limits
+}
+
+// TierFor maps a user's Chirpy Red membership onto a tier.
+func TierFor(isChirpyRed bool) Tier {
+	if isChirpyRed {
+		return Red
+	}
+	return Free
+}
@@ -0,0 +1,96 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "entitlements.json")
+	err := os.WriteFile(path, []byte(contents), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigKeepsDefaultsForMissingTiers(t *testing.T) {
+	path := writeConfig(t, `{"red": {"max_chirp_length": 1000, "daily_chirps": 0, "max_media": 10, "edit_window": "2h"}}`)
+
+	config, err := LoadConfig(path, DefaultConfig())
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	red := config.For(Red)
+	if red.MaxChirpLength != 1000 || red.MaxMedia != 10 || time.Duration(red.EditWindow) != 2*time.Hour {
+		t.Errorf("red limits = %+v", red)
+	}
+	if config.For(Free) != DefaultConfig()[Free] {
+		t.Errorf("free limits = %+v, want defaults", config.For(Free))
+	}
+}
+
+func TestLoadConfigRejectsBadInput(t *testing.T) {
+	tests := map[string]string{
+		"unknown tier":   `{"gold": {"max_chirp_length": 10}}`,
+		"zero length":    `{"free": {"max_chirp_length": 0}}`,
+		"bad duration":   `{"free": {"max_chirp_length": 10, "edit_window": "soon"}}`,
+		"negative quota": `{"free": {"max_chirp_length": 10, "daily_chirps": -1}}`,
+	}
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, contents), DefaultConfig())
+			if err == nil {
+				t.Error("LoadConfig succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestEditWindowOverrideYieldsToFile(t *testing.T) {
+	defaults := DefaultConfig().WithEditWindow(time.Hour)
+	if time.Duration(defaults.For(Free).EditWindow) != time.Hour || time.Duration(defaults.For(Red).EditWindow) != time.Hour {
+		t.Errorf("WithEditWindow did not set every tier: %+v", defaults)
+	}
+	if time.Duration(DefaultConfig().For(Free).EditWindow) != 15*time.Minute {
+		t.Error("WithEditWindow changed the config it was called on")
+	}
+
+	path := writeConfig(t, `{"red": {"max_chirp_length": 500, "edit_window": "5m"}}`)
+	config, err := LoadConfig(path, defaults)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if time.Duration(config.For(Red).EditWindow) != 5*time.Minute {
+		t.Errorf("red edit window = %v, want the file's 5m", time.Duration(config.For(Red).EditWindow))
+	}
+	if time.Duration(config.For(Free).EditWindow) != time.Hour {
+		t.Errorf("free edit window = %v, want the override's 1h", time.Duration(config.For(Free).EditWindow))
+	}
+}
+
+func TestDefaultFreeTierHasNoQuota(t *testing.T) {
+	if DefaultConfig().For(Free).DailyChirps != 0 {
+		t.Error("free tier should have no daily quota unless configured")
+	}
+}
+
+func TestTierFor(t *testing.T) {
+	if TierFor(true) != Red || TierFor(false) != Free {
+		t.Error("TierFor mapped membership to the wrong tier")
+	}
+}
+
+func TestDurationJSON(t *testing.T) {
+	data, err := json.Marshal(Duration(90 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"1m30s"` {
+		t.Errorf("Marshal = %s", data)
+	}
+}
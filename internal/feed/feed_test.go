@@ -0,0 +1,153 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	published := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:       "Alice (@alice)",
+		Description: "Chirps by Alice",
+		Link:        "https://chirpy.example/users/alice",
+		FeedURL:     "https://chirpy.example/users/alice/feed.rss",
+		Updated:     published,
+		Items: []Item{{
+			URL:       "https://chirpy.example/api/chirps/1",
+			Content:   "Tom & Jerry <script>alert(1)</script>\x00",
+			Author:    "Alice",
+			Published: published,
+			Updated:   published,
+		}},
+	}
+}
+
+func TestRSSEscapesContent(t *testing.T) {
+	body, err := RSS(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The document must parse, and the description must decode to
+	// HTML-escaped text so readers never see live markup.
+	doc := struct {
+		Items []struct {
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+		} `xml:"channel>item"`
+	}{}
+	err = xml.Unmarshal(body, &doc)
+	if err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, body)
+	}
+	if len(doc.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(doc.Items))
+	}
+	want := "Tom &amp; Jerry &lt;script&gt;alert(1)&lt;/script&gt;�"
+	if doc.Items[0].Description != want {
+		t.Fatalf("description = %q, want %q", doc.Items[0].Description, want)
+	}
+	if doc.Items[0].PubDate != "Sat, 01 Mar 2025 12:00:00 +0000" {
+		t.Fatalf("pubDate = %q", doc.Items[0].PubDate)
+	}
+	if !strings.Contains(string(body), `<atom:link href="https://chirpy.example/users/alice/feed.rss" rel="self"`) {
+		t.Fatalf("missing self link:\n%s", body)
+	}
+}
+
+func TestAtomIsPlainText(t *testing.T) {
+	body, err := Atom(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := struct {
+		XMLName xml.Name
+		Entries []struct {
+			Title   string `xml:"title"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}{}
+	err = xml.Unmarshal(body, &doc)
+	if err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, body)
+	}
+	if doc.XMLName.Space != "http://www.w3.org/2005/Atom" || doc.XMLName.Local != "feed" {
+		t.Fatalf("root element = %v", doc.XMLName)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(doc.Entries))
+	}
+	content := doc.Entries[0].Content
+	if content.Type != "text" || content.Value != "Tom & Jerry <script>alert(1)</script>�" {
+		t.Fatalf("content = %+v", content)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	body, err := JSON(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := struct {
+		Version string `json:"version"`
+		Items   []struct {
+			ID          string `json:"id"`
+			ContentText string `json:"content_text"`
+		} `json:"items"`
+	}{}
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != "https://jsonfeed.org/version/1.1" {
+		t.Fatalf("version = %q", doc.Version)
+	}
+	if len(doc.Items) != 1 || doc.Items[0].ID != "https://chirpy.example/api/chirps/1" {
+		t.Fatalf("items = %+v", doc.Items)
+	}
+}
+
+func TestEmptyFeedHasEmptyItems(t *testing.T) {
+	f := testFeed()
+	f.Items = nil
+	body, err := JSON(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"items": []`) {
+		t.Fatalf("items missing:\n%s", body)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	if got := Title("hello\n  world"); got != "hello world" {
+		t.Fatalf("Title = %q", got)
+	}
+	long := strings.Repeat("é", 100)
+	got := Title(long)
+	if !strings.HasSuffix(got, "…") || len([]rune(got)) != titleLength {
+		t.Fatalf("Title = %q (%d runes)", got, len([]rune(got)))
+	}
+}
+
+func TestETagChangesWithContent(t *testing.T) {
+	a := ETag([]byte("one"))
+	if a != ETag([]byte("one")) {
+		t.Fatal("ETag is not stable")
+	}
+	if a == ETag([]byte("two")) {
+		t.Fatal("ETag did not change with content")
+	}
+	if !strings.HasPrefix(a, `"`) || !strings.HasSuffix(a, `"`) {
+		t.Fatalf("ETag %s is not quoted", a)
+	}
+}
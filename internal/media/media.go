@@ -0,0 +1,245 @@
+// Package media validates uploaded images and prepares them for storage:
+// it sniffs the real content type, re-encodes the image so metadata such as
+// EXIF (and any GPS position in it) is dropped, and renders a thumbnail.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// MaxPixels bounds the decoded size of an image, so a small file claiming
+// huge dimensions cannot exhaust memory. For animated GIFs it bounds the
+// frames' pixels put together.
+const MaxPixels = 40_000_000
+
+// MaxFrames bounds the number of frames in an animated GIF.
+const MaxFrames = 1000
+
+// ThumbnailSize is the longest edge of generated thumbnails, in pixels.
+const ThumbnailSize = 320
+
+var ErrUnsupportedType = errors.New("unsupported image type")
+
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+// Image is an upload ready to store.
+type Image struct {
+	ContentType string
+	Data        []byte
+	Width       int
+	Height      int
+
+	ThumbnailContentType string
+	Thumbnail            []byte
+}
+
+// Process checks that data is a supported image and returns a clean copy
+// of it along with a thumbnail. JPEGs are turned upright according to
+// their EXIF orientation, since the tag is dropped with the rest of the
+// metadata.
+func Process(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("decoding image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	img := Image{
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+	}
+
+	var first image.Image
+	buf := bytes.Buffer{}
+	switch contentType {
+	case "image/gif":
+		// DecodeAll decodes every frame up front, so count them first.
+		frames, pixels, err := scanGIF(data)
+		if err != nil {
+			return Image{}, fmt.Errorf("decoding image: %w", err)
+		}
+		if frames > MaxFrames || pixels > MaxPixels {
+			return Image{}, ErrTooLarge
+		}
+		// Keep every frame of an animation; re-encoding drops comment and
+		// application extension blocks.
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, fmt.Errorf("decoding image: %w", err)
+		}
+		first = anim.Image[0]
+		err = gif.EncodeAll(&buf, anim)
+		if err != nil {
+			return Image{}, err
+		}
+	case "image/png":
+		first, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, fmt.Errorf("decoding image: %w", err)
+		}
+		err = png.Encode(&buf, first)
+		if err != nil {
+			return Image{}, err
+		}
+	case "image/jpeg":
+		first, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return Image{}, fmt.Errorf("decoding image: %w", err)
+		}
+		first = orient(first, jpegOrientation(data))
+		img.Width, img.Height = first.Bounds().Dx(), first.Bounds().Dy()
+		err = jpeg.Encode(&buf, first, &jpeg.Options{Quality: 90})
+		if err != nil {
+			return Image{}, err
+		}
+	}
+	img.Data = buf.Bytes()
+
+	thumb := bytes.Buffer{}
+	small := Thumbnail(first, ThumbnailSize)
+	if contentType == "image/jpeg" {
+		img.ThumbnailContentType = "image/jpeg"
+		err = jpeg.Encode(&thumb, small, &jpeg.Options{Quality: 80})
+	} else {
+		// PNG keeps any transparency the original had.
+		img.ThumbnailContentType = "image/png"
+		err = png.Encode(&thumb, small)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	img.Thumbnail = thumb.Bytes()
+
+	return img, nil
+}
+
+var errMalformedGIF = errors.New("gif: malformed block structure")
+
+// scanGIF walks the blocks of a GIF without decoding any image data, and
+// returns how many frames it has and how many pixels they cover in all.
+func scanGIF(data []byte) (frames, pixels int, err error) {
+	// Header and logical screen descriptor.
+	if len(data) < 13 {
+		return 0, 0, errMalformedGIF
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	// skipSubBlocks moves i past a run of data sub-blocks.
+	skipSubBlocks := func() error {
+		for {
+			if i >= len(data) {
+				return errMalformedGIF
+			}
+			size := int(data[i])
+			i += 1 + size
+			if size == 0 {
+				return nil
+			}
+		}
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension
+			i += 2
+			err = skipSubBlocks()
+		case 0x2c: // image descriptor
+			if i+10 > len(data) {
+				return 0, 0, errMalformedGIF
+			}
+			w := int(data[i+5]) | int(data[i+6])<<8
+			h := int(data[i+7]) | int(data[i+8])<<8
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			// LZW minimum code size, then the image data.
+			i++
+			err = skipSubBlocks()
+			frames++
+			pixels += w * h
+		case 0x3b: // trailer
+			return frames, pixels, nil
+		default:
+			return 0, 0, errMalformedGIF
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return 0, 0, errMalformedGIF
+}
+
+// Thumbnail scales src down so its longest edge is at most size pixels,
+// averaging the source pixels that fall into each destination pixel. Images
+// that are already small enough are copied unchanged.
+func Thumbnail(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	dw, dh := w, h
+	if w > size || h > size {
+		if w >= h {
+			dw = size
+			dh = max(1, h*size/w)
+		} else {
+			dh = size
+			dw = max(1, w*size/h)
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := bounds.Min.Y + y*h/dh
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*h/dh)
+		for x := 0; x < dw; x++ {
+			x0 := bounds.Min.X + x*w/dw
+			x1 := max(x0+1, bounds.Min.X+(x+1)*w/dw)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			// RGBA returns alpha-premultiplied 16-bit values; NRGBA wants
+			// them un-premultiplied and 8-bit.
+			i := dst.PixOffset(x, y)
+			if a == 0 {
+				continue
+			}
+			dst.Pix[i+0] = uint8(r * 0xff / a)
+			dst.Pix[i+1] = uint8(g * 0xff / a)
+			dst.Pix[i+2] = uint8(b * 0xff / a)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
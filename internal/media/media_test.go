@@ -0,0 +1,196 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	return img
+}
+
+// exifSegment is a minimal APP1 segment carrying an "Exif" header.
+var exifSegment = []byte{0xff, 0xe1, 0x00, 0x0e, 'E', 'x', 'i', 'f', 0, 0, 'M', 'M', 0, 42, 0, 0, 0, 8}
+
+func TestProcessStripsEXIF(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := jpeg.Encode(&buf, testImage(800, 400), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Splice the EXIF segment in straight after the SOI marker.
+	withExif := append([]byte{0xff, 0xd8}, exifSegment...)
+	withExif = append(withExif, buf.Bytes()[2:]...)
+
+	img, err := Process(withExif)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if img.ContentType != "image/jpeg" {
+		t.Errorf("ContentType = %q", img.ContentType)
+	}
+	if img.Width != 800 || img.Height != 400 {
+		t.Errorf("size = %dx%d, want 800x400", img.Width, img.Height)
+	}
+	if bytes.Contains(img.Data, []byte("Exif")) {
+		t.Error("processed image still contains EXIF data")
+	}
+
+	thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatalf("decoding thumbnail: %v", err)
+	}
+	if thumb.Width != ThumbnailSize || thumb.Height != ThumbnailSize/2 {
+		t.Errorf("thumbnail size = %dx%d, want %dx%d", thumb.Width, thumb.Height, ThumbnailSize, ThumbnailSize/2)
+	}
+}
+
+// orientationSegment is an APP1 segment whose EXIF data says the photo
+// must be turned a quarter turn clockwise to display upright.
+var orientationSegment = []byte{
+	0xff, 0xe1, 0x00, 0x22, 'E', 'x', 'i', 'f', 0, 0,
+	'M', 'M', 0, 42, 0, 0, 0, 8,
+	0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 6, 0, 0,
+	0, 0, 0, 0,
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	// Red on the left, blue on the right.
+	src := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= 32 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	buf := bytes.Buffer{}
+	err := jpeg.Encode(&buf, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated := append([]byte{0xff, 0xd8}, orientationSegment...)
+	rotated = append(rotated, buf.Bytes()[2:]...)
+
+	img, err := Process(rotated)
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if img.Width != 32 || img.Height != 64 {
+		t.Errorf("size = %dx%d, want 32x64", img.Width, img.Height)
+	}
+	upright, err := jpeg.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Turned clockwise, the left of the photo is at the top.
+	if r, _, b, _ := upright.At(16, 8).RGBA(); r < b {
+		t.Error("top of the upright image should be red")
+	}
+	if r, _, b, _ := upright.At(16, 56).RGBA(); b < r {
+		t.Error("bottom of the upright image should be blue")
+	}
+}
+
+func testGIF(t *testing.T, frames, w, h int) []byte {
+	t.Helper()
+	anim := &gif.GIF{}
+	for range frames {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, w, h), color.Palette{color.Black, color.White}))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	buf := bytes.Buffer{}
+	err := gif.EncodeAll(&buf, anim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestScanGIF(t *testing.T) {
+	frames, pixels, err := scanGIF(testGIF(t, 3, 10, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames != 3 || pixels != 600 {
+		t.Errorf("got %d frames, %d pixels; want 3, 600", frames, pixels)
+	}
+	_, _, err = scanGIF([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00\x2c"))
+	if err == nil {
+		t.Error("truncated GIF should be malformed")
+	}
+}
+
+func TestProcessRejectsTooManyFrames(t *testing.T) {
+	_, err := Process(testGIF(t, MaxFrames+1, 1, 1))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+	_, err = Process(testGIF(t, 2, 10, 10))
+	if err != nil {
+		t.Errorf("small animation: %v", err)
+	}
+}
+
+func TestProcessPNG(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := png.Encode(&buf, testImage(10, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if img.ContentType != "image/png" || img.ThumbnailContentType != "image/png" {
+		t.Errorf("content types = %q, %q", img.ContentType, img.ThumbnailContentType)
+	}
+
+	thumb, err := png.Decode(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumb.Bounds().Dx() != 10 || thumb.Bounds().Dy() != 20 {
+		t.Errorf("small images should not be resized, got %v", thumb.Bounds())
+	}
+	r, g, b, _ := thumb.At(5, 5).RGBA()
+	if r>>8 != 200 || g>>8 != 100 || b>>8 != 50 {
+		t.Errorf("thumbnail colour = %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	_, err := Process([]byte("<html><body>not an image</body></html>"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestProcessRejectsHugeDimensions(t *testing.T) {
+	// A valid PNG header claiming 100000x100000 pixels.
+	ihdr := []byte("IHDR\x00\x01\x86\xa0\x00\x01\x86\xa0\x08\x02\x00\x00\x00")
+	header := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	header = append(header, ihdr...)
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(ihdr))
+	_, err := Process(header)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,97 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Config is the on-disk description of a rule set, e.g.
+//
+//	{
+//	  "words": [{"action": "censor", "words": ["kerfuffle"]}],
+//	  "patterns": [{"name": "phone", "pattern": "\\d{3}-\\d{4}", "action": "hold"}],
+//	  "links": [{"action": "reject", "domains": ["spam.example"]}]
+//	}
+type Config struct {
+	Words    []WordRuleConfig    `json:"words"`
+	Patterns []PatternRuleConfig `json:"patterns"`
+	Links    []LinkRuleConfig    `json:"links"`
+}
+
+type WordRuleConfig struct {
+	Action string   `json:"action"`
+	Words  []string `json:"words"`
+}
+
+type PatternRuleConfig struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+}
+
+type LinkRuleConfig struct {
+	Action  string   `json:"action"`
+	Domains []string `json:"domains"`
+}
+
+// DefaultConfig is used when no config file is given. It censors the words
+// Chirpy has always censored.
+func DefaultConfig() Config {
+	return Config{
+		Words: []WordRuleConfig{
+			{Action: "censor", Words: []string{"kerfuffle", "sharbert", "fornax"}},
+		},
+	}
+}
+
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	config := Config{}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return Config{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Build compiles the config into a pipeline that runs word rules, then
+// patterns, then links.
+func (c Config) Build() (*Pipeline, error) {
+	words := NewWordFilter()
+	for _, rule := range c.Words {
+		action, err := ParseAction(rule.Action)
+		if err != nil {
+			return nil, err
+		}
+		words.Add(action, rule.Words...)
+	}
+
+	rules := []RegexRule{}
+	for _, rule := range c.Patterns {
+		action, err := ParseAction(rule.Action)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", rule.Name, err)
+		}
+		rules = append(rules, RegexRule{Name: rule.Name, Pattern: pattern, Action: action})
+	}
+
+	links := NewLinkFilter()
+	for _, rule := range c.Links {
+		action, err := ParseAction(rule.Action)
+		if err != nil {
+			return nil, err
+		}
+		links.Add(action, rule.Domains...)
+	}
+
+	return NewPipeline(words, NewRegexFilter(rules...), links), nil
+}
@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// WordFilter matches whole words, case-insensitively. A word is a run of
+// Unicode letters, digits and combining marks, so "Kerfuffle!" and
+// "(kerfuffle)" both match "kerfuffle" while "kerfuffles" does not.
+type WordFilter struct {
+	words map[string]Action
+}
+
+func NewWordFilter() *WordFilter {
+	return &WordFilter{words: map[string]Action{}}
+}
+
+// Add registers words with an action. If a word is added twice the more
+// severe action is kept.
+func (f *WordFilter) Add(action Action, words ...string) {
+	for _, w := range words {
+		w = strings.ToLower(w)
+		if existing, ok := f.words[w]; !ok || action > existing {
+			f.words[w] = action
+		}
+	}
+}
+
+func (f *WordFilter) Apply(body string) Verdict {
+	verdict := Verdict{Action: Allow}
+	var out strings.Builder
+	for _, tok := range tokenize(body) {
+		action, ok := Allow, false
+		if tok.word {
+			action, ok = f.words[strings.ToLower(tok.text)]
+		}
+		if !ok {
+			out.WriteString(tok.text)
+			continue
+		}
+		verdict.Reasons = append(verdict.Reasons, "word:"+strings.ToLower(tok.text))
+		if action > verdict.Action {
+			verdict.Action = action
+		}
+		if action == Censor {
+			out.WriteString(mask)
+		} else {
+			out.WriteString(tok.text)
+		}
+	}
+	verdict.Body = out.String()
+	return verdict
+}
+
+type token struct {
+	text string
+	word bool
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+// tokenize splits s into alternating runs of word and non-word runes.
+// Joining the tokens' text gives back s exactly.
+func tokenize(s string) []token {
+	tokens := []token{}
+	start := 0
+	inWord := false
+	for i, r := range s {
+		w := isWordRune(r)
+		if i == 0 {
+			inWord = w
+			continue
+		}
+		if w != inWord {
+			tokens = append(tokens, token{text: s[start:i], word: inWord})
+			start = i
+			inWord = w
+		}
+	}
+	if start < len(s) {
+		tokens = append(tokens, token{text: s[start:], word: inWord})
+	}
+	return tokens
+}
+
+// RegexRule is a named pattern with the action to take when it matches.
+type RegexRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Action  Action
+}
+
+type RegexFilter struct {
+	rules []RegexRule
+}
+
+func NewRegexFilter(rules ...RegexRule) *RegexFilter {
+	return &RegexFilter{rules: rules}
+}
+
+func (f *RegexFilter) Apply(body string) Verdict {
+	verdict := Verdict{Action: Allow, Body: body}
+	for _, rule := range f.rules {
+		if !rule.Pattern.MatchString(verdict.Body) {
+			continue
+		}
+		verdict.Reasons = append(verdict.Reasons, "pattern:"+rule.Name)
+		if rule.Action > verdict.Action {
+			verdict.Action = rule.Action
+		}
+		if rule.Action == Censor {
+			verdict.Body = rule.Pattern.ReplaceAllString(verdict.Body, mask)
+		}
+	}
+	return verdict
+}
+
+// linkPattern finds things that look like links, with or without a scheme.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://)?(?:[\p{L}\p{N}-]+\.)+\p{L}{2,}(?::\d+)?(?:/[^\s]*)?`)
+
+// LinkFilter acts on links to blocked domains, including their subdomains.
+type LinkFilter struct {
+	domains map[string]Action
+}
+
+func NewLinkFilter() *LinkFilter {
+	return &LinkFilter{domains: map[string]Action{}}
+}
+
+func (f *LinkFilter) Add(action Action, domains ...string) {
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(d), ".")
+		if existing, ok := f.domains[d]; !ok || action > existing {
+			f.domains[d] = action
+		}
+	}
+}
+
+func (f *LinkFilter) Apply(body string) Verdict {
+	verdict := Verdict{Action: Allow}
+	verdict.Body = linkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		domain, action, ok := f.match(link)
+		if !ok {
+			return link
+		}
+		verdict.Reasons = append(verdict.Reasons, "link:"+domain)
+		if action > verdict.Action {
+			verdict.Action = action
+		}
+		if action == Censor {
+			return mask
+		}
+		return link
+	})
+	return verdict
+}
+
+func (f *LinkFilter) match(link string) (string, Action, bool) {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", Allow, false
+	}
+	host := strings.ToLower(u.Hostname())
+	for host != "" {
+		if action, ok := f.domains[host]; ok {
+			return host, action, true
+		}
+		_, rest, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = rest
+	}
+	return "", Allow, false
+}
@@ -0,0 +1,91 @@
+// Package moderation decides what happens to user-submitted text before it
+// is published. A Pipeline runs a series of filters over the text; each
+// filter may censor parts of it, hold it for human review or reject it.
+package moderation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action is what a filter wants done with a piece of text. Actions are
+// ordered by severity so the most severe one wins when filters disagree.
+type Action int
+
+const (
+	Allow Action = iota
+	Censor
+	Hold
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Allow:
+		return "allow"
+	case Censor:
+		return "censor"
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// ParseAction converts the name used in config files into an Action.
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "allow":
+		return Allow, nil
+	case "censor":
+		return Censor, nil
+	case "hold":
+		return Hold, nil
+	case "reject":
+		return Reject, nil
+	}
+	return Allow, fmt.Errorf("unknown moderation action %q", s)
+}
+
+// Verdict is the outcome of moderating a piece of text.
+type Verdict struct {
+	Action Action
+	// Body is the text with any censored parts masked out.
+	Body string
+	// Reasons names the rules that fired, for moderators' benefit.
+	Reasons []string
+}
+
+// Filter inspects text and returns its verdict on it.
+type Filter interface {
+	Apply(body string) Verdict
+}
+
+// Pipeline runs filters in order. Censoring by one filter is visible to the
+// ones after it, and the most severe action seen becomes the final one.
+type Pipeline struct {
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+func (p *Pipeline) Check(body string) Verdict {
+	verdict := Verdict{Action: Allow, Body: body}
+	for _, f := range p.filters {
+		v := f.Apply(verdict.Body)
+		verdict.Body = v.Body
+		verdict.Reasons = append(verdict.Reasons, v.Reasons...)
+		if v.Action > verdict.Action {
+			verdict.Action = v.Action
+		}
+		if verdict.Action == Reject {
+			break
+		}
+	}
+	return verdict
+}
+
+const mask = "****"
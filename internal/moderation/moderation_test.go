@@ -0,0 +1,175 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestWordFilter_CensorsWholeWordsOnly(t *testing.T) {
+	f := NewWordFilter()
+	f.Add(Censor, "kerfuffle", "fornax")
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"what a kerfuffle", "what a ****"},
+		{"Kerfuffle! said the fornax.", "****! said the ****."},
+		{"kerfuffles are fine", "kerfuffles are fine"},
+		{"(FORNAX)\tkerfuffle\nok", "(****)\t****\nok"},
+	}
+
+	for _, tt := range tests {
+		v := f.Apply(tt.body)
+		if v.Body != tt.want {
+			t.Fatalf("Expected %q, got %q", tt.want, v.Body)
+		}
+	}
+}
+
+func TestWordFilter_UnicodeWords(t *testing.T) {
+	f := NewWordFilter()
+	f.Add(Censor, "straße", "café")
+
+	v := f.Apply("STRASSE? no: Straße, café…")
+	want := "STRASSE? no: ****, ****…"
+	if v.Body != want {
+		t.Fatalf("Expected %q, got %q", want, v.Body)
+	}
+
+	// "cafés" is a different word, not café plus punctuation.
+	v = f.Apply("cafés")
+	if v.Action != Allow {
+		t.Fatalf("Expected allow, got %v", v.Action)
+	}
+}
+
+func TestTokenize_RoundTrips(t *testing.T) {
+	body := "  héllo, wörld!! 123 — ok  "
+	joined := ""
+	for _, tok := range tokenize(body) {
+		joined += tok.text
+	}
+	if joined != body {
+		t.Fatalf("Expected %q, got %q", body, joined)
+	}
+}
+
+func TestRegexFilter(t *testing.T) {
+	f := NewRegexFilter(
+		RegexRule{Name: "phone", Pattern: regexp.MustCompile(`\d{3}-\d{4}`), Action: Censor},
+		RegexRule{Name: "shouting", Pattern: regexp.MustCompile(`^[A-Z !]{10,}$`), Action: Hold},
+	)
+
+	v := f.Apply("call 555-1234")
+	if v.Body != "call ****" || v.Action != Censor {
+		t.Fatalf("Expected censored phone number, got %q (%v)", v.Body, v.Action)
+	}
+
+	v = f.Apply("STOP SHOUTING")
+	if v.Action != Hold {
+		t.Fatalf("Expected hold, got %v", v.Action)
+	}
+}
+
+func TestLinkFilter_MatchesSubdomains(t *testing.T) {
+	f := NewLinkFilter()
+	f.Add(Reject, "spam.example")
+	f.Add(Censor, "tracker.test")
+
+	tests := []struct {
+		body   string
+		action Action
+	}{
+		{"see https://spam.example/buy", Reject},
+		{"see www.spam.example", Reject},
+		{"see notspam.example", Allow},
+		{"see http://x.tracker.test:8080/p?q=1", Censor},
+		{"see https://example.org", Allow},
+	}
+
+	for _, tt := range tests {
+		v := f.Apply(tt.body)
+		if v.Action != tt.action {
+			t.Fatalf("%q: expected %v, got %v", tt.body, tt.action, v.Action)
+		}
+	}
+}
+
+func TestPipeline_MostSevereActionWins(t *testing.T) {
+	words := NewWordFilter()
+	words.Add(Censor, "kerfuffle")
+	words.Add(Hold, "suspicious")
+	links := NewLinkFilter()
+	links.Add(Reject, "spam.example")
+
+	p := NewPipeline(words, links)
+
+	v := p.Check("a kerfuffle")
+	if v.Action != Censor || v.Body != "a ****" {
+		t.Fatalf("Expected censor, got %v %q", v.Action, v.Body)
+	}
+
+	v = p.Check("a suspicious kerfuffle")
+	if v.Action != Hold || v.Body != "a suspicious ****" {
+		t.Fatalf("Expected hold, got %v %q", v.Action, v.Body)
+	}
+
+	v = p.Check("kerfuffle at spam.example")
+	if v.Action != Reject {
+		t.Fatalf("Expected reject, got %v", v.Action)
+	}
+	if len(v.Reasons) != 2 {
+		t.Fatalf("Expected 2 reasons, got %v", v.Reasons)
+	}
+}
+
+func TestModerator_DefaultRules(t *testing.T) {
+	m, err := NewModerator("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	v := m.Check("This is a kerfuffle opinion I need to share with the world")
+	want := "This is a **** opinion I need to share with the world"
+	if v.Body != want {
+		t.Fatalf("Expected %q, got %q", want, v.Body)
+	}
+}
+
+func TestModerator_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	write(`{"words": [{"action": "reject", "words": ["banana"]}]}`)
+	m, err := NewModerator(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if v := m.Check("banana"); v.Action != Reject {
+		t.Fatalf("Expected reject, got %v", v.Action)
+	}
+
+	write(`{"words": [{"action": "censor", "words": ["banana"]}]}`)
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if v := m.Check("banana"); v.Action != Censor {
+		t.Fatalf("Expected censor after reload, got %v", v.Action)
+	}
+
+	// A broken file leaves the current rules in place.
+	write(`{"words": [{"action": "explode", "words": ["banana"]}]}`)
+	if err := m.Reload(); err == nil {
+		t.Fatal("Expected error for unknown action, got nil")
+	}
+	if v := m.Check("banana"); v.Action != Censor {
+		t.Fatalf("Expected censor to survive a bad reload, got %v", v.Action)
+	}
+}
@@ -0,0 +1,45 @@
+package moderation
+
+import "sync/atomic"
+
+// Moderator holds the active pipeline and can swap in a new one built from
+// the config file without interrupting requests that are mid-check.
+type Moderator struct {
+	path     string
+	pipeline atomic.Pointer[Pipeline]
+}
+
+// NewModerator loads the rule set at path, or the default rules if path is
+// empty.
+func NewModerator(path string) (*Moderator, error) {
+	m := &Moderator{path: path}
+	err := m.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload rebuilds the pipeline from the config file. If the file is missing
+// or invalid the previous rules stay in force and the error is returned.
+func (m *Moderator) Reload() error {
+	config := DefaultConfig()
+	if m.path != "" {
+		var err error
+		config, err = LoadConfig(m.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	pipeline, err := config.Build()
+	if err != nil {
+		return err
+	}
+	m.pipeline.Store(pipeline)
+	return nil
+}
+
+func (m *Moderator) Check(body string) Verdict {
+	return m.pipeline.Load().Check(body)
+}
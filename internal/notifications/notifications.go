@@ -0,0 +1,96 @@
+// Package notifications describes the kinds of in-app notification Chirpy
+// sends, how similar ones are grouped together and how each group is
+// summarised for display.
+package notifications
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	Mention       Type = "mention"
+	Reply         Type = "reply"
+	Like          Type = "like"
+	Follow        Type = "follow"
+	FollowRequest Type = "follow_request"
+	ChirpyRed     Type = "chirpy_red"
+)
+
+var types = map[Type]bool{
+	Mention:       true,
+	Reply:         true,
+	Like:          true,
+	Follow:        true,
+	FollowRequest: true,
+	ChirpyRed:     true,
+}
+
+// Valid reports whether t is a known notification type.
+func Valid(t Type) bool {
+	return types[t]
+}
+
+// GroupKey returns the key that unread notifications of type t about
+// subject are grouped under. Likes are grouped per chirp and follows per
+// recipient; every mention and reply stands alone, keyed by the chirp
+// that made it. subject is ignored for types that do not need one.
+func GroupKey(t Type, subject uuid.UUID) string {
+	switch t {
+	case Follow, FollowRequest, ChirpyRed:
+		return string(t)
+	default:
+		return string(t) + ":" + subject.String()
+	}
+}
+
+// Summary describes a group in one line, such as "alice and 4 others
+// liked your chirp". names are the most recent actors, newest first, and
+// count is how many actors there are in total.
+func Summary(t Type, names []string, count int) string {
+	if t == ChirpyRed {
+		return "Welcome to Chirpy Red"
+	}
+	return actors(names, count) + " " + verb(t)
+}
+
+func actors(names []string, count int) string {
+	if count < len(names) {
+		count = len(names)
+	}
+	switch {
+	case len(names) == 0 && count <= 1:
+		return "Someone"
+	case len(names) == 0:
+		return fmt.Sprintf("%d people", count)
+	case count == 1:
+		return names[0]
+	case count == 2 && len(names) >= 2:
+		return names[0] + " and " + names[1]
+	case count == 2:
+		return names[0] + " and 1 other"
+	default:
+		return fmt.Sprintf("%s and %d others", names[0], count-1)
+	}
+}
+
+func verb(t Type) string {
+	switch t {
+	case Mention:
+		return "mentioned you"
+	case Reply:
+		return "replied to your chirp"
+	case Like:
+		return "liked your chirp"
+	case Follow:
+		return "followed you"
+	case FollowRequest:
+		return "asked to follow you"
+	default:
+		return strings.ReplaceAll(string(t), "_", " ")
+	}
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupKey(t *testing.T) {
+	chirpID := uuid.MustParse("0b5b3a4e-8c55-4f6b-9c8e-0e4f3f1d2a10")
+
+	tests := []struct {
+		typ  Type
+		want string
+	}{
+		{Like, "like:" + chirpID.String()},
+		{Reply, "reply:" + chirpID.String()},
+		{Mention, "mention:" + chirpID.String()},
+		{Follow, "follow"},
+		{FollowRequest, "follow_request"},
+		{ChirpyRed, "chirpy_red"},
+	}
+	for _, tt := range tests {
+		if got := GroupKey(tt.typ, chirpID); got != tt.want {
+			t.Errorf("GroupKey(%s) = %q, want %q", tt.typ, got, tt.want)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		typ   Type
+		names []string
+		count int
+		want  string
+	}{
+		{"one", Like, []string{"alice"}, 1, "alice liked your chirp"},
+		{"two", Like, []string{"alice", "bob"}, 2, "alice and bob liked your chirp"},
+		{"many", Like, []string{"alice", "bob", "carol"}, 5, "alice and 4 others liked your chirp"},
+		{"two with one name", Follow, []string{"alice"}, 2, "alice and 1 other followed you"},
+		{"no names", Like, nil, 5, "5 people liked your chirp"},
+		{"nobody", Mention, nil, 0, "Someone mentioned you"},
+		{"count too low", Reply, []string{"alice", "bob"}, 1, "alice and bob replied to your chirp"},
+		{"request", FollowRequest, []string{"alice"}, 1, "alice asked to follow you"},
+		{"red", ChirpyRed, nil, 0, "Welcome to Chirpy Red"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summary(tt.typ, tt.names, tt.count); got != tt.want {
+				t.Errorf("Summary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid(Like) || !Valid(ChirpyRed) {
+		t.Error("known types reported invalid")
+	}
+	if Valid("poke") {
+		t.Error("unknown type reported valid")
+	}
+}
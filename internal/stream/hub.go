@@ -0,0 +1,121 @@
+// Package stream fans chirp events out to connected clients. A Hub keeps
+// the subscribers of one server instance; events reach it both from the
+// handlers on this instance and, via Postgres LISTEN/NOTIFY, from every
+// other instance, so it drops events it has already delivered.
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is one entry of the chirp event log. Data is the JSON payload sent
+// to clients. RechirpedBy is set only for rechirps. Hubs for other kinds of
+// events use only the fields they need.
+type Event struct {
+	ID             int64
+	CreatedAt      time.Time
+	Type           string
+	ChirpID        uuid.UUID
+	UserID         uuid.UUID
+	RechirpedBy    uuid.UUID
+	ConversationID uuid.UUID
+	Visibility     string
+	Tags           []string
+	Data           []byte
+}
+
+// Hub delivers published events to every subscriber whose filter accepts
+// them. The zero value is not usable; create one with NewHub.
+type Hub struct {
+	bufferSize int
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	recent *Window
+}
+
+// NewHub returns a hub whose subscribers can fall up to bufferSize events
+// behind, and which remembers the last dedupeWindow event ids to drop
+// duplicates. A dedupeWindow of zero delivers every event.
+func NewHub(bufferSize, dedupeWindow int) *Hub {
+	return &Hub{
+		bufferSize: bufferSize,
+		subs:       map[*Subscription]struct{}{},
+		recent:     NewWindow(dedupeWindow),
+	}
+}
+
+// Subscription receives events on C until it is closed, either by Close
+// or by the hub when the subscriber falls too far behind. A dropped
+// subscriber is expected to reconnect and resume from the last event id
+// it saw.
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	filter func(Event) bool
+	hub    *Hub
+}
+
+// Subscribe registers a subscriber. filter may be nil to receive every
+// event.
+func (h *Hub) Subscribe(filter func(Event) bool) *Subscription {
+	c := make(chan Event, h.bufferSize)
+	sub := &Subscription{C: c, c: c, filter: filter, hub: h}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// Close unsubscribes. It is safe to call more than once, and after the hub
+// has dropped the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// remove must be called with h.mu held.
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
+
+// Publish delivers ev to the matching subscribers without blocking.
+// Subscribers whose buffer is full are dropped. It reports false if the
+// event had already been published.
+func (h *Hub) Publish(ev Event) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.recent.Add(ev.ID) {
+		return false
+	}
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(ev) {
+			continue
+		}
+		select {
+		case sub.c <- ev:
+		default:
+			h.remove(sub)
+		}
+	}
+	return true
+}
+
+// Subscribers returns the number of connected subscribers.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
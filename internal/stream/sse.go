@@ -0,0 +1,30 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteEvent writes ev in the Server-Sent Events format. Payloads that
+// span several lines are split into several data fields, as the format
+// requires.
+func WriteEvent(w io.Writer, ev Event) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "id: %d\n", ev.ID)
+	fmt.Fprintf(&buf, "event: %s\n", ev.Type)
+	for _, line := range strings.Split(string(ev.Data), "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", strings.TrimSuffix(line, "\r"))
+	}
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteComment writes an SSE comment line, which clients ignore. It is used
+// for heartbeats that keep idle connections open through proxies.
+func WriteComment(w io.Writer, text string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", text)
+	return err
+}
@@ -0,0 +1,165 @@
+package stream
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.C:
+		return ev, ok
+	default:
+		return Event{}, false
+	}
+}
+
+func TestPublishDeliversToMatchingSubscribers(t *testing.T) {
+	hub := NewHub(4, 16)
+	author := uuid.New()
+
+	all := hub.Subscribe(nil)
+	mine := hub.Subscribe(func(ev Event) bool { return ev.UserID == author })
+
+	hub.Publish(Event{ID: 1, UserID: uuid.New()})
+	hub.Publish(Event{ID: 2, UserID: author})
+
+	for _, want := range []int64{1, 2} {
+		ev, ok := receive(t, all)
+		if !ok || ev.ID != want {
+			t.Fatalf("all: got %v (ok=%v), want event %d", ev.ID, ok, want)
+		}
+	}
+	ev, ok := receive(t, mine)
+	if !ok || ev.ID != 2 {
+		t.Fatalf("filtered: got %v (ok=%v), want event 2", ev.ID, ok)
+	}
+	if _, ok := receive(t, mine); ok {
+		t.Fatal("filtered subscriber received an extra event")
+	}
+}
+
+func TestPublishDropsDuplicates(t *testing.T) {
+	hub := NewHub(4, 2)
+	sub := hub.Subscribe(nil)
+
+	if !hub.Publish(Event{ID: 7}) {
+		t.Fatal("first publish reported as duplicate")
+	}
+	if hub.Publish(Event{ID: 7}) {
+		t.Fatal("second publish not reported as duplicate")
+	}
+	receive(t, sub)
+	if _, ok := receive(t, sub); ok {
+		t.Fatal("duplicate delivered")
+	}
+
+	// Ids fall out of the window once enough newer events arrive.
+	hub.Publish(Event{ID: 8})
+	hub.Publish(Event{ID: 9})
+	if !hub.Publish(Event{ID: 7}) {
+		t.Fatal("id outside the window still treated as duplicate")
+	}
+}
+
+func TestZeroDedupeWindowDeliversEverything(t *testing.T) {
+	hub := NewHub(4, 0)
+	sub := hub.Subscribe(nil)
+
+	for i := 0; i < 2; i++ {
+		if !hub.Publish(Event{}) {
+			t.Fatal("publish reported as duplicate")
+		}
+		if _, ok := receive(t, sub); !ok {
+			t.Fatal("event not delivered")
+		}
+	}
+}
+
+func TestWindowAcceptsOutOfOrderIDs(t *testing.T) {
+	w := NewWindow(3)
+
+	for _, id := range []int64{5, 3, 4} {
+		if !w.Add(id) {
+			t.Fatalf("id %d reported as seen", id)
+		}
+	}
+	if w.Add(3) {
+		t.Fatal("repeated id reported as new")
+	}
+
+	w.Add(6)
+	if !w.Add(5) {
+		t.Fatal("id outside the window still reported as seen")
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub(2, 16)
+	slow := hub.Subscribe(nil)
+	fast := hub.Subscribe(nil)
+
+	for id := int64(1); id <= 3; id++ {
+		hub.Publish(Event{ID: id})
+		if id < 3 {
+			receive(t, fast)
+		}
+	}
+
+	// The slow subscriber gets what fitted in its buffer, then a closed
+	// channel.
+	for _, want := range []int64{1, 2} {
+		ev, ok := <-slow.C
+		if !ok || ev.ID != want {
+			t.Fatalf("slow: got %v (ok=%v), want event %d", ev.ID, ok, want)
+		}
+	}
+	if _, ok := <-slow.C; ok {
+		t.Fatal("slow subscriber was not dropped")
+	}
+	if ev, ok := receive(t, fast); !ok || ev.ID != 3 {
+		t.Fatalf("fast: got %v (ok=%v), want event 3", ev.ID, ok)
+	}
+	if hub.Subscribers() != 1 {
+		t.Fatalf("Subscribers() = %d, want 1", hub.Subscribers())
+	}
+
+	// Closing a dropped subscription is harmless.
+	slow.Close()
+}
+
+func TestCloseUnsubscribes(t *testing.T) {
+	hub := NewHub(2, 16)
+	sub := hub.Subscribe(nil)
+	sub.Close()
+	sub.Close()
+
+	hub.Publish(Event{ID: 1})
+	if _, ok := <-sub.C; ok {
+		t.Fatal("closed subscription received an event")
+	}
+	if hub.Subscribers() != 0 {
+		t.Fatalf("Subscribers() = %d, want 0", hub.Subscribers())
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteEvent(&buf, Event{ID: 42, Type: "chirp.created", Data: []byte("{\"a\":1}\n{\"b\":2}")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id: 42\nevent: chirp.created\ndata: {\"a\":1}\ndata: {\"b\":2}\n\n"
+	if buf.String() != want {
+		t.Errorf("WriteEvent wrote %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	WriteComment(&buf, "heartbeat")
+	if buf.String() != ": heartbeat\n\n" {
+		t.Errorf("WriteComment wrote %q", buf.String())
+	}
+}
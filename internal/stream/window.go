@@ -0,0 +1,37 @@
+package stream
+
+// Window remembers the last few event ids it was given, so that events
+// which may arrive more than once, or out of order, are handled only once.
+// The zero value remembers nothing; create one with NewWindow.
+type Window struct {
+	seen   map[int64]struct{}
+	recent []int64
+	next   int
+}
+
+// NewWindow returns a window remembering the last size ids. A window of
+// size zero treats every id as new.
+func NewWindow(size int) *Window {
+	return &Window{
+		seen:   map[int64]struct{}{},
+		recent: make([]int64, size),
+	}
+}
+
+// Add records id and reports whether it was new, forgetting the oldest id
+// once the window is full.
+func (w *Window) Add(id int64) bool {
+	if _, ok := w.seen[id]; ok {
+		return false
+	}
+	if len(w.recent) == 0 {
+		return true
+	}
+	if old := w.recent[w.next]; old != 0 {
+		delete(w.seen, old)
+	}
+	w.recent[w.next] = id
+	w.next = (w.next + 1) % len(w.recent)
+	w.seen[id] = struct{}{}
+	return true
+}
@@ -0,0 +1,110 @@
+// Package subscription tracks the state of a Chirpy Red membership as
+// payment events arrive from Polka, and decides whether it still entitles
+// the user to Red.
+package subscription
+
+import "time"
+
+type Status string
+
+const (
+	// Active memberships are paid up until the end of their period.
+	Active Status = "active"
+	// PastDue memberships had a payment fail. They keep Red until the
+	// period they paid for ends, in case a renewal comes through.
+	PastDue Status = "past_due"
+	// Canceled memberships were downgraded and lost Red straight away.
+	Canceled Status = "canceled"
+	// Expired memberships reached the end of their period unrenewed.
+	Expired Status = "expired"
+)
+
+// Event is something that happened to a membership. All but Expiry come
+// from Polka webhooks.
+type Event string
+
+const (
+	Upgraded      Event = "user.upgraded"
+	Renewed       Event = "subscription.renewed"
+	PaymentFailed Event = "payment.failed"
+	Downgraded    Event = "user.downgraded"
+	Expiry        Event = "subscription.expired"
+)
+
+var polkaEvents = map[Event]bool{
+	Upgraded:      true,
+	Renewed:       true,
+	PaymentFailed: true,
+	Downgraded:    true,
+}
+
+// FromPolka reports whether e is an event Polka sends.
+func FromPolka(e Event) bool {
+	return polkaEvents[e]
+}
+
+const (
+	// DefaultPeriod is how long a payment lasts when Polka does not say
+	// when the period ends.
+	DefaultPeriod = 30 * 24 * time.Hour
+	// GracePeriod is how long a membership with no end keeps Red after a
+	// payment fails.
+	GracePeriod = 7 * 24 * time.Hour
+)
+
+// State is a membership's status and when the period paid for ends. A
+// zero PeriodEnd means the membership does not run out, as for members who
+// joined before periods were tracked.
+type State struct {
+	Status    Status
+	PeriodEnd time.Time
+}
+
+// Entitled reports whether the membership grants Red at now.
+func (s State) Entitled(now time.Time) bool {
+	if s.Status != Active && s.Status != PastDue {
+		return false
+	}
+	return s.PeriodEnd.IsZero() || now.Before(s.PeriodEnd)
+}
+
+// Apply returns the state after event e arrives at now. current is the
+// zero State when the user has never subscribed. periodEnd is the end of
+// the period according to Polka, or zero when it was not given.
+func Apply(current State, e Event, periodEnd, now time.Time) State {
+	switch e {
+	case Upgraded:
+		if periodEnd.IsZero() {
+			periodEnd = now.Add(DefaultPeriod)
+		}
+		return State{Status: Active, PeriodEnd: periodEnd}
+	case Renewed:
+		// A renewal extends the current period rather than starting a new
+		// one, so renewing early loses nothing. A membership with no end
+		// keeps none.
+		if periodEnd.IsZero() {
+			if current.Entitled(now) && current.PeriodEnd.IsZero() {
+				return State{Status: Active}
+			}
+			start := current.PeriodEnd
+			if !current.Entitled(now) {
+				start = now
+			}
+			periodEnd = start.Add(DefaultPeriod)
+		}
+		return State{Status: Active, PeriodEnd: periodEnd}
+	case PaymentFailed:
+		if !current.Entitled(now) {
+			return State{Status: PastDue, PeriodEnd: now}
+		}
+		if current.PeriodEnd.IsZero() {
+			return State{Status: PastDue, PeriodEnd: now.Add(GracePeriod)}
+		}
+		return State{Status: PastDue, PeriodEnd: current.PeriodEnd}
+	case Downgraded:
+		return State{Status: Canceled, PeriodEnd: now}
+	case Expiry:
+		return State{Status: Expired, PeriodEnd: current.PeriodEnd}
+	}
+	return current
+}
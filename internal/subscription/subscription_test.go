@@ -0,0 +1,81 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(10 * 24 * time.Hour)
+	given := now.Add(365 * 24 * time.Hour)
+	active := State{Status: Active, PeriodEnd: later}
+	lapsed := State{Status: Expired, PeriodEnd: now.Add(-time.Hour)}
+	legacy := State{Status: Active}
+
+	tests := []struct {
+		name      string
+		current   State
+		event     Event
+		periodEnd time.Time
+		want      State
+	}{
+		{"upgrade", State{}, Upgraded, time.Time{}, State{Active, now.Add(DefaultPeriod)}},
+		{"upgrade with period end", State{}, Upgraded, given, State{Active, given}},
+		{"renew extends period", active, Renewed, time.Time{}, State{Active, later.Add(DefaultPeriod)}},
+		{"renew after lapse starts now", lapsed, Renewed, time.Time{}, State{Active, now.Add(DefaultPeriod)}},
+		{"renew with period end", active, Renewed, given, State{Active, given}},
+		{"renew past due", State{PastDue, later}, Renewed, time.Time{}, State{Active, later.Add(DefaultPeriod)}},
+		{"payment failed keeps period", active, PaymentFailed, time.Time{}, State{PastDue, later}},
+		{"payment failed without membership", State{}, PaymentFailed, time.Time{}, State{PastDue, now}},
+		{"renew without end keeps none", legacy, Renewed, time.Time{}, legacy},
+		{"renew without end with period end", legacy, Renewed, given, State{Active, given}},
+		{"payment failed without end gets grace", legacy, PaymentFailed, time.Time{}, State{PastDue, now.Add(GracePeriod)}},
+		{"downgrade without end ends now", legacy, Downgraded, time.Time{}, State{Canceled, now}},
+		{"downgrade ends now", active, Downgraded, time.Time{}, State{Canceled, now}},
+		{"expiry", State{PastDue, now}, Expiry, time.Time{}, State{Expired, now}},
+		{"unknown event", active, Event("user.renamed"), time.Time{}, active},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apply(tt.current, tt.event, tt.periodEnd, now)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntitled(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		state State
+		want  bool
+	}{
+		{State{Active, now.Add(time.Hour)}, true},
+		{State{PastDue, now.Add(time.Hour)}, true},
+		{State{Active, now}, false},
+		{State{Active, time.Time{}}, true},
+		{State{PastDue, time.Time{}}, true},
+		{State{Canceled, time.Time{}}, false},
+		{State{Canceled, now.Add(time.Hour)}, false},
+		{State{Expired, now.Add(time.Hour)}, false},
+		{State{}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.state.Entitled(now); got != tt.want {
+			t.Errorf("%+v: got %v, want %v", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestFromPolka(t *testing.T) {
+	for _, e := range []Event{Upgraded, Renewed, PaymentFailed, Downgraded} {
+		if !FromPolka(e) {
+			t.Errorf("%s should come from Polka", e)
+		}
+	}
+	if FromPolka(Expiry) || FromPolka("user.renamed") {
+		t.Error("only Polka's events should be accepted")
+	}
+}
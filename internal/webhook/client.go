@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"server/internal/netguard"
+	"time"
+)
+
+// Headers sent with every outgoing delivery.
+const (
+	SignatureHeader = "Chirpy-Signature"
+	EventHeader     = "Chirpy-Event"
+	DeliveryHeader  = "Chirpy-Delivery"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is
+	// given up on.
+	MaxAttempts = 10
+	// BaseDelay is the wait after the first failed attempt. It doubles
+	// after each further failure, up to MaxDelay.
+	BaseDelay = 30 * time.Second
+	MaxDelay  = 6 * time.Hour
+	// MaxResponseBytes is how much of a receiver's response is kept.
+	MaxResponseBytes = 1024
+)
+
+// Backoff returns how long to wait before retrying a delivery that has
+// failed attempt times.
+func Backoff(attempt int) time.Duration {
+	delay := BaseDelay
+	for i := 1; i < attempt && delay < MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxDelay)
+}
+
+// Client sends signed deliveries.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+}
+
+// NewClient returns a Client whose requests time out after timeout.
+// Redirects are not followed: a receiver that moved must be updated.
+// Unless allowPrivate is set, the client refuses to connect to loopback,
+// private and link-local addresses, so endpoints cannot be used to reach
+// the server's own network.
+func NewClient(timeout time.Duration, userAgent string, allowPrivate bool) *Client {
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if !allowPrivate {
+		client.Transport = netguard.Transport()
+	}
+	return &Client{HTTP: client, UserAgent: userAgent}
+}
+
+// Delivery is one payload on its way to one endpoint.
+type Delivery struct {
+	ID     string
+	URL    string
+	Secret string
+	Event  string
+	Body   []byte
+}
+
+// Result is what became of one attempt. StatusCode is zero when no
+// response arrived.
+type Result struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+}
+
+// OK reports whether the receiver accepted the delivery.
+func (r Result) OK() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Send POSTs d to its endpoint, signed with its secret at now. An error
+// means no response arrived; a response with any status is not an error.
+func (c *Client) Send(ctx context.Context, d Delivery, now time.Time) (Result, error) {
+	result := Result{}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set(SignatureHeader, Sign(d.Secret, d.Body, now))
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, d.ID)
+
+	start := time.Now()
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		result.Duration = time.Since(start)
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseBytes))
+	result.StatusCode = resp.StatusCode
+	result.Body = string(body)
+	result.Duration = time.Since(start)
+	return result, nil
+}
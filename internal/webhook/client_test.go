@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/netguard"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDelivery(url string) Delivery {
+	return Delivery{
+		ID:     "3f1c1a4e-0000-4000-8000-000000000001",
+		URL:    url,
+		Secret: "whsec_test",
+		Event:  "chirp.created",
+		Body:   []byte(`{"event":"chirp.created","data":{"body":"hello"}}`),
+	}
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("thanks"))
+	}))
+	defer receiver.Close()
+
+	d := testDelivery(receiver.URL + "/hook")
+	now := time.Now()
+	result, err := NewClient(time.Second, "Chirpy", true).Send(context.Background(), d, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK() || result.StatusCode != http.StatusAccepted || result.Body != "thanks" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	r := <-received
+	body := <-bodies
+	if r.Method != http.MethodPost || r.URL.Path != "/hook" {
+		t.Errorf("got %s %s", r.Method, r.URL.Path)
+	}
+	if string(body) != string(d.Body) {
+		t.Errorf("body changed in transit: %s", body)
+	}
+	if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("User-Agent") != "Chirpy" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+	if r.Header.Get(EventHeader) != d.Event || r.Header.Get(DeliveryHeader) != d.ID {
+		t.Errorf("unexpected event headers %v", r.Header)
+	}
+
+	// The receiver checks the signature the same way Chirpy checks Polka's.
+	err = Verify(d.Secret, r.Header.Get(SignatureHeader), body, time.Now(), DefaultTolerance)
+	if err != nil {
+		t.Errorf("receiver could not verify signature: %v", err)
+	}
+	err = Verify("whsec_other", r.Header.Get(SignatureHeader), body, time.Now(), DefaultTolerance)
+	if err == nil {
+		t.Error("signature verified with the wrong secret")
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/hook", http.StatusFound)
+		case "/hook":
+			w.WriteHeader(http.StatusOK)
+		case "/verbose":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("x", 2*MaxResponseBytes)))
+		}
+	}))
+	defer receiver.Close()
+	client := NewClient(time.Second, "Chirpy", true)
+
+	result, err := client.Send(context.Background(), testDelivery(receiver.URL+"/moved"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK() || result.StatusCode != http.StatusFound {
+		t.Errorf("redirect should not be followed, got %+v", result)
+	}
+
+	result, err = client.Send(context.Background(), testDelivery(receiver.URL+"/verbose"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK() || len(result.Body) != MaxResponseBytes {
+		t.Errorf("got status %d and %d bytes of body", result.StatusCode, len(result.Body))
+	}
+
+	receiver.Close()
+	result, err = client.Send(context.Background(), testDelivery(receiver.URL+"/hook"), time.Now())
+	if err == nil || result.StatusCode != 0 {
+		t.Errorf("expected a connection error, got %+v, %v", result, err)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivery reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	result, err := NewClient(time.Second, "Chirpy", false).Send(context.Background(), testDelivery(receiver.URL), time.Now())
+	if !errors.Is(err, netguard.ErrForbiddenAddress) || result.StatusCode != 0 {
+		t.Errorf("got %+v, %v; want ErrForbiddenAddress", result, err)
+	}
+}
+
+func TestSendTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer receiver.Close()
+	defer close(release)
+
+	_, err := NewClient(50*time.Millisecond, "Chirpy", true).Send(context.Background(), testDelivery(receiver.URL), time.Now())
+	if err == nil {
+		t.Error("expected a timeout")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, MaxDelay},
+		{100, MaxDelay},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	header := Sign("secret", body, now)
+
+	if !strings.HasPrefix(header, "t=1700000000,v1=") {
+		t.Fatalf("unexpected header %q", header)
+	}
+	err := Verify("secret", header, body, now.Add(time.Minute), DefaultTolerance)
+	if err != nil {
+		t.Fatalf("valid signature refused: %v", err)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1"}`)
+	header := Sign("secret", body, now)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"missing", "secret", "", body, now, ErrNoSignature},
+		{"wrong secret", "other", header, body, now, ErrBadSignature},
+		{"tampered body", "secret", header, []byte(`{"id":"evt_2"}`), now, ErrBadSignature},
+		{"too old", "secret", header, body, now.Add(DefaultTolerance + time.Second), ErrExpired},
+		{"too new", "secret", header, body, now.Add(-DefaultTolerance - time.Second), ErrExpired},
+		{"no timestamp", "secret", strings.Split(header, ",")[1], body, now, ErrBadSignature},
+		{"no v1", "secret", "t=1700000000", body, now, ErrBadSignature},
+		{"bad hex", "secret", "t=1700000000,v1=zz", body, now, ErrBadSignature},
+		{"malformed", "secret", "garbage", body, now, ErrBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, tt.now, DefaultTolerance)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// During a secret rotation the sender signs with both secrets, and either
+// one is enough.
+func TestVerifyAnySignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{}`)
+	oldSig := strings.Split(Sign("old", body, now), ",")[1]
+	newHeader := Sign("new", body, now)
+
+	header := newHeader + "," + oldSig
+	for _, secret := range []string{"old", "new"} {
+		err := Verify(secret, header, body, now, DefaultTolerance)
+		if err != nil {
+			t.Errorf("secret %q refused: %v", secret, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"server/internal/database"
+	"server/internal/notifications"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), userID, chirpID)
+	if err != nil || chirp.Status != chirpStatusPublished {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Liking twice is not an error; the second like is simply ignored.
+	liked, err := cfg.dbQueries.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if liked > 0 {
+		cfg.notify(r.Context(), chirp.UserID, notifications.Like, userID, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerGetChirpLikes(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	viewer := cfg.viewerID(r)
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), viewer, chirpID)
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	// Users on either side of a block with the viewer are left out.
+	users, err := cfg.dbQueries.GetChirpLikers(r.Context(), database.GetChirpLikersParams{
+		ChirpID:    chirp.ID,
+		ViewerID:   viewer,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnPublicUser{}
+	for _, user := range users {
+		respBody = append(respBody, publicUser(user))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetUserLikes(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	user, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// A user's likes say who they have been reading, so they are hidden
+	// from anyone on either side of a block.
+	viewer := cfg.viewerID(r)
+	blocked, err := cfg.isBlocked(r.Context(), viewer, user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if blocked {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	chirps, err := cfg.dbQueries.GetUserLikedChirps(r.Context(), database.GetUserLikedChirpsParams{
+		UserID:     user.ID,
+		ViewerID:   viewer,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody, err := cfg.chirpResponses(r.Context(), viewer, chirps)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respBody)
+}
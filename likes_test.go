@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// likeRequest likes (POST) or unlikes (DELETE) chirpID as userID.
+func likeRequest(t *testing.T, cfg *apiConfig, method string, userID, chirpID uuid.UUID) {
+	t.Helper()
+	r := testRequest(t, cfg, method, "/api/chirps/"+chirpID.String()+"/like", userID, nil)
+	r.SetPathValue("chirpID", chirpID.String())
+	handler := cfg.handlerLikeChirp
+	if method == http.MethodDelete {
+		handler = cfg.handlerUnlikeChirp
+	}
+	serve(t, handler, r, 204)
+}
+
+func TestLikesAreCountedOncePerUser(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	fan := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+
+	// Liking twice is the same as liking once.
+	likeRequest(t, cfg, "POST", fan.ID, chirp.ID)
+	likeRequest(t, cfg, "POST", fan.ID, chirp.ID)
+	likeRequest(t, cfg, "POST", author.ID, chirp.ID)
+
+	got := getChirp(t, cfg, fan.ID, chirp.ID)
+	if got.LikeCount != 2 || got.LikedByMe == nil || !*got.LikedByMe {
+		t.Errorf("fan sees %d likes, liked_by_me %v; want 2 and true", got.LikeCount, got.LikedByMe)
+	}
+
+	likeRequest(t, cfg, "DELETE", fan.ID, chirp.ID)
+	got = getChirp(t, cfg, fan.ID, chirp.ID)
+	if got.LikeCount != 1 || got.LikedByMe == nil || *got.LikedByMe {
+		t.Errorf("after unliking, fan sees %d likes, liked_by_me %v; want 1 and false", got.LikeCount, got.LikedByMe)
+	}
+
+	// Anonymous readers get the count but no liked_by_me.
+	got = getChirp(t, cfg, uuid.Nil, chirp.ID)
+	if got.LikeCount != 1 || got.LikedByMe != nil {
+		t.Errorf("anonymous reader sees %d likes, liked_by_me %v; want 1 and none", got.LikeCount, got.LikedByMe)
+	}
+}
+
+func TestChirpLikersAndUserLikes(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	fan := createTestUser(t, cfg)
+	liked := createTestChirp(t, cfg, author.ID, chirpInput{})
+	createTestChirp(t, cfg, author.ID, chirpInput{})
+	likeRequest(t, cfg, "POST", fan.ID, liked.ID)
+
+	r := testRequest(t, cfg, "GET", "/api/chirps/"+liked.ID.String()+"/likes", uuid.Nil, nil)
+	r.SetPathValue("chirpID", liked.ID.String())
+	w := serve(t, cfg.handlerGetChirpLikes, r, 200)
+	var likers []returnPublicUser
+	err := json.NewDecoder(w.Body).Decode(&likers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(likers) != 1 || likers[0].ID != fan.ID {
+		t.Errorf("got likers %+v, want the fan", likers)
+	}
+
+	r = testRequest(t, cfg, "GET", "/api/users/"+fan.ID.String()+"/likes", uuid.Nil, nil)
+	r.SetPathValue("id", fan.ID.String())
+	w = serve(t, cfg.handlerGetUserLikes, r, 200)
+	var chirps []returnChirp
+	err = json.NewDecoder(w.Body).Decode(&chirps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chirps) != 1 || chirps[0].ID != liked.ID {
+		t.Errorf("got liked chirps %+v, want the liked chirp", chirps)
+	}
+}
@@ -5,8 +5,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"server/internal/activitypub"
+	"server/internal/blobstore"
 	"server/internal/database"
+	"server/internal/entitlements"
+	"server/internal/moderation"
+	"server/internal/netguard"
+	"server/internal/stream"
+	"server/internal/webhook"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -14,10 +24,28 @@ import (
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	// db is only used directly to begin transactions; see withTx.
+	db             *sql.DB
 	dbQueries      *database.Queries
 	platform       string
 	secret         string
 	polkaKey       string
+	entitlements   entitlements.Config
+	moderator      *moderation.Moderator
+	adminKey       string
+	blobs          blobstore.BlobStore
+	maxUploadBytes int64
+	restoreWindow  time.Duration
+	publicURL      string
+	apClient       *activitypub.Client
+	webhookClient  *webhook.Client
+	// webhookWake nudges this instance's webhook worker when deliveries
+	// are queued.
+	webhookWake chan struct{}
+	hub         *stream.Hub
+	// notificationHub carries rendered notifications to WebSocket
+	// clients. Its events arrive only via NOTIFY, so it does not dedupe.
+	notificationHub *stream.Hub
 }
 
 func main() {
@@ -26,19 +54,88 @@ func main() {
 	platform := os.Getenv("PLATFORM")
 	secret := os.Getenv("SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
+	adminKey := os.Getenv("ADMIN_KEY")
+	moderator, err := moderation.NewModerator(os.Getenv("MODERATION_CONFIG"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	entitlementConfig := entitlements.DefaultConfig()
+	// CHIRP_EDIT_WINDOW predates tiers. It still sets every tier's edit
+	// window, unless ENTITLEMENTS_CONFIG gives one.
+	editWindow, err := time.ParseDuration(os.Getenv("CHIRP_EDIT_WINDOW"))
+	if err == nil && editWindow >= 0 {
+		entitlementConfig = entitlementConfig.WithEditWindow(editWindow)
+	}
+	if path := os.Getenv("ENTITLEMENTS_CONFIG"); path != "" {
+		entitlementConfig, err = entitlements.LoadConfig(path, entitlementConfig)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	restoreWindow, err := time.ParseDuration(os.Getenv("CHIRP_RESTORE_WINDOW"))
+	if err != nil || restoreWindow <= 0 {
+		restoreWindow = defaultRestoreWindow
+	}
+	schedulerInterval, err := time.ParseDuration(os.Getenv("SCHEDULER_INTERVAL"))
+	if err != nil || schedulerInterval <= 0 {
+		schedulerInterval = defaultSchedulerInterval
+	}
+	blobs, err := newBlobStore()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	maxUploadBytes, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_BYTES"), 10, 64)
+	if err != nil || maxUploadBytes <= 0 {
+		maxUploadBytes = defaultMaxUploadBytes
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		fmt.Println(err)
 	}
 
+	// Remote servers are named by whoever sends us an activity, so outside
+	// dev the federation client only connects to public addresses.
+	apHTTP := &http.Client{Timeout: 10 * time.Second}
+	if platform != "dev" {
+		apHTTP.Transport = netguard.Transport()
+	}
+
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
+		db:             db,
 		dbQueries:      database.New(db),
 		platform:       platform,
 		secret:         secret,
 		polkaKey:       polkaKey,
+		entitlements:   entitlementConfig,
+		moderator:      moderator,
+		adminKey:       adminKey,
+		blobs:          blobs,
+		maxUploadBytes: maxUploadBytes,
+		restoreWindow:  restoreWindow,
+		publicURL:      strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+		apClient: &activitypub.Client{
+			HTTP:      apHTTP,
+			UserAgent: "Chirpy",
+		},
+		webhookClient:   webhook.NewClient(webhookTimeout, "Chirpy-Webhooks", platform == "dev"),
+		webhookWake:     make(chan struct{}, 1),
+		hub:             stream.NewHub(streamBufferSize, streamDedupeWindow),
+		notificationHub: stream.NewHub(streamBufferSize, 0),
 	}
 
+	go apiCfg.reloadModerationOnHangup()
+
+	go apiCfg.runScheduler(schedulerInterval)
+
+	go apiCfg.runPurge()
+	go apiCfg.runSubscriptionExpiry()
+	go apiCfg.runWebhookDeliveries()
+	go apiCfg.listenForEvents(dbURL)
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
@@ -51,25 +148,174 @@ func main() {
 
 	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
 
+	mux.HandleFunc("POST /admin/moderation/reload", apiCfg.handlerReloadModeration)
+
 	mux.HandleFunc("POST /api/users", apiCfg.handlerMakeUser)
 
 	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUser)
 
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.handlerUpdateProfile)
+
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.handlerGetEntitlements)
+
+	mux.HandleFunc("GET /api/users/me/subscription", apiCfg.handlerGetSubscription)
+
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.handlerGetProfile)
+
+	mux.HandleFunc("GET /api/users/{id}/likes", apiCfg.handlerGetUserLikes)
+
+	mux.HandleFunc("POST /api/users/{id}/follow", apiCfg.handlerFollowUser)
+
+	mux.HandleFunc("DELETE /api/users/{id}/follow", apiCfg.handlerUnfollowUser)
+
+	mux.HandleFunc("GET /api/users/{id}/followers", apiCfg.handlerGetFollowers)
+
+	mux.HandleFunc("GET /api/users/{id}/following", apiCfg.handlerGetFollowing)
+
+	mux.HandleFunc("POST /api/users/{id}/block", apiCfg.handlerBlockUser)
+
+	mux.HandleFunc("DELETE /api/users/{id}/block", apiCfg.handlerUnblockUser)
+
+	mux.HandleFunc("POST /api/users/{id}/mute", apiCfg.handlerMuteUser)
+
+	mux.HandleFunc("DELETE /api/users/{id}/mute", apiCfg.handlerUnmuteUser)
+
+	mux.HandleFunc("GET /api/users/me/blocks", apiCfg.handlerGetBlocks)
+
+	mux.HandleFunc("GET /api/users/me/mutes", apiCfg.handlerGetMutes)
+
+	mux.HandleFunc("GET /api/users/me/follow-requests", apiCfg.handlerGetFollowRequests)
+
+	mux.HandleFunc("POST /api/users/me/follow-requests/{followerID}/approve", apiCfg.handlerApproveFollowRequest)
+
+	mux.HandleFunc("DELETE /api/users/me/follow-requests/{followerID}", apiCfg.handlerRejectFollowRequest)
+
+	mux.HandleFunc("GET /api/notifications", apiCfg.handlerGetNotifications)
+
+	mux.HandleFunc("GET /api/notifications/unread", apiCfg.handlerGetUnreadNotifications)
+
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.handlerMarkAllNotificationsRead)
+
+	mux.HandleFunc("POST /api/notifications/{notificationID}/read", apiCfg.handlerMarkNotificationRead)
+
+	mux.HandleFunc("POST /api/conversations", apiCfg.handlerStartConversation)
+
+	mux.HandleFunc("GET /api/conversations", apiCfg.handlerGetConversations)
+
+	mux.HandleFunc("GET /api/conversations/{conversationID}", apiCfg.handlerGetConversation)
+
+	mux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.handlerGetMessages)
+
+	mux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.handlerSendMessage)
+
+	mux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.handlerMarkConversationRead)
+
+	mux.HandleFunc("POST /api/conversations/{conversationID}/leave", apiCfg.handlerLeaveConversation)
+
 	mux.HandleFunc("POST /api/login", apiCfg.handlerUserLogin)
 
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
 
+	mux.HandleFunc("GET /api/stream", apiCfg.handlerStream)
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
+
+	mux.HandleFunc("GET /users/{id}/feed.rss", apiCfg.handlerUserFeed)
+	mux.HandleFunc("GET /users/{id}/feed.atom", apiCfg.handlerUserFeed)
+	mux.HandleFunc("GET /users/{id}/feed.json", apiCfg.handlerUserFeed)
+	mux.HandleFunc("GET /tags/{tag}/feed.rss", apiCfg.handlerTagFeed)
+	mux.HandleFunc("GET /tags/{tag}/feed.atom", apiCfg.handlerTagFeed)
+	mux.HandleFunc("GET /tags/{tag}/feed.json", apiCfg.handlerTagFeed)
+
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.handlerWebFinger)
+	mux.HandleFunc("GET /ap/users/{id}", apiCfg.handlerGetActor)
+	mux.HandleFunc("POST /ap/users/{id}/inbox", apiCfg.handlerInbox)
+	mux.HandleFunc("GET /ap/users/{id}/outbox", apiCfg.handlerGetOutbox)
+	mux.HandleFunc("GET /ap/users/{id}/followers", apiCfg.handlerGetFollowersCollection)
+	mux.HandleFunc("GET /ap/chirps/{chirpID}", apiCfg.handlerGetNote)
+
+	mux.HandleFunc("GET /api/timeline", apiCfg.handlerGetTimeline)
+
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetChirps)
 
 	mux.HandleFunc("POST /api/chirps", apiCfg.handlerPostChirp)
 
+	mux.HandleFunc("GET /api/chirps/scheduled", apiCfg.handlerGetScheduledChirps)
+
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
 
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handlerEditChirp)
+
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
 
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerRedUser)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", apiCfg.handlerRestoreChirp)
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/history", apiCfg.handlerGetChirpHistory)
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.handlerGetThread)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handlerLikeChirp)
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handlerUnlikeChirp)
+
+	mux.HandleFunc("GET /api/chirps/{chirpID}/likes", apiCfg.handlerGetChirpLikes)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.handlerRechirp)
+
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.handlerUndoRechirp)
+
+	mux.HandleFunc("POST /api/drafts", apiCfg.handlerCreateDraft)
+
+	mux.HandleFunc("GET /api/drafts", apiCfg.handlerGetDrafts)
+
+	mux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.handlerGetDraft)
+
+	mux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.handlerUpdateDraft)
+
+	mux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.handlerDeleteDraft)
+
+	mux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.handlerPublishDraft)
+
+	mux.HandleFunc("POST /api/media", apiCfg.handlerUploadMedia)
+
+	mux.HandleFunc("GET /api/media/{mediaID}", apiCfg.handlerGetMedia)
+
+	mux.HandleFunc("GET /api/media/{mediaID}/thumbnail", apiCfg.handlerGetMediaThumbnail)
+
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.handlerReportChirp)
+
+	mux.HandleFunc("GET /api/moderation/reports", apiCfg.handlerListReports)
+
+	mux.HandleFunc("GET /api/moderation/reports/{reportID}", apiCfg.handlerGetReport)
+
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/actions", apiCfg.handlerTakeModerationAction)
+
+	mux.HandleFunc("POST /api/moderation/reports/{reportID}/resolve", apiCfg.handlerResolveReport)
+
+	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
+
+	mux.HandleFunc("POST /api/webhooks", apiCfg.handlerCreateWebhook)
+
+	mux.HandleFunc("GET /api/webhooks", apiCfg.handlerGetWebhooks)
+
+	mux.HandleFunc("GET /api/webhooks/{webhookID}", apiCfg.handlerGetWebhook)
+
+	mux.HandleFunc("DELETE /api/webhooks/{webhookID}", apiCfg.handlerDeleteWebhook)
+
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/verify", apiCfg.handlerVerifyWebhook)
+
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries", apiCfg.handlerGetWebhookDeliveries)
+
+	mux.HandleFunc("GET /api/webhooks/{webhookID}/deliveries/{deliveryID}", apiCfg.handlerGetWebhookDelivery)
+
+	mux.HandleFunc("POST /api/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", apiCfg.handlerRedeliverWebhook)
+
+	mux.HandleFunc("GET /chirps/{chirpID}", apiCfg.handlerChirpPage)
+
+	mux.HandleFunc("GET /users/{id}", apiCfg.handlerProfilePage)
+
+	mux.HandleFunc("GET /api/oembed", apiCfg.handlerOEmbed)
 
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
 
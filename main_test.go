@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"server/internal/activitypub"
+	"server/internal/auth"
+	"server/internal/blobstore"
+	"server/internal/database"
+	"server/internal/entitlements"
+	"server/internal/moderation"
+	"server/internal/stream"
+	"server/internal/webhook"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestConfig needs TEST_DB_URL to point at a migrated database, like
+// the timeline benchmarks. It runs as the dev platform and does not
+// federate.
+func newTestConfig(t *testing.T) *apiConfig {
+	t.Helper()
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	moderator, err := moderation.NewModerator("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &apiConfig{
+		db:             db,
+		dbQueries:      database.New(db),
+		platform:       "dev",
+		secret:         "test-secret",
+		entitlements:   entitlements.DefaultConfig(),
+		moderator:      moderator,
+		blobs:          blobs,
+		maxUploadBytes: defaultMaxUploadBytes,
+		restoreWindow:  defaultRestoreWindow,
+		apClient: &activitypub.Client{
+			HTTP:      &http.Client{Timeout: time.Second},
+			UserAgent: "Chirpy",
+		},
+		webhookClient:   webhook.NewClient(webhookTimeout, "Chirpy-Webhooks", true),
+		webhookWake:     make(chan struct{}, 1),
+		hub:             stream.NewHub(streamBufferSize, streamDedupeWindow),
+		notificationHub: stream.NewHub(streamBufferSize, 0),
+	}
+}
+
+// createTestUser adds a user that is removed, with everything it made,
+// when the test ends.
+func createTestUser(t *testing.T, cfg *apiConfig) database.User {
+	t.Helper()
+	user, err := cfg.dbQueries.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          uuid.NewString() + "@test.invalid",
+		HashedPassword: "unused",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cfg.db.Exec("DELETE FROM users WHERE id = $1", user.ID)
+	})
+	return user
+}
+
+// createTestChirp posts a chirp the way POST /api/chirps does.
+func createTestChirp(t *testing.T, cfg *apiConfig, userID uuid.UUID, in chirpInput) database.Chirp {
+	t.Helper()
+	if in.Body == "" {
+		in.Body = "test chirp"
+	}
+	chirp, err := cfg.createChirp(context.Background(), userID, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chirp
+}
+
+// testRequest builds a request made by userID, or anonymously if userID is
+// uuid.Nil. A non-nil body is sent as JSON.
+func testRequest(t *testing.T, cfg *apiConfig, method, target string, userID uuid.UUID, body any) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&buf).Encode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, target, &buf)
+	if userID != uuid.Nil {
+		token, err := auth.MakeJWT(userID, cfg.secret, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+// serve runs handler on r and fails the test unless it responds with
+// want.
+func serve(t *testing.T, handler http.HandlerFunc, r *http.Request, want int) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != want {
+		t.Fatalf("%s %s: got status %d, want %d: %s", r.Method, r.URL, w.Code, want, w.Body.String())
+	}
+	return w
+}
+
+// requestErrorCode returns the status a requestError carries, or 0 for
+// any other error.
+func requestErrorCode(err error) int {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.code
+	}
+	return 0
+}
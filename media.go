@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"server/internal/blobstore"
+	"server/internal/database"
+	"server/internal/media"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const defaultMaxUploadBytes = 5 << 20
+
+// unattachedMediaTTL is how long an upload may go without being attached
+// to a chirp before it is purged.
+const unattachedMediaTTL = 24 * time.Hour
+
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// newBlobStore picks the storage backend from MEDIA_STORE: "s3" for an
+// S3-compatible bucket, anything else for files under MEDIA_DIR.
+func newBlobStore() (blobstore.BlobStore, error) {
+	if os.Getenv("MEDIA_STORE") == "s3" {
+		return blobstore.NewS3Store(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_REGION"),
+			blobstore.Credentials{
+				AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			},
+		)
+	}
+
+	dir := os.Getenv("MEDIA_DIR")
+	if dir == "" {
+		dir = "media"
+	}
+	return blobstore.NewLocalStore(dir)
+}
+
+func mediaResponse(file database.MediaFile) returnMedia {
+	return returnMedia{
+		ID:           file.ID,
+		URL:          "/api/media/" + file.ID.String(),
+		ThumbnailURL: "/api/media/" + file.ID.String() + "/thumbnail",
+		ContentType:  file.ContentType,
+		Width:        file.Width,
+		Height:       file.Height,
+	}
+}
+
+// mediaAttachable reports whether every one of ids is an upload of userID's
+// that is not attached to a chirp yet.
+func (cfg *apiConfig) mediaAttachable(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (bool, error) {
+	seen := map[uuid.UUID]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return false, nil
+		}
+		seen[id] = true
+	}
+
+	files, err := cfg.dbQueries.GetMediaFilesByIDs(ctx, ids)
+	if err != nil {
+		return false, err
+	}
+	if len(files) != len(ids) {
+		return false, nil
+	}
+	for _, file := range files {
+		if file.UserID != userID || file.ChirpID.Valid {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// removeChirpMedia detaches and deletes everything attached to a chirp that
+// is being deleted. The chirp is going regardless, so blob store failures
+// are only logged.
+func (cfg *apiConfig) removeChirpMedia(ctx context.Context, chirpID uuid.UUID) error {
+	files, err := cfg.dbQueries.DeleteChirpMedia(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return err
+	}
+	cfg.deleteMediaBlobs(ctx, files)
+	return nil
+}
+
+func (cfg *apiConfig) deleteMediaBlobs(ctx context.Context, files []database.MediaFile) {
+	for _, file := range files {
+		for _, key := range []string{file.StorageKey, file.ThumbnailKey} {
+			err := cfg.blobs.Delete(ctx, key)
+			if err != nil {
+				fmt.Println("deleting media blob:", err)
+			}
+		}
+	}
+}
+
+// purgeUnattachedMedia removes uploads that were never attached to a
+// chirp, or whose chirp went away, once unattachedMediaTTL has passed.
+func (cfg *apiConfig) purgeUnattachedMedia(ctx context.Context) {
+	for {
+		files, err := cfg.dbQueries.DeleteUnattachedMedia(ctx, database.DeleteUnattachedMediaParams{
+			CreatedBefore: time.Now().Add(-unattachedMediaTTL),
+			BatchSize:     purgeBatchSize,
+		})
+		if err != nil {
+			fmt.Println("purging unattached media:", err)
+			return
+		}
+		cfg.deleteMediaBlobs(ctx, files)
+		if len(files) < purgeBatchSize {
+			return
+		}
+	}
+}
+
+func (cfg *apiConfig) handlerUploadMedia(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.maxUploadBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			msg = "File is too large"
+			code = 413
+		} else {
+			msg = "Expected a multipart form with a file field"
+			code = 400
+		}
+		respondWithError(w, code, msg)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, cfg.maxUploadBytes+1))
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if int64(len(data)) > cfg.maxUploadBytes {
+		msg = "File is too large"
+		code = 413
+		respondWithError(w, code, msg)
+		return
+	}
+
+	img, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		msg = "Only JPEG, PNG and GIF images are supported"
+		code = 415
+		respondWithError(w, code, msg)
+		return
+	}
+	if errors.Is(err, media.ErrTooLarge) {
+		msg = "Image dimensions are too large"
+		code = 413
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Invalid image"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	id := uuid.New()
+	key := "media/" + id.String() + mediaExtensions[img.ContentType]
+	thumbnailKey := "media/" + id.String() + "_thumb" + mediaExtensions[img.ThumbnailContentType]
+
+	err = cfg.blobs.Put(r.Context(), key, img.Data, img.ContentType)
+	if err == nil {
+		err = cfg.blobs.Put(r.Context(), thumbnailKey, img.Thumbnail, img.ThumbnailContentType)
+	}
+	if err != nil {
+		fmt.Println("storing media:", err)
+		cfg.blobs.Delete(r.Context(), key)
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	mediaFile, err := cfg.dbQueries.CreateMediaFile(r.Context(), database.CreateMediaFileParams{
+		ID:           id,
+		UserID:       userID,
+		ContentType:  img.ContentType,
+		SizeBytes:    int64(len(img.Data)),
+		Width:        int32(img.Width),
+		Height:       int32(img.Height),
+		StorageKey:   key,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		cfg.blobs.Delete(r.Context(), key)
+		cfg.blobs.Delete(r.Context(), thumbnailKey)
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, mediaResponse(mediaFile))
+}
+
+// canViewMedia applies the visibility of the chirp a file is attached to;
+// files not yet attached are only visible to the user who uploaded them.
+func (cfg *apiConfig) canViewMedia(ctx context.Context, viewer uuid.UUID, file database.MediaFile) (bool, error) {
+	if !file.ChirpID.Valid {
+		return file.UserID == viewer, nil
+	}
+	chirp, err := cfg.dbQueries.GetChirp(ctx, file.ChirpID.UUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return cfg.canViewChirp(ctx, viewer, chirp)
+}
+
+func (cfg *apiConfig) handlerGetMedia(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, false)
+}
+
+func (cfg *apiConfig) handlerGetMediaThumbnail(w http.ResponseWriter, r *http.Request) {
+	cfg.serveMedia(w, r, true)
+}
+
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	msg := ""
+	code := 200
+
+	mediaID, err := uuid.Parse(r.PathValue("mediaID"))
+	if err != nil {
+		msg = "Invalid media ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	file, err := cfg.dbQueries.GetMediaFile(r.Context(), mediaID)
+	if err != nil {
+		msg = "Media not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	ok, err := cfg.canViewMedia(r.Context(), cfg.viewerID(r), file)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if !ok {
+		msg = "Media not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	key := file.StorageKey
+	contentType := file.ContentType
+	if thumbnail {
+		key = file.ThumbnailKey
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+
+	blob, err := cfg.blobs.Get(r.Context(), key)
+	if err != nil {
+		msg = "Media not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	io.Copy(w, blob)
+}
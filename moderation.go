@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"server/internal/auth"
+	"syscall"
+)
+
+// reloadModerationOnHangup reloads the moderation rules whenever the process
+// receives SIGHUP.
+func (cfg *apiConfig) reloadModerationOnHangup() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		err := cfg.moderator.Reload()
+		if err != nil {
+			fmt.Println("reloading moderation rules:", err)
+			continue
+		}
+		fmt.Println("moderation rules reloaded")
+	}
+}
+
+func (cfg *apiConfig) handlerReloadModeration(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil || cfg.adminKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.adminKey)) != 1 {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.moderator.Reload()
+	if err != nil {
+		msg = fmt.Sprintf("Could not reload moderation rules: %v", err)
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
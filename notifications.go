@@ -0,0 +1,442 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/internal/database"
+	"server/internal/notifications"
+	"server/internal/stream"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// notificationActorsShown is how many of a group's actors are listed and
+// named in its summary; the rest are only counted.
+const notificationActorsShown = 3
+
+// notificationEvent is the event type WebSocket clients receive new and
+// updated notifications as.
+const notificationEvent = "notification"
+
+// notify records that actor (uuid.Nil for system events) did something of
+// type typ that recipient should hear about, grouping it with any unread
+// notification of the same kind. Nobody is notified about their own
+// actions, or about users they have blocked, been blocked by or muted.
+// Notifications are best effort: failures are logged, not returned.
+// Connected WebSocket clients are sent the notification as it now stands.
+func (cfg *apiConfig) notify(ctx context.Context, recipient uuid.UUID, typ notifications.Type, actor uuid.UUID, chirpID uuid.NullUUID) {
+	if recipient == actor {
+		return
+	}
+
+	if actor != uuid.Nil {
+		blocked, err := cfg.isBlocked(ctx, recipient, actor)
+		if err != nil {
+			fmt.Println("notifying:", err)
+			return
+		}
+		muted, err := cfg.dbQueries.IsMuted(ctx, database.IsMutedParams{
+			MuterID: recipient,
+			MutedID: actor,
+		})
+		if err != nil {
+			fmt.Println("notifying:", err)
+			return
+		}
+		if blocked || muted {
+			return
+		}
+	}
+
+	notification, err := cfg.dbQueries.UpsertNotification(ctx, database.UpsertNotificationParams{
+		UserID:   recipient,
+		Type:     string(typ),
+		GroupKey: notifications.GroupKey(typ, chirpID.UUID),
+		ChirpID:  chirpID,
+	})
+	if err != nil {
+		fmt.Println("notifying:", err)
+		return
+	}
+
+	if actor != uuid.Nil {
+		err = cfg.dbQueries.AddNotificationActor(ctx, database.AddNotificationActorParams{
+			NotificationID: notification.ID,
+			ActorID:        actor,
+		})
+		if err != nil {
+			fmt.Println("notifying:", err)
+			return
+		}
+	}
+
+	err = cfg.dbQueries.AnnounceNotification(ctx, notification.ID.String())
+	if err != nil {
+		fmt.Println("notifying:", err)
+	}
+}
+
+// publishNotification renders an announced notification for its recipient
+// and hands it to this instance's WebSocket clients.
+func (cfg *apiConfig) publishNotification(ctx context.Context, id uuid.UUID) {
+	notification, err := cfg.dbQueries.GetNotification(ctx, id)
+	if err != nil {
+		fmt.Println("publishing notification:", err)
+		return
+	}
+	respBody, err := cfg.notificationResponses(ctx, notification.UserID, []database.Notification{notification})
+	if err != nil {
+		fmt.Println("publishing notification:", err)
+		return
+	}
+	data, err := json.Marshal(respBody[0])
+	if err != nil {
+		fmt.Println("publishing notification:", err)
+		return
+	}
+	cfg.notificationHub.Publish(stream.Event{
+		Type:   notificationEvent,
+		UserID: notification.UserID,
+		Data:   data,
+	})
+}
+
+// afterChirpPublished runs once a chirp becomes visible to others, whether
+// straight away, when its scheduled time comes or when a moderator
+// approves it. It streams and federates the chirp, and tells the author of
+// the parent chirp about the reply and everyone mentioned about the
+// mention, as long as they can see the chirp. Its webhooks are left to the
+// caller, which queues them in the transaction that publishes it where
+// there is one.
+func (cfg *apiConfig) afterChirpPublished(ctx context.Context, chirp database.Chirp) {
+	cfg.recordChirpEvent(ctx, chirpEventCreated, chirp)
+	cfg.federateChirp(chirpEventCreated, chirp)
+
+	notified := map[uuid.UUID]bool{chirp.UserID: true}
+
+	if chirp.InReplyTo.Valid {
+		parent, err := cfg.dbQueries.GetChirp(ctx, chirp.InReplyTo.UUID)
+		if err == nil && !notified[parent.UserID] {
+			notified[parent.UserID] = true
+			cfg.notifyAboutChirp(ctx, parent.UserID, notifications.Reply, chirp)
+		}
+	}
+
+	for _, handle := range mentionedHandles(chirp.Body) {
+		user, err := cfg.dbQueries.GetUserByHandle(ctx, handle)
+		if err != nil || notified[user.ID] {
+			continue
+		}
+		notified[user.ID] = true
+		cfg.notifyAboutChirp(ctx, user.ID, notifications.Mention, chirp)
+	}
+}
+
+// afterChirpEdited announces the new body of a published chirp.
+func (cfg *apiConfig) afterChirpEdited(ctx context.Context, chirp database.Chirp) {
+	cfg.recordChirpEvent(ctx, chirpEventUpdated, chirp)
+	cfg.federateChirpUpdate(chirp)
+	cfg.enqueueWebhooks(ctx, chirpEventUpdated, chirp)
+}
+
+// afterChirpWithdrawn runs when an edit sends a published chirp back for
+// review. It is announced as deleted, with chirp as it was before the
+// edit, until it is released.
+func (cfg *apiConfig) afterChirpWithdrawn(ctx context.Context, chirp database.Chirp) {
+	cfg.recordChirpEvent(ctx, chirpEventDeleted, chirp)
+	cfg.federateChirp(chirpEventDeleted, chirp)
+	cfg.enqueueWebhooks(ctx, chirpEventDeleted, chirp)
+}
+
+// afterChirpReleased runs when a held chirp becomes visible, after an edit
+// or a moderator's approval. A chirp that was published before it was held
+// was withdrawn then, so it is announced again, but people were already
+// notified about it.
+func (cfg *apiConfig) afterChirpReleased(ctx context.Context, chirp database.Chirp) {
+	published, err := cfg.dbQueries.ChirpWasPublished(ctx, chirp.ID)
+	if err != nil {
+		fmt.Println("releasing chirp:", err)
+		return
+	}
+	cfg.enqueueWebhooks(ctx, chirpEventCreated, chirp)
+	if published {
+		cfg.recordChirpEvent(ctx, chirpEventCreated, chirp)
+		cfg.federateChirp(chirpEventCreated, chirp)
+		return
+	}
+	cfg.afterChirpPublished(ctx, chirp)
+}
+
+func (cfg *apiConfig) notifyAboutChirp(ctx context.Context, recipient uuid.UUID, typ notifications.Type, chirp database.Chirp) {
+	visible, err := cfg.canViewChirp(ctx, recipient, chirp)
+	if err != nil {
+		fmt.Println("notifying:", err)
+		return
+	}
+	if !visible {
+		return
+	}
+	cfg.notify(ctx, recipient, typ, chirp.UserID, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+}
+
+// displayName is how a user is named in notification summaries.
+func displayName(user database.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	if user.Handle.Valid {
+		return "@" + user.Handle.String
+	}
+	return "Someone"
+}
+
+// notificationResponses converts notifications in one batch, loading their
+// most recent actors and the chirps they are about. Chirps the viewer can
+// no longer see are left out, but the notification is kept.
+func (cfg *apiConfig) notificationResponses(ctx context.Context, viewer uuid.UUID, list []database.Notification) ([]returnNotification, error) {
+	ids := []uuid.UUID{}
+	chirpIDs := []uuid.UUID{}
+	for _, n := range list {
+		ids = append(ids, n.ID)
+		if n.ChirpID.Valid {
+			chirpIDs = append(chirpIDs, n.ChirpID.UUID)
+		}
+	}
+
+	actorRows, err := cfg.dbQueries.GetNotificationActors(ctx, database.GetNotificationActorsParams{
+		NotificationIds: ids,
+		PerNotification: notificationActorsShown,
+	})
+	if err != nil {
+		return nil, err
+	}
+	actors := map[uuid.UUID][]database.User{}
+	for _, row := range actorRows {
+		actors[row.NotificationID] = append(actors[row.NotificationID], row.User)
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	visible := []database.Chirp{}
+	for _, chirp := range chirps {
+		ok, err := cfg.canViewChirp(ctx, viewer, chirp)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, chirp)
+		}
+	}
+	respChirps, err := cfg.chirpResponses(ctx, viewer, visible)
+	if err != nil {
+		return nil, err
+	}
+	chirpsByID := map[uuid.UUID]returnChirp{}
+	for _, chirp := range respChirps {
+		chirpsByID[chirp.ID] = chirp
+	}
+
+	respBody := []returnNotification{}
+	for _, n := range list {
+		respNotification := returnNotification{
+			ID:         n.ID,
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+			Type:       n.Type,
+			Read:       n.ReadAt.Valid,
+			ActorCount: n.ActorCount,
+			Actors:     []returnPublicUser{},
+		}
+		if n.ReadAt.Valid {
+			respNotification.ReadAt = &n.ReadAt.Time
+		}
+
+		names := []string{}
+		for _, actor := range actors[n.ID] {
+			respNotification.Actors = append(respNotification.Actors, publicUser(actor))
+			names = append(names, displayName(actor))
+		}
+		respNotification.Summary = notifications.Summary(notifications.Type(n.Type), names, int(n.ActorCount))
+
+		if n.ChirpID.Valid {
+			respNotification.ChirpID = &n.ChirpID.UUID
+			if chirp, ok := chirpsByID[n.ChirpID.UUID]; ok {
+				respNotification.Chirp = &chirp
+			}
+		}
+
+		respBody = append(respBody, respNotification)
+	}
+
+	return respBody, nil
+}
+
+// notificationTypeParam reads the optional type filter from the query
+// string.
+func notificationTypeParam(r *http.Request) (sql.NullString, bool) {
+	typ := r.URL.Query().Get("type")
+	if typ == "" {
+		return sql.NullString{}, true
+	}
+	if !notifications.Valid(notifications.Type(typ)) {
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: typ, Valid: true}, true
+}
+
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	typ, ok := notificationTypeParam(r)
+	if !ok {
+		msg = "Unknown notification type"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	limit, offset := paginationParams(r)
+
+	list, err := cfg.dbQueries.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:     userID,
+		Type:       typ,
+		UnreadOnly: unreadOnly,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody, err := cfg.notificationResponses(r.Context(), userID, list)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetUnreadNotifications(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	counts, err := cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnUnreadCount{
+		ByType: map[string]int64{},
+	}
+	for _, c := range counts {
+		respBody.Total += c.Count
+		respBody.ByType[c.Type] = c.Count
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		msg = "Invalid notification ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Marking a notification that is already read is not an error.
+	_, err = cfg.dbQueries.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+// handlerMarkAllNotificationsRead marks every unread notification read, or
+// only those of the type given in the query string.
+func (cfg *apiConfig) handlerMarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	typ, ok := notificationTypeParam(r)
+	if !ok {
+		msg = "Unknown notification type"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.MarkAllNotificationsRead(r.Context(), database.MarkAllNotificationsReadParams{
+		UserID: userID,
+		Type:   typ,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"server/internal/database"
+	"server/internal/feed"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pageTemplates holds each page parsed together with the shared layout.
+var pageTemplates = map[string]*template.Template{}
+
+var embedTemplate = template.Must(template.ParseFS(templateFS, "templates/embed.html"))
+
+func init() {
+	for _, name := range []string{"chirp", "profile", "not_found"} {
+		pageTemplates[name] = template.Must(template.ParseFS(templateFS, "templates/base.html", "templates/"+name+".html"))
+	}
+}
+
+const (
+	descriptionLength = 200
+	profileChirps     = 20
+	oEmbedMaxWidth    = 550
+	oEmbedCacheAge    = 3600
+)
+
+// pageContentSecurityPolicy lets pages show images from anywhere, since
+// avatars are external links, and nothing else beyond their own styles.
+const pageContentSecurityPolicy = "default-src 'none'; img-src * data:; style-src 'unsafe-inline'"
+
+type pageLink struct {
+	Type  string
+	Href  string
+	Title string
+}
+
+// pageMeta fills the <head> of every page, including its OpenGraph tags.
+type pageMeta struct {
+	Title       string
+	Description string
+	URL         string
+	Type        string
+	Image       string
+	Alternates  []pageLink
+}
+
+type chirpPage struct {
+	Meta       pageMeta
+	Chirp      returnChirp
+	Author     returnPublicUser
+	AuthorName string
+	AuthorURL  string
+	ReplyToURL string
+	Images     []string
+}
+
+type profilePageChirp struct {
+	Body      string
+	CreatedAt time.Time
+	URL       string
+}
+
+type profilePage struct {
+	Meta           pageMeta
+	User           returnPublicUser
+	Name           string
+	Chirps         []profilePageChirp
+	ChirpCount     int64
+	FollowerCount  int64
+	FollowingCount int64
+}
+
+// excerpt shortens text to at most n runes on a single line.
+func excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+// profilePath is where a user's page lives, by handle when they have one.
+func profilePath(user database.User) string {
+	if user.Handle.Valid {
+		return "/users/" + user.Handle.String
+	}
+	return "/users/" + user.ID.String()
+}
+
+// absoluteURL makes a server-relative link, such as a media URL, absolute.
+func (cfg *apiConfig) absoluteURL(r *http.Request, link string) string {
+	if strings.HasPrefix(link, "/") {
+		return cfg.baseURL(r) + link
+	}
+	return link
+}
+
+func renderPage(w http.ResponseWriter, code int, name string, data any) {
+	buf := bytes.Buffer{}
+	err := pageTemplates[name].Execute(&buf, data)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", pageContentSecurityPolicy)
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
+}
+
+func (cfg *apiConfig) renderNotFound(w http.ResponseWriter, r *http.Request) {
+	renderPage(w, 404, "not_found", struct{ Meta pageMeta }{pageMeta{
+		Title: "Not found · Chirpy",
+		URL:   cfg.baseURL(r) + r.URL.Path,
+		Type:  "website",
+	}})
+}
+
+// handlerChirpPage renders a chirp's permalink page as an anonymous reader
+// sees it, so links shared anywhere unfurl with a preview.
+func (cfg *apiConfig) handlerChirpPage(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		cfg.renderNotFound(w, r)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), uuid.Nil, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.renderNotFound(w, r)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	author, err := cfg.dbQueries.GetUserByID(r.Context(), chirp.UserID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respChirps, err := cfg.chirpResponses(r.Context(), uuid.Nil, []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	base := cfg.baseURL(r)
+	page := chirpPage{
+		Chirp:      respChirps[0],
+		Author:     publicUser(author),
+		AuthorName: displayName(author),
+		AuthorURL:  base + profilePath(author),
+		Meta: pageMeta{
+			Title:       displayName(author) + " on Chirpy: “" + feed.Title(chirp.Body) + "”",
+			Description: excerpt(chirp.Body, descriptionLength),
+			URL:         cfg.chirpURL(r, chirp.ID),
+			Type:        "article",
+			Image:       cfg.absoluteURL(r, author.AvatarUrl),
+		},
+	}
+	if base != "" {
+		page.Meta.Alternates = append(page.Meta.Alternates, pageLink{
+			Type: "application/json+oembed",
+			Href: base + "/api/oembed?url=" + url.QueryEscape(cfg.chirpURL(r, chirp.ID)),
+		})
+	}
+	if chirp.InReplyTo.Valid {
+		page.ReplyToURL = cfg.chirpURL(r, chirp.InReplyTo.UUID)
+	}
+	for _, m := range page.Chirp.Media {
+		page.Images = append(page.Images, cfg.absoluteURL(r, m.URL))
+	}
+	if len(page.Images) > 0 {
+		page.Meta.Image = page.Images[0]
+	}
+	if cfg.federating() && federatable(chirp) {
+		page.Meta.Alternates = append(page.Meta.Alternates, pageLink{
+			Type: "application/activity+json",
+			Href: cfg.noteURI(chirp.ID),
+		})
+	}
+
+	renderPage(w, 200, "chirp", page)
+}
+
+// handlerProfilePage renders a user's page with their latest public
+// chirps and links to their feeds.
+func (cfg *apiConfig) handlerProfilePage(w http.ResponseWriter, r *http.Request) {
+	user, err := cfg.lookupUser(r.Context(), r.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.renderNotFound(w, r)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	page, err := cfg.profilePage(r, user)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	renderPage(w, 200, "profile", page)
+}
+
+func (cfg *apiConfig) profilePage(r *http.Request, user database.User) (profilePage, error) {
+	ctx := r.Context()
+
+	chirps, err := cfg.dbQueries.GetPublicUserChirps(ctx, database.GetPublicUserChirpsParams{
+		UserID:    user.ID,
+		PageLimit: profileChirps,
+	})
+	if err != nil {
+		return profilePage{}, err
+	}
+	counts, err := cfg.profileCounts(ctx, user.ID)
+	if err != nil {
+		return profilePage{}, err
+	}
+
+	base := cfg.baseURL(r)
+	pageURL := base + profilePath(user)
+	description := user.Bio
+	if description == "" {
+		description = "Chirps by " + displayName(user)
+	}
+	page := profilePage{
+		User:           publicUser(user),
+		Name:           displayName(user),
+		ChirpCount:     counts[0],
+		FollowerCount:  counts[1],
+		FollowingCount: counts[2],
+		Meta: pageMeta{
+			Title:       displayName(user) + " · Chirpy",
+			Description: excerpt(description, descriptionLength),
+			URL:         pageURL,
+			Type:        "profile",
+			Image:       cfg.absoluteURL(r, user.AvatarUrl),
+		},
+	}
+	if base != "" {
+		page.Meta.Alternates = append(page.Meta.Alternates,
+			pageLink{Type: "application/rss+xml", Href: pageURL + "/feed.rss", Title: "RSS"},
+			pageLink{Type: "application/atom+xml", Href: pageURL + "/feed.atom", Title: "Atom"},
+			pageLink{Type: "application/feed+json", Href: pageURL + "/feed.json", Title: "JSON Feed"},
+		)
+	}
+	if cfg.federating() && user.Handle.Valid {
+		page.Meta.Alternates = append(page.Meta.Alternates, pageLink{
+			Type: "application/activity+json",
+			Href: cfg.actorURI(user.ID),
+		})
+	}
+
+	for _, chirp := range chirps {
+		page.Chirps = append(page.Chirps, profilePageChirp{
+			Body:      chirp.Body,
+			CreatedAt: chirp.CreatedAt,
+			URL:       cfg.chirpURL(r, chirp.ID),
+		})
+	}
+	return page, nil
+}
+
+// profileCounts returns a user's public chirp, follower and following
+// counts.
+func (cfg *apiConfig) profileCounts(ctx context.Context, userID uuid.UUID) ([3]int64, error) {
+	counts := [3]int64{}
+	count := []func(context.Context, uuid.UUID) (int64, error){
+		cfg.dbQueries.CountUserChirps,
+		cfg.dbQueries.CountFollowers,
+		cfg.dbQueries.CountFollowing,
+	}
+	for i, f := range count {
+		n, err := f(ctx, userID)
+		if err != nil {
+			return counts, err
+		}
+		counts[i] = n
+	}
+	return counts, nil
+}
+
+// handlerOEmbed describes a chirp permalink for embedding on other sites,
+// following the oEmbed spec. Only JSON is supported.
+func (cfg *apiConfig) handlerOEmbed(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "json" {
+		msg = "Only the json format is supported"
+		code = 501
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Embeds need absolute links.
+	if cfg.baseURL(r) == "" {
+		msg = "Not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Only our own chirp permalinks can be embedded.
+	target, err := url.Parse(r.URL.Query().Get("url"))
+	base, _ := url.Parse(cfg.baseURL(r))
+	rest, isChirp := "", false
+	if err == nil {
+		rest, isChirp = strings.CutPrefix(target.Path, "/chirps/")
+	}
+	if err != nil || !strings.EqualFold(target.Host, base.Host) || !isChirp {
+		msg = "Not a chirp URL"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	chirpID, err := uuid.Parse(rest)
+	if err != nil {
+		msg = "Not a chirp URL"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), uuid.Nil, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	author, err := cfg.dbQueries.GetUserByID(r.Context(), chirp.UserID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpURL := cfg.chirpURL(r, chirp.ID)
+	buf := bytes.Buffer{}
+	err = embedTemplate.Execute(&buf, map[string]string{
+		"URL":        chirpURL,
+		"Body":       chirp.Body,
+		"AuthorName": displayName(author),
+		"Handle":     author.Handle.String,
+		"Date":       chirp.CreatedAt.UTC().Format("2 Jan 2006"),
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	width := oEmbedMaxWidth
+	if maxWidth, err := strconv.Atoi(r.URL.Query().Get("maxwidth")); err == nil && maxWidth > 0 {
+		width = min(width, maxWidth)
+	}
+
+	respondWithJSON(w, code, returnOEmbed{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "Chirpy",
+		ProviderURL:  cfg.baseURL(r),
+		AuthorName:   displayName(author),
+		AuthorURL:    cfg.baseURL(r) + profilePath(author),
+		Title:        feed.Title(chirp.Body),
+		HTML:         strings.TrimSpace(buf.String()),
+		Width:        width,
+		CacheAge:     oEmbedCacheAge,
+	})
+}
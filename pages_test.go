@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const testPublicURL = "https://chirpy.example"
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"line one\n\nline   two", 20, "line one line two"},
+		{"one two three four", 9, "one two…"},
+		{"héllo wörld", 6, "héllo…"},
+	}
+	for _, tt := range tests {
+		if got := excerpt(tt.text, tt.n); got != tt.want {
+			t.Errorf("excerpt(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestOEmbedOnlyEmbedsOurChirps(t *testing.T) {
+	cfg := &apiConfig{platform: "prod", publicURL: testPublicURL}
+	chirpURL := "/chirps/" + uuid.NewString()
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"other site", "?url=" + url.QueryEscape("https://elsewhere.example"+chirpURL), 404},
+		{"not a chirp", "?url=" + url.QueryEscape(testPublicURL+"/users/alice"), 404},
+		{"bad chirp ID", "?url=" + url.QueryEscape(testPublicURL+"/chirps/nope"), 404},
+		{"xml", "?format=xml&url=" + url.QueryEscape(testPublicURL+chirpURL), 501},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/oembed"+tt.query, nil)
+		w := httptest.NewRecorder()
+		cfg.handlerOEmbed(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestChirpPageHasPreviewTags(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.publicURL = testPublicURL
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Body: "Tom & <b>Jerry</b>"})
+
+	r := testRequest(t, cfg, "GET", "/chirps/"+chirp.ID.String(), uuid.Nil, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	w := serve(t, cfg.handlerChirpPage, r, 200)
+
+	page := w.Body.String()
+	for _, want := range []string{
+		`<meta property="og:type" content="article">`,
+		`<meta property="og:url" content="` + testPublicURL + `/chirps/` + chirp.ID.String() + `">`,
+		`<meta property="og:description" content="Tom &amp; &lt;b&gt;Jerry&lt;/b&gt;">`,
+		`type="application/json+oembed"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+	if strings.Contains(page, "<b>Jerry") {
+		t.Error("chirp body was not escaped")
+	}
+}
+
+func TestChirpPageIsAnonymous(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Visibility: visibilityFollowers})
+
+	// Pages show what anyone would see, even to the author.
+	r := testRequest(t, cfg, "GET", "/chirps/"+chirp.ID.String(), author.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerChirpPage, r, 404)
+}
+
+func TestOEmbedForChirp(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.publicURL = testPublicURL
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Body: "embed me"})
+
+	query := "?maxwidth=300&url=" + url.QueryEscape(testPublicURL+"/chirps/"+chirp.ID.String())
+	r := testRequest(t, cfg, "GET", "/api/oembed"+query, uuid.Nil, nil)
+	w := serve(t, cfg.handlerOEmbed, r, 200)
+
+	var embed returnOEmbed
+	err := json.NewDecoder(w.Body).Decode(&embed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embed.Version != "1.0" || embed.Type != "rich" || embed.ProviderURL != testPublicURL || embed.Width != 300 {
+		t.Errorf("got %+v", embed)
+	}
+	if !strings.Contains(embed.HTML, "embed me") || !strings.Contains(embed.HTML, chirp.ID.String()) {
+		t.Errorf("embed HTML %q does not show the chirp", embed.HTML)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"server/internal/database"
+	"server/internal/notifications"
+	"server/internal/subscription"
+	"server/internal/webhook"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// polkaSignatureHeader carries the webhook signature, in the format
+	// described by the webhook package, made with POLKA_KEY.
+	polkaSignatureHeader = "Polka-Signature"
+	maxPolkaWebhookBytes = 64 << 10
+)
+
+// What became of a webhook, as recorded in polka_webhooks.
+const (
+	polkaResultProcessed = "processed"
+	polkaResultDuplicate = "duplicate"
+	polkaResultIgnored   = "ignored"
+	polkaResultRejected  = "rejected"
+	polkaResultInvalid   = "invalid"
+	polkaResultFailed    = "failed"
+)
+
+// polkaOutcome is the result of handling one webhook. Message is sent back
+// to Polka, while Err, when set, is only recorded.
+type polkaOutcome struct {
+	Result  string
+	Code    int
+	Message string
+	EventID string
+	Event   string
+	Err     error
+}
+
+// handlerPolkaWebhook receives payment events from Polka. Requests must be
+// signed with POLKA_KEY over the raw body. Each event is applied at most
+// once, however often Polka retries it, and every request is recorded
+// whatever its outcome.
+func (cfg *apiConfig) handlerPolkaWebhook(w http.ResponseWriter, r *http.Request) {
+	receivedAt := time.Now()
+	signature := r.Header.Get(polkaSignatureHeader)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPolkaWebhookBytes))
+	outcome := polkaOutcome{Result: polkaResultInvalid, Code: 413, Message: "Webhook too large", Err: err}
+	if err == nil {
+		outcome = cfg.processPolkaWebhook(r.Context(), signature, body, receivedAt)
+	}
+
+	errText := ""
+	if outcome.Err != nil {
+		errText = outcome.Err.Error()
+	}
+	err = cfg.dbQueries.RecordPolkaWebhook(r.Context(), database.RecordPolkaWebhookParams{
+		ReceivedAt: receivedAt,
+		EventID:    outcome.EventID,
+		Event:      outcome.Event,
+		Signature:  signature,
+		Body:       body,
+		Result:     outcome.Result,
+		StatusCode: int32(outcome.Code),
+		Error:      errText,
+	})
+	if err != nil {
+		fmt.Println("recording polka webhook:", err)
+	}
+
+	if outcome.Code == 204 {
+		w.WriteHeader(outcome.Code)
+		return
+	}
+	respondWithError(w, outcome.Code, outcome.Message)
+}
+
+func (cfg *apiConfig) processPolkaWebhook(ctx context.Context, signature string, body []byte, now time.Time) polkaOutcome {
+	type parameters struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			UserID           string     `json:"user_id"`
+			CurrentPeriodEnd *time.Time `json:"current_period_end"`
+		} `json:"data"`
+	}
+
+	// Without a key anyone could sign a webhook.
+	if cfg.polkaKey == "" {
+		return polkaOutcome{Result: polkaResultRejected, Code: 401, Message: "Invalid signature", Err: errors.New("POLKA_KEY is not set")}
+	}
+	err := webhook.Verify(cfg.polkaKey, signature, body, now, webhook.DefaultTolerance)
+	if err != nil {
+		return polkaOutcome{Result: polkaResultRejected, Code: 401, Message: "Invalid signature", Err: err}
+	}
+
+	params := parameters{}
+	err = json.Unmarshal(body, &params)
+	if err != nil {
+		return polkaOutcome{Result: polkaResultInvalid, Code: 400, Message: "Invalid payload", Err: err}
+	}
+	outcome := polkaOutcome{EventID: params.ID, Event: params.Event}
+	if params.ID == "" {
+		outcome.Result, outcome.Code, outcome.Message = polkaResultInvalid, 400, "Event id is required"
+		return outcome
+	}
+
+	event := subscription.Event(params.Event)
+	if !subscription.FromPolka(event) {
+		outcome.Result, outcome.Code = polkaResultIgnored, 204
+		return outcome
+	}
+
+	userID, err := uuid.Parse(params.Data.UserID)
+	if err != nil {
+		outcome.Result, outcome.Code, outcome.Message, outcome.Err = polkaResultInvalid, 400, "Invalid user id", err
+		return outcome
+	}
+
+	periodEnd := time.Time{}
+	if params.Data.CurrentPeriodEnd != nil {
+		periodEnd = *params.Data.CurrentPeriodEnd
+	}
+
+	// The event id is claimed in the same transaction the event is applied
+	// in, so a failure releases the claim for Polka's retry. A retry that
+	// arrives while the first delivery is still being handled waits on the
+	// claim and is then treated as a duplicate.
+	duplicate := false
+	joined := false
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		claimed, err := q.ClaimPolkaEvent(ctx, database.ClaimPolkaEventParams{
+			EventID: params.ID,
+			Event:   params.Event,
+		})
+		if err != nil {
+			return err
+		}
+		if claimed == 0 {
+			duplicate = true
+			return nil
+		}
+		joined, err = applySubscriptionEvent(ctx, q, userID, event, periodEnd, params.ID)
+		return err
+	})
+	if err != nil {
+		outcome.Result, outcome.Code, outcome.Message, outcome.Err = polkaResultFailed, 500, "Something went wrong", err
+		if errors.Is(err, errSubscriberNotFound) {
+			outcome.Code, outcome.Message = 404, "User not found"
+		}
+		return outcome
+	}
+	if duplicate {
+		outcome.Result, outcome.Code = polkaResultDuplicate, 204
+		return outcome
+	}
+
+	if joined {
+		cfg.notify(ctx, userID, notifications.ChirpyRed, uuid.Nil, uuid.NullUUID{})
+	}
+	outcome.Result, outcome.Code = polkaResultProcessed, 204
+	return outcome
+}
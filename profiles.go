@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"server/internal/database"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+func validHandle(handle string) bool {
+	return handlePattern.MatchString(handle)
+}
+
+func publicUser(user database.User) returnPublicUser {
+	return returnPublicUser{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		Handle:      user.Handle.String,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarURL:   user.AvatarUrl,
+		IsChirpyRed: user.IsChirpyRed,
+		IsPrivate:   user.IsPrivate,
+	}
+}
+
+func privateUser(user database.User) returnUser {
+	return returnUser{
+		returnPublicUser: publicUser(user),
+		Email:            user.Email,
+	}
+}
+
+// lookupUser resolves a path segment naming a user, which may be either
+// their UUID or their handle.
+func (cfg *apiConfig) lookupUser(ctx context.Context, ref string) (database.User, error) {
+	if id, err := uuid.Parse(ref); err == nil {
+		return cfg.dbQueries.GetUserByID(ctx, id)
+	}
+	if !validHandle(ref) {
+		return database.User{}, sql.ErrNoRows
+	}
+	return cfg.dbQueries.GetUserByHandle(ctx, ref)
+}
+
+func (cfg *apiConfig) handlerGetProfile(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	user, err := cfg.lookupUser(r.Context(), r.PathValue("handle"))
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "User not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Users who have blocked the viewer are hidden from them entirely.
+	viewer := cfg.viewerID(r)
+	if viewer != uuid.Nil {
+		blockedBy, err := cfg.dbQueries.IsBlocking(r.Context(), database.IsBlockingParams{
+			BlockerID: user.ID,
+			BlockedID: viewer,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		if blockedBy {
+			msg = "User not found"
+			code = 404
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	chirpCount, err := cfg.dbQueries.CountUserChirps(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	followerCount, err := cfg.dbQueries.CountFollowers(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	followingCount, err := cfg.dbQueries.CountFollowing(r.Context(), user.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnProfile{
+		returnPublicUser: publicUser(user),
+		ChirpCount:       chirpCount,
+		FollowerCount:    followerCount,
+		FollowingCount:   followingCount,
+	}
+
+	if viewer != uuid.Nil && viewer != user.ID {
+		follow, err := cfg.dbQueries.GetFollow(r.Context(), database.GetFollowParams{
+			FollowerID: viewer,
+			FolloweeID: user.ID,
+		})
+		if err == nil {
+			respBody.FollowStatus = follow.Status
+		}
+
+		respBody.Blocking, err = cfg.dbQueries.IsBlocking(r.Context(), database.IsBlockingParams{
+			BlockerID: viewer,
+			BlockedID: user.ID,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+
+		respBody.Muting, err = cfg.dbQueries.IsMuted(r.Context(), database.IsMutedParams{
+			MuterID: viewer,
+			MutedID: user.ID,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+		IsPrivate   *bool   `json:"is_private"`
+	}
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	args := database.UpdateUserProfileParams{
+		ID:          user.ID,
+		Handle:      user.Handle,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		AvatarUrl:   user.AvatarUrl,
+		IsPrivate:   user.IsPrivate,
+	}
+
+	if params.Handle != nil {
+		if !validHandle(*params.Handle) {
+			msg = "Handle must be 3-30 letters, digits or underscores"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		args.Handle = sql.NullString{String: *params.Handle, Valid: true}
+	}
+
+	if params.DisplayName != nil {
+		if utf8.RuneCountInString(*params.DisplayName) > maxDisplayNameLength {
+			msg = "Display name is too long"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		args.DisplayName = *params.DisplayName
+	}
+
+	if params.Bio != nil {
+		if utf8.RuneCountInString(*params.Bio) > maxBioLength {
+			msg = "Bio is too long"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		args.Bio = *params.Bio
+	}
+
+	if params.AvatarURL != nil {
+		if *params.AvatarURL != "" && !validAvatarURL(*params.AvatarURL) {
+			msg = "Avatar must be an http or https URL"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		args.AvatarUrl = *params.AvatarURL
+	}
+
+	if params.IsPrivate != nil {
+		args.IsPrivate = *params.IsPrivate
+	}
+
+	user, err = cfg.dbQueries.UpdateUserProfile(r.Context(), args)
+	if isUniqueViolation(err) {
+		msg = "Handle already taken"
+		code = 409
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Going public lets in everyone who was waiting for approval.
+	if !user.IsPrivate {
+		err = cfg.dbQueries.AcceptAllPendingFollows(r.Context(), user.ID)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	respondWithJSON(w, code, privateUser(user))
+}
+
+func validAvatarURL(raw string) bool {
+	if len(raw) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
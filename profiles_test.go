@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func updateProfile(t *testing.T, cfg *apiConfig, userID uuid.UUID, body map[string]any, want int) {
+	t.Helper()
+	r := testRequest(t, cfg, "PATCH", "/api/users/me", userID, body)
+	serve(t, cfg.handlerUpdateProfile, r, want)
+}
+
+func TestValidHandle(t *testing.T) {
+	for _, handle := range []string{"abc", "Chirpy_Bird", strings.Repeat("a", 30)} {
+		if !validHandle(handle) {
+			t.Errorf("%q rejected", handle)
+		}
+	}
+	for _, handle := range []string{"", "ab", strings.Repeat("a", 31), "has space", "dash-ed", "émile"} {
+		if validHandle(handle) {
+			t.Errorf("%q accepted", handle)
+		}
+	}
+}
+
+func TestProfileByHandleHidesEmail(t *testing.T) {
+	cfg := newTestConfig(t)
+	user := createTestUser(t, cfg)
+	reader := createTestUser(t, cfg)
+	handle := "u" + strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+	updateProfile(t, cfg, user.ID, map[string]any{"handle": handle, "bio": "hello"}, 200)
+	createTestChirp(t, cfg, user.ID, chirpInput{})
+
+	// Handles are looked up ignoring case.
+	ref := strings.ToUpper(handle)
+	r := testRequest(t, cfg, "GET", "/api/users/"+ref, reader.ID, nil)
+	r.SetPathValue("handle", ref)
+	w := serve(t, cfg.handlerGetProfile, r, 200)
+
+	if strings.Contains(w.Body.String(), user.Email) || strings.Contains(w.Body.String(), `"email"`) {
+		t.Fatalf("profile leaks the email: %s", w.Body)
+	}
+	var profile returnProfile
+	err := json.NewDecoder(w.Body).Decode(&profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.ID != user.ID || profile.Handle != handle || profile.Bio != "hello" || profile.ChirpCount != 1 {
+		t.Errorf("got profile %+v", profile)
+	}
+}
+
+func TestHandlesAreUniqueIgnoringCase(t *testing.T) {
+	cfg := newTestConfig(t)
+	first := createTestUser(t, cfg)
+	second := createTestUser(t, cfg)
+	handle := "u" + strings.ReplaceAll(uuid.NewString(), "-", "")[:20]
+
+	updateProfile(t, cfg, first.ID, map[string]any{"handle": handle}, 200)
+	updateProfile(t, cfg, second.ID, map[string]any{"handle": strings.ToUpper(handle)}, 409)
+	updateProfile(t, cfg, second.ID, map[string]any{"handle": "no spaces please"}, 400)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), userID, chirpID)
+	if err != nil || chirp.Status != chirpStatusPublished {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Rechirping would show a followers-only or unlisted chirp to a wider
+	// audience.
+	if chirp.Visibility != visibilityPublic {
+		msg = "Only public chirps can be rechirped"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// A user can only rechirp a chirp once; repeats are ignored.
+	rechirped, err := cfg.dbQueries.Rechirp(r.Context(), database.RechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if rechirped > 0 {
+		cfg.recordRechirpEvent(r.Context(), chirp, userID)
+	}
+
+	w.WriteHeader(code)
+}
+
+func (cfg *apiConfig) handlerUndoRechirp(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	_, err = cfg.dbQueries.UndoRechirp(r.Context(), database.UndoRechirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
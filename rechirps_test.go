@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// rechirpRequest rechirps (POST) or undoes a rechirp (DELETE) of chirpID
+// as userID, failing unless the response is want.
+func rechirpRequest(t *testing.T, cfg *apiConfig, method string, userID, chirpID uuid.UUID, want int) {
+	t.Helper()
+	r := testRequest(t, cfg, method, "/api/chirps/"+chirpID.String()+"/rechirp", userID, nil)
+	r.SetPathValue("chirpID", chirpID.String())
+	handler := cfg.handlerRechirp
+	if method == http.MethodDelete {
+		handler = cfg.handlerUndoRechirp
+	}
+	serve(t, handler, r, want)
+}
+
+func TestRechirpsAreCountedOncePerUser(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	fan := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+
+	rechirpRequest(t, cfg, "POST", fan.ID, chirp.ID, 204)
+	rechirpRequest(t, cfg, "POST", fan.ID, chirp.ID, 204)
+
+	got := getChirp(t, cfg, fan.ID, chirp.ID)
+	if got.RechirpCount != 1 || got.RechirpedByMe == nil || !*got.RechirpedByMe {
+		t.Errorf("got %d rechirps, rechirped_by_me %v; want 1 and true", got.RechirpCount, got.RechirpedByMe)
+	}
+
+	rechirpRequest(t, cfg, "DELETE", fan.ID, chirp.ID, 204)
+	got = getChirp(t, cfg, fan.ID, chirp.ID)
+	if got.RechirpCount != 0 || got.RechirpedByMe == nil || *got.RechirpedByMe {
+		t.Errorf("after undoing, got %d rechirps, rechirped_by_me %v; want 0 and false", got.RechirpCount, got.RechirpedByMe)
+	}
+}
+
+func TestOnlyPublicChirpsCanBeRechirped(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{Visibility: visibilityUnlisted})
+
+	rechirpRequest(t, cfg, "POST", author.ID, chirp.ID, 400)
+}
+
+func TestQuoteChirpEmbedsOriginalUntilDeleted(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	quoter := createTestUser(t, cfg)
+	original := createTestChirp(t, cfg, author.ID, chirpInput{})
+	quote := createTestChirp(t, cfg, quoter.ID, chirpInput{Body: "look at this", QuoteOf: &original.ID})
+
+	got := getChirp(t, cfg, quoter.ID, quote.ID)
+	if got.QuoteOf == nil || *got.QuoteOf != original.ID || got.QuotedChirp == nil || got.QuotedChirp.ID != original.ID {
+		t.Fatalf("got quote_of %v, quoted chirp %+v; want %v embedded", got.QuoteOf, got.QuotedChirp, original.ID)
+	}
+
+	deleteChirp(t, cfg, author.ID, original)
+
+	got = getChirp(t, cfg, quoter.ID, quote.ID)
+	if got.QuoteOf == nil || *got.QuoteOf != original.ID || got.QuotedChirp != nil || !got.QuoteUnavailable {
+		t.Errorf("after deleting the original, got quote_of %v, quoted chirp %+v, unavailable %v",
+			got.QuoteOf, got.QuotedChirp, got.QuoteUnavailable)
+	}
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"server/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	reportStatusOpen     = "open"
+	reportStatusResolved = "resolved"
+
+	// reportReasonAutomated marks reports filed by the moderation pipeline
+	// for chirps it held for review.
+	reportReasonAutomated = "automated"
+
+	defaultSuspension = 7 * 24 * time.Hour
+)
+
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual_content": true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+const (
+	actionHideChirp     = "hide_chirp"
+	actionUnhideChirp   = "unhide_chirp"
+	actionDeleteChirp   = "delete_chirp"
+	actionApproveChirp  = "approve_chirp"
+	actionWarnAuthor    = "warn_author"
+	actionSuspendAuthor = "suspend_author"
+	actionResolve       = "resolve"
+)
+
+func isSuspended(user database.User) bool {
+	return user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(time.Now())
+}
+
+// isModerator reports whether viewer is a moderator; anonymous viewers
+// never are.
+func (cfg *apiConfig) isModerator(ctx context.Context, viewer uuid.UUID) (bool, error) {
+	if viewer == uuid.Nil {
+		return false, nil
+	}
+	user, err := cfg.dbQueries.GetUserByID(ctx, viewer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.IsModerator, nil
+}
+
+// fileAutomatedReport puts a chirp the moderation pipeline held into the
+// review queue. The chirp is already saved, so failures are only logged.
+func (cfg *apiConfig) fileAutomatedReport(ctx context.Context, chirp database.Chirp) {
+	_, err := cfg.dbQueries.CreateReport(ctx, database.CreateReportParams{
+		ChirpID: chirp.ID,
+		Reason:  reportReasonAutomated,
+		Details: strings.Join(chirp.ModerationReasons, ", "),
+	})
+	if err != nil {
+		fmt.Println("filing automated report:", err)
+	}
+}
+
+func reportResponse(report database.Report) returnReport {
+	respReport := returnReport{
+		ID:         report.ID,
+		CreatedAt:  report.CreatedAt,
+		UpdatedAt:  report.UpdatedAt,
+		ChirpID:    report.ChirpID,
+		Reason:     report.Reason,
+		Details:    report.Details,
+		Status:     report.Status,
+		Resolution: report.Resolution,
+	}
+	if report.ReporterID.Valid {
+		respReport.ReporterID = &report.ReporterID.UUID
+	}
+	if report.ResolvedBy.Valid {
+		respReport.ResolvedBy = &report.ResolvedBy.UUID
+	}
+	if report.ResolvedAt.Valid {
+		respReport.ResolvedAt = &report.ResolvedAt.Time
+	}
+	return respReport
+}
+
+func moderationActionResponse(action database.ModerationAction) returnModerationAction {
+	respAction := returnModerationAction{
+		ID:           action.ID,
+		CreatedAt:    action.CreatedAt,
+		TargetUserID: action.TargetUserID,
+		Action:       action.Action,
+		Note:         action.Note,
+		ChirpBody:    action.ChirpBody,
+	}
+	if action.ModeratorID.Valid {
+		respAction.ModeratorID = &action.ModeratorID.UUID
+	}
+	if action.ReportID.Valid {
+		respAction.ReportID = &action.ReportID.UUID
+	}
+	if action.ChirpID.Valid {
+		respAction.ChirpID = &action.ChirpID.UUID
+	}
+	return respAction
+}
+
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if !reportReasons[params.Reason] {
+		msg = "Unknown report reason"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if len(params.Details) > 1000 {
+		msg = "Details are too long"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.getVisibleChirp(r.Context(), userID, chirpID)
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	if chirp.UserID == userID {
+		msg = "You cannot report your own chirp"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	report, err := cfg.dbQueries.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if isUniqueViolation(err) {
+		msg = "You have already reported this chirp"
+		code = 409
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, reportResponse(report))
+}
+
+// requireModerator authenticates the request and checks the user is a
+// moderator, writing the error response itself when they are not.
+func (cfg *apiConfig) requireModerator(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		respondWithError(w, 401, "Unauthorized")
+		return uuid.Nil, false
+	}
+
+	ok, err := cfg.isModerator(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return uuid.Nil, false
+	}
+	if !ok {
+		respondWithError(w, 403, "Moderators only")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (cfg *apiConfig) handlerListReports(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	_, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = reportStatusOpen
+	}
+	if status != reportStatusOpen && status != reportStatusResolved {
+		msg = "Status must be open or resolved"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	reports, err := cfg.dbQueries.ListReports(r.Context(), database.ListReportsParams{
+		Status:     status,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpIDs := []uuid.UUID{}
+	for _, report := range reports {
+		chirpIDs = append(chirpIDs, report.ChirpID)
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsByIDs(r.Context(), chirpIDs)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpsByID := map[uuid.UUID]returnChirp{}
+	for _, chirp := range chirps {
+		chirpsByID[chirp.ID] = chirpResponse(chirp)
+	}
+
+	respBody := []returnReport{}
+	for _, report := range reports {
+		respReport := reportResponse(report)
+		if chirp, ok := chirpsByID[report.ChirpID]; ok {
+			respReport.Chirp = &chirp
+		}
+		respBody = append(respBody, respReport)
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetReport(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	_, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := cfg.getReport(r)
+	if err != nil {
+		msg = "Report not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	actions, err := cfg.dbQueries.GetReportActions(r.Context(), uuid.NullUUID{UUID: report.ID, Valid: true})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnReportDetail{
+		returnReport: reportResponse(report),
+		Actions:      []returnModerationAction{},
+	}
+	for _, action := range actions {
+		respBody.Actions = append(respBody.Actions, moderationActionResponse(action))
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), report.ChirpID)
+	if err == nil {
+		respChirp := chirpResponse(chirp)
+		respBody.Chirp = &respChirp
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) getReport(r *http.Request) (database.Report, error) {
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		return database.Report{}, err
+	}
+	return cfg.dbQueries.GetReport(r.Context(), reportID)
+}
+
+func (cfg *apiConfig) handlerTakeModerationAction(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action        string `json:"action"`
+		Note          string `json:"note"`
+		DurationHours int    `json:"duration_hours"`
+	}
+	msg := ""
+	code := 201
+
+	moderatorID, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	report, err := cfg.getReport(r)
+	if err != nil {
+		msg = "Report not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), report.ChirpID)
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// The action and its record are written together; events, federation
+	// and media cleanup only happen once both are committed.
+	var action database.ModerationAction
+	var after func()
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		after, err = cfg.applyModerationAction(r.Context(), q, chirp, params.Action, &params.Note, params.DurationHours)
+		if err != nil {
+			return err
+		}
+		action, err = cfg.recordModerationAction(r.Context(), q, moderatorID, report, chirp, params.Action, params.Note)
+		return err
+	})
+	if errors.Is(err, errUnknownModerationAction) {
+		msg = "Unknown action"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	after()
+
+	respondWithJSON(w, code, moderationActionResponse(action))
+}
+
+var errUnknownModerationAction = errors.New("unknown moderation action")
+
+// announced reports whether chirp is published and showing, and so has
+// gone out to streams and federated servers. Hiding or removing such a
+// chirp is announced too; held or scheduled chirps never went out and are
+// left alone.
+func announced(chirp database.Chirp) bool {
+	return chirp.Status == chirpStatusPublished && !chirp.HiddenAt.Valid && !chirp.DeletedAt.Valid && !chirp.TombstonedAt.Valid
+}
+
+// applyModerationAction carries out action on chirp or its author with q
+// and returns what must happen once the transaction commits. The note of a
+// suspension is extended with its duration.
+func (cfg *apiConfig) applyModerationAction(ctx context.Context, q *database.Queries, chirp database.Chirp, action string, note *string, durationHours int) (func(), error) {
+	switch action {
+	case actionHideChirp:
+		err := q.HideChirp(ctx, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if announced(chirp) {
+				cfg.recordChirpEvent(ctx, chirpEventDeleted, chirp)
+				cfg.federateChirp(chirpEventDeleted, chirp)
+			}
+		}, nil
+	case actionUnhideChirp:
+		err := q.UnhideChirp(ctx, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		unhidden := chirp
+		unhidden.HiddenAt = sql.NullTime{}
+		return func() {
+			if chirp.HiddenAt.Valid && announced(unhidden) {
+				cfg.recordChirpEvent(ctx, chirpEventCreated, unhidden)
+				cfg.federateChirp(chirpEventCreated, unhidden)
+			}
+		}, nil
+	case actionApproveChirp:
+		err := q.ApproveChirp(ctx, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		approved, err := q.GetChirp(ctx, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if chirp.Status == chirpStatusHeld && approved.Status == chirpStatusPublished {
+				cfg.afterChirpReleased(ctx, approved)
+			}
+		}, nil
+	case actionDeleteChirp:
+		// Moderators always leave a tombstone so the report and the
+		// conversation around the chirp keep pointing at something.
+		files, err := q.DeleteChirpMedia(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		err = q.TombstoneChirp(ctx, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			cfg.deleteMediaBlobs(ctx, files)
+			if announced(chirp) {
+				cfg.recordChirpEvent(ctx, chirpEventDeleted, chirp)
+				cfg.federateChirp(chirpEventDeleted, chirp)
+			}
+		}, nil
+	case actionWarnAuthor:
+		// A warning has no effect beyond being recorded.
+		return func() {}, nil
+	case actionSuspendAuthor:
+		duration := defaultSuspension
+		if durationHours > 0 {
+			duration = time.Duration(durationHours) * time.Hour
+		}
+		err := q.SuspendUser(ctx, database.SuspendUserParams{
+			ID:             chirp.UserID,
+			SuspendedUntil: sql.NullTime{Time: time.Now().Add(duration), Valid: true},
+		})
+		if err != nil {
+			return nil, err
+		}
+		*note = strings.TrimSpace(fmt.Sprintf("%s (suspended for %s)", *note, duration))
+		return func() {}, nil
+	default:
+		return nil, errUnknownModerationAction
+	}
+}
+
+func (cfg *apiConfig) recordModerationAction(ctx context.Context, q *database.Queries, moderatorID uuid.UUID, report database.Report, chirp database.Chirp, action, note string) (database.ModerationAction, error) {
+	return q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+		ReportID:     uuid.NullUUID{UUID: report.ID, Valid: true},
+		ChirpID:      uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		TargetUserID: chirp.UserID,
+		Action:       action,
+		Note:         note,
+		ChirpBody:    chirp.Body,
+	})
+}
+
+func (cfg *apiConfig) handlerResolveReport(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Resolution string `json:"resolution"`
+	}
+	msg := ""
+	code := 200
+
+	moderatorID, ok := cfg.requireModerator(w, r)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	report, err := cfg.getReport(r)
+	if err != nil {
+		msg = "Report not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), report.ChirpID)
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// ResolveReport only matches open reports, so of two moderators
+	// resolving the same report at once only the first succeeds.
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		report, err = q.ResolveReport(r.Context(), database.ResolveReportParams{
+			ID:         report.ID,
+			ResolvedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+			Resolution: params.Resolution,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = cfg.recordModerationAction(r.Context(), q, moderatorID, report, chirp, actionResolve, params.Resolution)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		msg = "Report is already resolved"
+		code = 409
+		respondWithError(w, code, msg)
+		return
+	}
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, reportResponse(report))
+}
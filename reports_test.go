@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"server/internal/database"
+	"server/internal/stream"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func createTestModerator(t *testing.T, cfg *apiConfig) database.User {
+	t.Helper()
+	user := createTestUser(t, cfg)
+	_, err := cfg.db.Exec("UPDATE users SET is_moderator = true WHERE id = $1", user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// createTestReport has a new user report chirpID and returns the report.
+func createTestReport(t *testing.T, cfg *apiConfig, chirpID uuid.UUID) database.Report {
+	t.Helper()
+	reporter := createTestUser(t, cfg)
+	r := testRequest(t, cfg, "POST", "/api/chirps/"+chirpID.String()+"/reports", reporter.ID, map[string]string{"reason": "spam"})
+	r.SetPathValue("chirpID", chirpID.String())
+	serve(t, cfg.handlerReportChirp, r, 201)
+
+	reports, err := cfg.dbQueries.ListReports(context.Background(), database.ListReportsParams{
+		Status:    reportStatusOpen,
+		PageLimit: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		if report.ChirpID == chirpID && report.ReporterID.UUID == reporter.ID {
+			return report
+		}
+	}
+	t.Fatal("report not found")
+	return database.Report{}
+}
+
+func TestResolveReportOnlyOnce(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	report := createTestReport(t, cfg, chirp.ID)
+
+	moderators := []database.User{createTestModerator(t, cfg), createTestModerator(t, cfg)}
+	codes := make([]int, len(moderators))
+
+	var wg sync.WaitGroup
+	for i, moderator := range moderators {
+		r := testRequest(t, cfg, "POST", "/api/moderation/reports/"+report.ID.String()+"/resolve", moderator.ID, map[string]string{"resolution": "handled"})
+		r.SetPathValue("reportID", report.ID.String())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			cfg.handlerResolveReport(w, r)
+			codes[i] = w.Code
+		}()
+	}
+	wg.Wait()
+
+	if !(codes[0] == 200 && codes[1] == 409) && !(codes[0] == 409 && codes[1] == 200) {
+		t.Fatalf("got statuses %v, want one 200 and one 409", codes)
+	}
+}
+
+// takeAction has a new moderator take action on report.
+func takeAction(t *testing.T, cfg *apiConfig, report database.Report, action string) {
+	t.Helper()
+	moderator := createTestModerator(t, cfg)
+	r := testRequest(t, cfg, "POST", "/api/moderation/reports/"+report.ID.String()+"/actions", moderator.ID, map[string]string{"action": action})
+	r.SetPathValue("reportID", report.ID.String())
+	serve(t, cfg.handlerTakeModerationAction, r, 201)
+}
+
+func TestHideAndUnhideAreAnnounced(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	report := createTestReport(t, cfg, chirp.ID)
+
+	sub := cfg.hub.Subscribe(func(ev stream.Event) bool { return ev.ChirpID == chirp.ID })
+	defer sub.Close()
+
+	takeAction(t, cfg, report, actionHideChirp)
+	takeAction(t, cfg, report, actionUnhideChirp)
+
+	for _, want := range []string{chirpEventDeleted, chirpEventCreated} {
+		select {
+		case ev := <-sub.C:
+			if ev.Type != want {
+				t.Fatalf("got %s event, want %s", ev.Type, want)
+			}
+		default:
+			t.Fatalf("no %s event", want)
+		}
+	}
+}
+
+func TestHidingHeldChirpIsNotAnnounced(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp, err := cfg.dbQueries.CreateChirp(context.Background(), database.CreateChirpParams{
+		Body:              "held chirp",
+		UserID:            author.ID,
+		Status:            chirpStatusHeld,
+		ModerationReasons: []string{},
+		Visibility:        visibilityPublic,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := cfg.dbQueries.CreateReport(context.Background(), database.CreateReportParams{
+		ChirpID: chirp.ID,
+		Reason:  "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := cfg.hub.Subscribe(func(ev stream.Event) bool { return ev.ChirpID == chirp.ID })
+	defer sub.Close()
+
+	takeAction(t, cfg, report, actionHideChirp)
+	takeAction(t, cfg, report, actionUnhideChirp)
+
+	select {
+	case ev := <-sub.C:
+		t.Fatalf("got %s event for a held chirp", ev.Type)
+	default:
+	}
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"server/internal/auth"
-	"strings"
+	"server/internal/database"
+	"strconv"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -43,14 +49,120 @@ func respondWithError(w http.ResponseWriter, code int, msg string) {
 	w.Write(data)
 }
 
-func badWordReplacement(body string) string {
-	bodyWords := strings.Split(body, " ")
-	for i, word := range bodyWords {
-		if strings.ToLower(word) == "kerfuffle" || strings.ToLower(word) == "sharbert" || strings.ToLower(word) == "fornax" {
-			bodyWords[i] = "****"
-		}
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// requestError is a failure to report to the client as-is, for helpers
+// shared between handlers that need to choose the response status.
+type requestError struct {
+	code int
+	msg  string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+// respondWithRequestError writes err as a response, using its status and
+// message if it is a requestError and a 500 otherwise.
+func respondWithRequestError(w http.ResponseWriter, err error) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		respondWithError(w, reqErr.code, reqErr.msg)
+		return
+	}
+	respondWithError(w, 500, "Something went wrong")
+}
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// paginationParams reads the limit and offset query parameters, falling
+// back to sensible defaults when they are missing or out of range.
+func paginationParams(r *http.Request) (int32, int32) {
+	limit := int32(defaultPageLimit)
+	offset := int32(0)
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = int32(min(l, maxPageLimit))
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o > 0 {
+		offset = int32(o)
+	}
+	return limit, offset
+}
+
+// isUniqueViolation reports whether err came from Postgres rejecting a
+// duplicate value for a unique column or index.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// withTx runs fn with queries bound to a transaction, committing if fn
+// returns nil and rolling back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(cfg.dbQueries.WithTx(tx))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// authenticatedUserID returns the user behind the request's bearer JWT.
+func (cfg *apiConfig) authenticatedUserID(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return auth.ValidateJWT(token, cfg.secret)
+}
+
+// viewerID is authenticatedUserID for endpoints that anonymous readers may
+// also use; it returns uuid.Nil when the request carries no valid token.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	id, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// baseURL is the server's public address, for absolute links such as those
+// in feeds. PUBLIC_URL sets it. The request's Host header is up to the
+// client, so it is only trusted on the dev platform; elsewhere baseURL is
+// empty without PUBLIC_URL, leaving links relative to the site.
+func (cfg *apiConfig) baseURL(r *http.Request) string {
+	if cfg.publicURL != "" || cfg.platform != "dev" {
+		return cfg.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
 	}
-	return strings.Join(bodyWords, " ")
+	return scheme + "://" + r.Host
 }
 
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
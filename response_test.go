@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBaseURLIgnoresHostHeader(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users/alice", nil)
+	r.Host = "attacker.example"
+
+	cfg := &apiConfig{platform: "prod", publicURL: "https://chirpy.example"}
+	if got := cfg.baseURL(r); got != "https://chirpy.example" {
+		t.Errorf("with PUBLIC_URL: got %q", got)
+	}
+
+	cfg.publicURL = ""
+	if got := cfg.baseURL(r); got != "" {
+		t.Errorf("without PUBLIC_URL: got %q, want relative links", got)
+	}
+
+	cfg.platform = "dev"
+	if got := cfg.baseURL(r); got != "http://attacker.example" {
+		t.Errorf("on dev: got %q, want the request's host", got)
+	}
+}
@@ -1,25 +1,310 @@
 package main
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type returnChirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Body           string     `json:"body"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Status         string     `json:"status"`
+	Visibility     string     `json:"visibility"`
+	Hidden         bool       `json:"hidden,omitempty"`
+	InReplyTo      *uuid.UUID `json:"in_reply_to,omitempty"`
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	Tombstone      bool       `json:"tombstone,omitempty"`
+	LikeCount      int32      `json:"like_count"`
+	LikedByMe      *bool      `json:"liked_by_me,omitempty"`
+	RechirpCount   int32      `json:"rechirp_count"`
+	RechirpedByMe  *bool      `json:"rechirped_by_me,omitempty"`
+	// QuoteOf is kept even after the quoted chirp is deleted, in which
+	// case QuotedChirp is nil and QuoteUnavailable is set.
+	QuoteOf          *uuid.UUID    `json:"quote_of,omitempty"`
+	QuotedChirp      *returnChirp  `json:"quoted_chirp,omitempty"`
+	QuoteUnavailable bool          `json:"quote_unavailable,omitempty"`
+	Edited           bool          `json:"edited"`
+	EditedAt         *time.Time    `json:"edited_at,omitempty"`
+	Media            []returnMedia `json:"media,omitempty"`
+	PublishAt        *time.Time    `json:"publish_at,omitempty"`
 }
 
-type returnUser struct {
+type returnDraft struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	QuoteOf   *uuid.UUID `json:"quote_of,omitempty"`
+}
+
+type returnMedia struct {
 	ID           uuid.UUID `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+}
+
+// returnTimelineEntry is a chirp in a timeline, either posted by someone
+// the viewer follows or rechirped by them.
+type returnTimelineEntry struct {
+	Chirp       returnChirp `json:"chirp"`
+	RechirpedBy *uuid.UUID  `json:"rechirped_by,omitempty"`
+	RechirpedAt *time.Time  `json:"rechirped_at,omitempty"`
+}
+
+type returnTimeline struct {
+	Entries    []returnTimelineEntry `json:"entries"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+type returnChirpRevision struct {
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type returnChirpHistory struct {
+	Chirp     returnChirp           `json:"chirp"`
+	Revisions []returnChirpRevision `json:"revisions"`
+}
+
+type returnThreadNode struct {
+	returnChirp
+	Depth   int32               `json:"depth"`
+	Replies []*returnThreadNode `json:"replies"`
+}
+
+type returnThread struct {
+	Ancestors  []returnChirp       `json:"ancestors"`
+	Chirp      returnChirp         `json:"chirp"`
+	Replies    []*returnThreadNode `json:"replies"`
+	NextOffset *int32              `json:"next_offset,omitempty"`
+}
+
+// returnPublicUser is everything about a user that anyone may see.
+type returnPublicUser struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	IsPrivate   bool      `json:"is_private"`
+}
+
+// returnUser is only ever sent to the user it describes.
+type returnUser struct {
+	returnPublicUser
+	Email        string `json:"email"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type returnProfile struct {
+	returnPublicUser
+	ChirpCount     int64 `json:"chirp_count"`
+	FollowerCount  int64 `json:"follower_count"`
+	FollowingCount int64 `json:"following_count"`
+	// FollowStatus is the viewer's follow of this user: "accepted",
+	// "pending" or empty when not following or anonymous.
+	FollowStatus string `json:"follow_status,omitempty"`
+	// Blocking and Muting say whether the viewer has blocked or muted
+	// this user.
+	Blocking bool `json:"blocking,omitempty"`
+	Muting   bool `json:"muting,omitempty"`
+}
+
+type returnNotification struct {
+	ID         uuid.UUID          `json:"id"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	Type       string             `json:"type"`
+	Summary    string             `json:"summary"`
+	Read       bool               `json:"read"`
+	ReadAt     *time.Time         `json:"read_at,omitempty"`
+	Actors     []returnPublicUser `json:"actors"`
+	ActorCount int32              `json:"actor_count"`
+	ChirpID    *uuid.UUID         `json:"chirp_id,omitempty"`
+	Chirp      *returnChirp       `json:"chirp,omitempty"`
+}
+
+type returnUnreadCount struct {
+	Total  int64            `json:"total"`
+	ByType map[string]int64 `json:"by_type"`
+}
+
+type returnConversation struct {
+	ID           uuid.UUID          `json:"id"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+	IsGroup      bool               `json:"is_group"`
+	Participants []returnPublicUser `json:"participants"`
+	UnreadCount  int64              `json:"unread_count"`
+	LastReadAt   *time.Time         `json:"last_read_at,omitempty"`
+}
+
+type returnMessage struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+}
+
+type returnMessages struct {
+	Messages   []returnMessage `json:"messages"`
+	NextCursor *uuid.UUID      `json:"next_cursor,omitempty"`
+}
+
+type returnMute struct {
+	User      returnPublicUser `json:"user"`
+	ExpiresAt *time.Time       `json:"expires_at,omitempty"`
+}
+
+type returnFollow struct {
+	FollowerID uuid.UUID  `json:"follower_id"`
+	FolloweeID uuid.UUID  `json:"followee_id"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type returnReport struct {
+	ID         uuid.UUID    `json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	ChirpID    uuid.UUID    `json:"chirp_id"`
+	ReporterID *uuid.UUID   `json:"reporter_id"`
+	Reason     string       `json:"reason"`
+	Details    string       `json:"details"`
+	Status     string       `json:"status"`
+	Resolution string       `json:"resolution,omitempty"`
+	ResolvedBy *uuid.UUID   `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time   `json:"resolved_at,omitempty"`
+	Chirp      *returnChirp `json:"chirp,omitempty"`
+}
+
+type returnModerationAction struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ModeratorID  *uuid.UUID `json:"moderator_id"`
+	ReportID     *uuid.UUID `json:"report_id,omitempty"`
+	ChirpID      *uuid.UUID `json:"chirp_id,omitempty"`
+	TargetUserID uuid.UUID  `json:"target_user_id"`
+	Action       string     `json:"action"`
+	Note         string     `json:"note"`
+	ChirpBody    string     `json:"chirp_body"`
+}
+
+type returnReportDetail struct {
+	returnReport
+	Actions []returnModerationAction `json:"actions"`
+}
+
+// returnEntitlements leaves DailyChirps and ChirpsRemaining out for tiers
+// without a daily quota.
+type returnEntitlements struct {
+	Tier            string `json:"tier"`
+	MaxChirpLength  int    `json:"max_chirp_length"`
+	DailyChirps     *int   `json:"daily_chirps,omitempty"`
+	ChirpsToday     int64  `json:"chirps_today"`
+	ChirpsRemaining *int64 `json:"chirps_remaining,omitempty"`
+	MaxMedia        int    `json:"max_media"`
+	EditWindow      string `json:"edit_window"`
+}
+
+// returnOEmbed is an oEmbed rich response. Height is always null, since
+// the embed grows to fit the chirp.
+type returnOEmbed struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	Title        string `json:"title"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       *int   `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+// returnSubscription has a Status of "none" for users who have never
+// subscribed. CurrentPeriodEnd is left out when there is none, including
+// for members whose membership does not run out. History lists the newest
+// event first.
+type returnSubscription struct {
+	Status           string                    `json:"status"`
+	IsChirpyRed      bool                      `json:"is_chirpy_red"`
+	CurrentPeriodEnd *time.Time                `json:"current_period_end,omitempty"`
+	History          []returnSubscriptionEvent `json:"history"`
+}
+
+type returnSubscriptionEvent struct {
+	Event            string     `json:"event"`
+	Status           string     `json:"status"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// returnWebhookEndpoint includes Secret only in the response that creates
+// the endpoint.
+type returnWebhookEndpoint struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	URL        string     `json:"url"`
+	Events     []string   `json:"events"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	Secret     string     `json:"secret,omitempty"`
+}
+
+// returnWebhookEvent is the body of every outgoing webhook delivery.
+// Redeliveries repeat the same ID.
+type returnWebhookEvent struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      returnChirp `json:"data"`
+}
+
+type returnWebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	EventID        uuid.UUID  `json:"event_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	AttemptCount   int32      `json:"attempt_count"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	LastStatusCode int32      `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	RedeliveryOf   *uuid.UUID `json:"redelivery_of,omitempty"`
+}
+
+type returnWebhookDeliveryDetail struct {
+	returnWebhookDelivery
+	Payload  json.RawMessage        `json:"payload"`
+	Attempts []returnWebhookAttempt `json:"attempts"`
+}
+
+// returnWebhookAttempt has a StatusCode of 0 when no response arrived, in
+// which case Error says why.
+type returnWebhookAttempt struct {
+	Attempt      int32     `json:"attempt"`
 	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	StatusCode   int32     `json:"status_code"`
+	ResponseBody string    `json:"response_body"`
+	Error        string    `json:"error,omitempty"`
+	DurationMs   int32     `json:"duration_ms"`
 }
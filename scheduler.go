@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"server/internal/database"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSchedulerInterval = 15 * time.Second
+	publishBatchSize         = 100
+	quotaRetryDelay          = time.Hour
+
+	defaultRestoreWindow = 30 * 24 * time.Hour
+	purgeInterval        = time.Hour
+	purgeBatchSize       = 100
+)
+
+// runScheduler publishes scheduled chirps as they fall due. Every server
+// instance runs one; ClaimDueChirps locks rows with FOR UPDATE SKIP LOCKED
+// for the rest of the transaction, so each chirp is published by exactly
+// one instance.
+func (cfg *apiConfig) runScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cfg.publishDueChirps(context.Background())
+	}
+}
+
+// publishDueChirps publishes every chirp that has fallen due. A chirp whose
+// author has used up their daily quota is put off by quotaRetryDelay.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) {
+	for {
+		claimed := 0
+		published := []database.Chirp{}
+		webhooksQueued := false
+		err := cfg.withTx(ctx, func(q *database.Queries) error {
+			chirps, err := q.ClaimDueChirps(ctx, publishBatchSize)
+			if err != nil {
+				return err
+			}
+			claimed = len(chirps)
+
+			for _, chirp := range chirps {
+				ok, err := cfg.canGoLive(ctx, q, chirp.UserID)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					err = q.PostponeScheduledChirp(ctx, database.PostponeScheduledChirpParams{
+						PublishAt: sql.NullTime{Time: time.Now().Add(quotaRetryDelay).UTC(), Valid: true},
+						ID:        chirp.ID,
+					})
+					if err != nil {
+						return err
+					}
+					continue
+				}
+
+				chirp, err = q.PublishScheduledChirp(ctx, chirp.ID)
+				if err != nil {
+					return err
+				}
+				queued, err := cfg.queueWebhooks(ctx, q, chirpEventCreated, chirp)
+				if err != nil {
+					return err
+				}
+				webhooksQueued = webhooksQueued || queued
+				published = append(published, chirp)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println("publishing scheduled chirps:", err)
+			return
+		}
+
+		if webhooksQueued {
+			cfg.wakeWebhookWorker()
+		}
+		for _, chirp := range published {
+			cfg.afterChirpPublished(ctx, chirp)
+		}
+		if claimed < publishBatchSize {
+			return
+		}
+	}
+}
+
+// runPurge permanently removes soft-deleted chirps once they can no longer
+// be restored, and clears out unattached uploads, expired mutes and old
+// stream events.
+func (cfg *apiConfig) runPurge() {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cfg.purgeDeletedChirps(context.Background())
+		cfg.purgeUnattachedMedia(context.Background())
+
+		_, err := cfg.dbQueries.DeleteExpiredMutes(context.Background())
+		if err != nil {
+			fmt.Println("deleting expired mutes:", err)
+		}
+
+		_, err = cfg.dbQueries.DeleteChirpEventsBefore(context.Background(), time.Now().Add(-chirpEventRetention))
+		if err != nil {
+			fmt.Println("deleting old chirp events:", err)
+		}
+	}
+}
+
+// purgeDeletedChirps hard-deletes expired chirps, except that chirps with
+// replies become tombstones so the conversation below them keeps its
+// shape. Running it on several instances at once is harmless.
+func (cfg *apiConfig) purgeDeletedChirps(ctx context.Context) {
+	for {
+		chirps, err := cfg.dbQueries.GetExpiredDeletedChirps(ctx, database.GetExpiredDeletedChirpsParams{
+			DeletedBefore: sql.NullTime{Time: time.Now().Add(-cfg.restoreWindow), Valid: true},
+			BatchSize:     purgeBatchSize,
+		})
+		if err != nil {
+			fmt.Println("purging deleted chirps:", err)
+			return
+		}
+
+		for _, chirp := range chirps {
+			err = cfg.purgeChirp(ctx, chirp)
+			if err != nil {
+				fmt.Println("purging deleted chirps:", err)
+				return
+			}
+		}
+
+		if len(chirps) < purgeBatchSize {
+			return
+		}
+	}
+}
+
+func (cfg *apiConfig) purgeChirp(ctx context.Context, chirp database.Chirp) error {
+	err := cfg.removeChirpMedia(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	replies, err := cfg.dbQueries.CountChirpReplies(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if replies > 0 {
+		return cfg.dbQueries.TombstoneChirp(ctx, chirp.ID)
+	}
+	return cfg.dbQueries.DeleteChirp(ctx, chirp.ID)
+}
+
+func (cfg *apiConfig) handlerGetScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.GetUserScheduledChirps(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody, err := cfg.chirpResponses(r.Context(), userID, chirps)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, respBody)
+}
@@ -0,0 +1,41 @@
+-- name: GetActorKey :one
+SELECT * FROM actor_keys WHERE user_id = $1;
+
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys (user_id, created_at, public_key_pem, private_key_pem)
+VALUES ($1, NOW(), $2, $3)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: UpsertRemoteActor :one
+INSERT INTO remote_actors (id, created_at, updated_at, uri, key_id, inbox, shared_inbox, preferred_username, public_key_pem)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
+ON CONFLICT (uri) DO UPDATE SET
+    updated_at = NOW(),
+    key_id = EXCLUDED.key_id,
+    inbox = EXCLUDED.inbox,
+    shared_inbox = EXCLUDED.shared_inbox,
+    preferred_username = EXCLUDED.preferred_username,
+    public_key_pem = EXCLUDED.public_key_pem
+RETURNING *;
+
+-- name: GetRemoteActorByKeyID :one
+SELECT * FROM remote_actors WHERE key_id = $1;
+
+-- name: DeleteRemoteActor :execrows
+DELETE FROM remote_actors WHERE uri = $1;
+
+-- name: AddRemoteFollow :exec
+INSERT INTO remote_follows (remote_actor_id, user_id, activity_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (remote_actor_id, user_id) DO UPDATE SET activity_id = EXCLUDED.activity_id;
+
+-- name: RemoveRemoteFollow :execrows
+DELETE FROM remote_follows WHERE remote_actor_id = $1 AND user_id = $2;
+
+-- name: GetRemoteFollowerInboxes :many
+SELECT DISTINCT COALESCE(NULLIF(a.shared_inbox, ''), a.inbox)::text AS inbox
+FROM remote_actors a INNER JOIN remote_follows f ON f.remote_actor_id = a.id
+WHERE f.user_id = $1;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*) FROM remote_follows WHERE user_id = $1;
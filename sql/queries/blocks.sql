@@ -0,0 +1,55 @@
+-- name: BlockUser :one
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (blocker_id, blocked_id) DO UPDATE SET blocker_id = EXCLUDED.blocker_id
+RETURNING *;
+
+-- name: UnblockUser :execrows
+DELETE FROM blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: RemoveFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = sqlc.arg(user_a) AND followee_id = sqlc.arg(user_b))
+    OR (follower_id = sqlc.arg(user_b) AND followee_id = sqlc.arg(user_a));
+
+-- name: IsBlockedEitherWay :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = sqlc.arg(user_a) AND blocked_id = sqlc.arg(user_b))
+        OR (blocker_id = sqlc.arg(user_b) AND blocked_id = sqlc.arg(user_a))
+);
+
+-- name: GetBlockedUsers :many
+SELECT u.* FROM users u INNER JOIN blocks b ON u.id = b.blocked_id
+WHERE b.blocker_id = sqlc.arg(user_id)
+ORDER BY b.created_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: MuteUser :one
+INSERT INTO mutes (muter_id, muted_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (muter_id, muted_id) DO UPDATE SET created_at = NOW(), expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: UnmuteUser :execrows
+DELETE FROM mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutes :many
+SELECT sqlc.embed(u), m.expires_at FROM users u INNER JOIN mutes m ON u.id = m.muted_id
+WHERE m.muter_id = sqlc.arg(user_id) AND (m.expires_at IS NULL OR m.expires_at > NOW())
+ORDER BY m.created_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: IsMuted :one
+SELECT EXISTS (
+    SELECT 1 FROM mutes
+    WHERE muter_id = $1 AND muted_id = $2 AND (expires_at IS NULL OR expires_at > NOW())
+);
+
+-- name: DeleteExpiredMutes :execrows
+DELETE FROM mutes WHERE expires_at IS NOT NULL AND expires_at <= NOW();
+
+-- name: IsBlocking :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks WHERE blocker_id = $1 AND blocked_id = $2
+);
@@ -0,0 +1,23 @@
+-- name: CreateChirpEvent :one
+INSERT INTO chirp_events (created_at, type, chirp_id, user_id, tags, visibility, conversation_id, rechirped_by)
+VALUES (NOW(), $1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetChirpEvent :one
+SELECT * FROM chirp_events WHERE id = $1;
+
+-- name: GetChirpEventsAfter :many
+SELECT * FROM chirp_events
+WHERE id > sqlc.arg(after_id)
+ORDER BY id ASC
+LIMIT sqlc.arg(batch_size)::int;
+
+-- name: DeleteChirpEventsBefore :execrows
+DELETE FROM chirp_events WHERE created_at < $1;
+
+-- name: GetHiddenAuthorIDs :many
+SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = sqlc.arg(user_id)
+UNION
+SELECT blocker_id FROM blocks WHERE blocked_id = sqlc.arg(user_id)
+UNION
+SELECT muted_id FROM mutes WHERE muter_id = sqlc.arg(user_id) AND (expires_at IS NULL OR expires_at > NOW());
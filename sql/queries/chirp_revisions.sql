@@ -0,0 +1,16 @@
+-- name: EditChirp :one
+WITH revision AS (
+    INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at, status)
+    SELECT gen_random_uuid(), id, body, updated_at, NOW(), status FROM chirps WHERE id = sqlc.arg(id)
+)
+UPDATE chirps SET body = sqlc.arg(body), status = sqlc.arg(status), moderation_reasons = sqlc.arg(moderation_reasons)::text[], updated_at = NOW(), edited_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_id = sqlc.arg(chirp_id) AND (sqlc.arg(include_unpublished)::bool OR status = 'published')
+ORDER BY replaced_at ASC;
+
+-- name: ChirpWasPublished :one
+SELECT EXISTS (SELECT 1 FROM chirp_revisions WHERE chirp_id = $1 AND status = 'published');
\ No newline at end of file
@@ -1,22 +1,141 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
-    gen_random_uuid(),
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, conversation_id, quote_of, status, moderation_reasons, publish_at, visibility)
+SELECT
+    new_chirp.id,
     NOW(),
     NOW(),
-    $1,
-    $2
-)
+    sqlc.arg(body)::text,
+    sqlc.arg(user_id)::uuid,
+    sqlc.narg(in_reply_to)::uuid,
+    COALESCE((SELECT conversation_id FROM chirps WHERE id = sqlc.narg(in_reply_to)::uuid), new_chirp.id),
+    sqlc.narg(quote_of)::uuid,
+    sqlc.arg(status)::text,
+    sqlc.arg(moderation_reasons)::text[],
+    sqlc.narg(publish_at)::timestamp,
+    sqlc.arg(visibility)::text
+FROM (SELECT gen_random_uuid() AS id) AS new_chirp
 RETURNING *;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+SELECT * FROM chirps
+WHERE tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND (hidden_at IS NULL OR sqlc.arg(include_hidden)::bool)
+AND (visibility = 'public' OR user_id = sqlc.arg(viewer_id)
+    OR (visibility = 'followers' AND EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = sqlc.arg(viewer_id) AND f.followee_id = chirps.user_id AND f.status = 'accepted')))
+AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = chirps.user_id)
+    OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.arg(viewer_id)))
+AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.arg(viewer_id) AND m.muted_id = chirps.user_id
+    AND (m.expires_at IS NULL OR m.expires_at > NOW()))
+ORDER BY created_at ASC;
+
+-- name: GetPublicChirpsByTag :many
+SELECT * FROM chirps
+WHERE tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND hidden_at IS NULL AND visibility = 'public'
+AND body ~* ('(^|[^A-Za-z0-9_#&])#' || sqlc.arg(tag)::text || '([^A-Za-z0-9_]|$)')
+ORDER BY created_at DESC
+LIMIT sqlc.arg(page_limit)::int;
 
 -- name: GetChirp :one
 SELECT * FROM chirps WHERE id = $1;
 
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1;
 
 -- name: GetAllUserChirps :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
\ No newline at end of file
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND (hidden_at IS NULL OR sqlc.arg(include_hidden)::bool)
+AND (visibility = 'public' OR user_id = sqlc.arg(viewer_id)
+    OR (visibility = 'followers' AND EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = sqlc.arg(viewer_id) AND f.followee_id = chirps.user_id AND f.status = 'accepted')))
+AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = chirps.user_id)
+    OR (b.blocker_id = chirps.user_id AND b.blocked_id = sqlc.arg(viewer_id)))
+ORDER BY created_at ASC;
+
+-- name: GetPublicUserChirps :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND hidden_at IS NULL AND visibility = 'public'
+ORDER BY created_at DESC
+LIMIT sqlc.arg(page_limit)::int;
+
+-- name: CountUserChirps :one
+SELECT COUNT(*) FROM chirps WHERE user_id = $1 AND tombstoned_at IS NULL AND deleted_at IS NULL AND status = 'published' AND hidden_at IS NULL AND visibility = 'public';
+
+-- name: CountChirpReplies :one
+SELECT COUNT(*) FROM chirps WHERE in_reply_to = $1;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps SET body = '', updated_at = NOW(), tombstoned_at = NOW(), deleted_at = NULL WHERE id = $1;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT p.id, p.in_reply_to, 1 AS depth FROM chirps p WHERE p.id = (SELECT in_reply_to FROM chirps WHERE id = $1)
+    UNION ALL
+    SELECT p.id, p.in_reply_to, a.depth + 1 FROM chirps p INNER JOIN ancestors a ON p.id = a.in_reply_to
+)
+SELECT c.* FROM chirps c INNER JOIN ancestors a ON c.id = a.id ORDER BY a.depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT r.id, 1 AS depth, ARRAY[to_char(r.created_at, 'YYYYMMDDHH24MISSUS') || r.id::text] AS path
+    FROM chirps r WHERE r.in_reply_to = sqlc.arg(chirp_id)::uuid
+    UNION ALL
+    SELECT r.id, d.depth + 1, d.path || (to_char(r.created_at, 'YYYYMMDDHH24MISSUS') || r.id::text)
+    FROM chirps r INNER JOIN descendants d ON r.in_reply_to = d.id
+),
+-- A reply the viewer may not see (e.g. deleted or held after an edit) is
+-- still returned when a reply below it is visible, so the tree stays intact.
+visible AS (
+    SELECT d.id, d.depth, d.path, (c.status = 'published' AND c.hidden_at IS NULL AND c.deleted_at IS NULL
+        AND (c.visibility <> 'followers' OR c.user_id = sqlc.arg(viewer_id)
+            OR EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = sqlc.arg(viewer_id) AND f.followee_id = c.user_id AND f.status = 'accepted'))
+        AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = c.user_id)
+            OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.arg(viewer_id))))::boolean AS visible
+    FROM chirps c INNER JOIN descendants d ON c.id = d.id
+)
+SELECT sqlc.embed(c), d.depth, d.visible FROM chirps c INNER JOIN visible d ON c.id = d.id
+WHERE d.visible OR EXISTS (SELECT 1 FROM visible v WHERE v.visible AND v.depth > d.depth AND v.path[1:d.depth] = d.path)
+ORDER BY d.path
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetUserScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND status = 'scheduled' AND tombstoned_at IS NULL AND deleted_at IS NULL
+ORDER BY publish_at ASC;
+
+-- name: ClaimDueChirps :many
+SELECT * FROM chirps
+WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+ORDER BY publish_at
+LIMIT sqlc.arg(batch_size)::int
+FOR UPDATE SKIP LOCKED;
+
+-- name: PublishScheduledChirp :one
+UPDATE chirps SET status = 'published', created_at = publish_at, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: PostponeScheduledChirp :exec
+UPDATE chirps SET publish_at = sqlc.arg(publish_at), updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: SoftDeleteChirp :exec
+UPDATE chirps SET deleted_at = NOW() WHERE id = $1;
+
+-- name: RestoreChirp :one
+UPDATE chirps SET deleted_at = NULL
+WHERE id = sqlc.arg(id) AND deleted_at > sqlc.arg(deleted_after)
+RETURNING *;
+
+-- name: GetExpiredDeletedChirps :many
+SELECT * FROM chirps WHERE deleted_at <= sqlc.arg(deleted_before)
+ORDER BY deleted_at
+LIMIT sqlc.arg(batch_size)::int;
+
+-- name: CountUserChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND created_at >= sqlc.arg(since);
+
+-- name: CountUserLiveChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND created_at >= sqlc.arg(since) AND status <> 'scheduled';
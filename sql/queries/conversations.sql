@@ -0,0 +1,90 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, created_by, is_group)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW());
+
+-- name: GetConversation :one
+SELECT * FROM conversations WHERE id = $1;
+
+-- name: FindDirectConversation :one
+SELECT c.* FROM conversations c
+WHERE NOT c.is_group
+    AND EXISTS (SELECT 1 FROM conversation_participants p WHERE p.conversation_id = c.id AND p.user_id = sqlc.arg(user_a) AND p.left_at IS NULL)
+    AND EXISTS (SELECT 1 FROM conversation_participants p WHERE p.conversation_id = c.id AND p.user_id = sqlc.arg(user_b) AND p.left_at IS NULL)
+ORDER BY c.updated_at DESC
+LIMIT 1;
+
+-- name: GetConversationParticipant :one
+SELECT * FROM conversation_participants
+WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL;
+
+-- name: GetConversationsParticipants :many
+SELECT p.conversation_id, sqlc.embed(u) FROM users u INNER JOIN conversation_participants p ON u.id = p.user_id
+WHERE p.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[]) AND p.left_at IS NULL
+ORDER BY p.conversation_id, p.joined_at, u.id;
+
+-- name: GetUserConversations :many
+SELECT sqlc.embed(c), p.last_read_at,
+    (SELECT COUNT(*) FROM messages m
+        WHERE m.conversation_id = c.id AND m.sender_id <> p.user_id
+            AND (p.last_read_at IS NULL OR m.created_at > p.last_read_at)
+            AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = p.user_id AND b.blocked_id = m.sender_id)
+                OR (b.blocker_id = m.sender_id AND b.blocked_id = p.user_id))
+    ) AS unread_count
+FROM conversations c INNER JOIN conversation_participants p ON c.id = p.conversation_id
+WHERE p.user_id = sqlc.arg(user_id) AND p.left_at IS NULL
+ORDER BY c.updated_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetUserConversation :one
+SELECT sqlc.embed(c), p.last_read_at,
+    (SELECT COUNT(*) FROM messages m
+        WHERE m.conversation_id = c.id AND m.sender_id <> p.user_id
+            AND (p.last_read_at IS NULL OR m.created_at > p.last_read_at)
+            AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = p.user_id AND b.blocked_id = m.sender_id)
+                OR (b.blocker_id = m.sender_id AND b.blocked_id = p.user_id))
+    ) AS unread_count
+FROM conversations c INNER JOIN conversation_participants p ON c.id = p.conversation_id
+WHERE c.id = sqlc.arg(conversation_id) AND p.user_id = sqlc.arg(user_id) AND p.left_at IS NULL;
+
+-- name: CreateMessage :one
+WITH touched AS (
+    UPDATE conversations SET updated_at = NOW() WHERE id = sqlc.arg(conversation_id)
+)
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg(conversation_id),
+    sqlc.arg(sender_id),
+    sqlc.arg(body)
+)
+RETURNING *;
+
+-- name: GetMessages :many
+SELECT m.* FROM messages m
+WHERE m.conversation_id = sqlc.arg(conversation_id)
+    AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = m.sender_id)
+        OR (b.blocker_id = m.sender_id AND b.blocked_id = sqlc.arg(viewer_id)))
+    AND (sqlc.narg(before_id)::uuid IS NULL
+        OR (m.created_at, m.id) < (SELECT created_at, id FROM messages WHERE id = sqlc.narg(before_id)::uuid))
+ORDER BY m.created_at DESC, m.id DESC
+LIMIT sqlc.arg(page_limit)::int;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL;
+
+-- name: LeaveConversation :execrows
+UPDATE conversation_participants SET left_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL;
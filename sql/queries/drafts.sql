@@ -0,0 +1,36 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, in_reply_to, quote_of)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetDraft :one
+SELECT * FROM drafts WHERE id = $1;
+
+-- name: GetUserDrafts :many
+SELECT * FROM drafts WHERE user_id = sqlc.arg(user_id)
+ORDER BY updated_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: UpdateDraft :one
+UPDATE drafts SET body = $2, in_reply_to = $3, quote_of = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDraft :exec
+DELETE FROM drafts WHERE id = $1;
+
+-- name: ClaimDraft :one
+DELETE FROM drafts WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RestoreDraft :exec
+INSERT INTO drafts (id, created_at, updated_at, user_id, body, in_reply_to, quote_of)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
@@ -0,0 +1,58 @@
+-- name: FollowUser :one
+INSERT INTO follows (follower_id, followee_id, status, created_at, accepted_at)
+VALUES (
+    sqlc.arg(follower_id),
+    sqlc.arg(followee_id),
+    sqlc.arg(status),
+    NOW(),
+    CASE WHEN sqlc.arg(status) = 'accepted' THEN NOW() END
+)
+ON CONFLICT (follower_id, followee_id) DO UPDATE SET follower_id = EXCLUDED.follower_id
+RETURNING *;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollow :one
+SELECT * FROM follows WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: AcceptFollow :one
+UPDATE follows SET status = 'accepted', accepted_at = NOW()
+WHERE follower_id = $1 AND followee_id = $2 AND status = 'pending'
+RETURNING *;
+
+-- name: AcceptAllPendingFollows :exec
+UPDATE follows SET status = 'accepted', accepted_at = NOW()
+WHERE followee_id = $1 AND status = 'pending';
+
+-- name: GetFollowers :many
+SELECT u.* FROM users u INNER JOIN follows f ON u.id = f.follower_id
+WHERE f.followee_id = sqlc.arg(user_id) AND f.status = 'accepted'
+ORDER BY f.accepted_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetFollowing :many
+SELECT u.* FROM users u INNER JOIN follows f ON u.id = f.followee_id
+WHERE f.follower_id = sqlc.arg(user_id) AND f.status = 'accepted'
+ORDER BY f.accepted_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetFollowingIDs :many
+SELECT followee_id FROM follows WHERE follower_id = $1 AND status = 'accepted';
+
+-- name: GetFollowRequests :many
+SELECT u.* FROM users u INNER JOIN follows f ON u.id = f.follower_id
+WHERE f.followee_id = sqlc.arg(user_id) AND f.status = 'pending'
+ORDER BY f.created_at ASC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: CountFollowers :one
+SELECT COUNT(*) FROM follows WHERE followee_id = $1 AND status = 'accepted';
+
+-- name: CountFollowing :one
+SELECT COUNT(*) FROM follows WHERE follower_id = $1 AND status = 'accepted';
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+    SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2 AND status = 'accepted'
+);
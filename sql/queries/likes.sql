@@ -0,0 +1,32 @@
+-- name: LikeChirp :execrows
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetChirpLikers :many
+SELECT u.* FROM users u INNER JOIN likes l ON u.id = l.user_id
+WHERE l.chirp_id = sqlc.arg(chirp_id)
+AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = u.id)
+    OR (b.blocker_id = u.id AND b.blocked_id = sqlc.arg(viewer_id)))
+ORDER BY l.created_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetUserLikedChirps :many
+SELECT c.* FROM chirps c INNER JOIN likes l ON c.id = l.chirp_id
+WHERE l.user_id = sqlc.arg(user_id) AND c.tombstoned_at IS NULL AND c.deleted_at IS NULL AND c.status = 'published' AND c.hidden_at IS NULL
+AND (c.visibility = 'public' OR c.user_id = sqlc.arg(viewer_id)
+    OR (c.visibility = 'followers' AND EXISTS (SELECT 1 FROM follows f WHERE f.follower_id = sqlc.arg(viewer_id) AND f.followee_id = c.user_id AND f.status = 'accepted')))
+AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = c.user_id)
+    OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.arg(viewer_id)))
+ORDER BY l.created_at DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM likes WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
\ No newline at end of file
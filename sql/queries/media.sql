@@ -0,0 +1,42 @@
+-- name: CreateMediaFile :one
+INSERT INTO media_files (id, created_at, user_id, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetMediaFile :one
+SELECT * FROM media_files WHERE id = $1;
+
+-- name: GetMediaFilesByIDs :many
+SELECT * FROM media_files WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: AttachMediaToChirp :execrows
+UPDATE media_files
+SET chirp_id = sqlc.arg(chirp_id), position = array_position(sqlc.arg(ids)::uuid[], id)
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND user_id = sqlc.arg(user_id) AND chirp_id IS NULL;
+
+-- name: GetChirpsMedia :many
+SELECT * FROM media_files WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: DeleteChirpMedia :many
+DELETE FROM media_files WHERE chirp_id = $1
+RETURNING *;
+
+-- name: DeleteUnattachedMedia :many
+DELETE FROM media_files WHERE id IN (
+    SELECT id FROM media_files
+    WHERE chirp_id IS NULL AND created_at < sqlc.arg(created_before)
+    ORDER BY created_at
+    LIMIT sqlc.arg(batch_size)::int
+)
+RETURNING *;
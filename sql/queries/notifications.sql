@@ -0,0 +1,61 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, created_at, updated_at, user_id, type, group_key, chirp_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL DO UPDATE SET user_id = EXCLUDED.user_id
+RETURNING *;
+
+-- name: AddNotificationActor :exec
+WITH added AS (
+    INSERT INTO notification_actors (notification_id, actor_id, created_at)
+    VALUES (sqlc.arg(notification_id), sqlc.arg(actor_id), NOW())
+    ON CONFLICT DO NOTHING
+    RETURNING notification_id
+)
+UPDATE notifications SET actor_count = actor_count + 1, updated_at = NOW()
+WHERE id IN (SELECT notification_id FROM added);
+
+-- name: GetNotification :one
+SELECT * FROM notifications WHERE id = $1;
+
+-- name: AnnounceNotification :exec
+SELECT pg_notify('notifications', sqlc.arg(notification_id)::text);
+
+-- name: GetNotifications :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type)::text)
+    AND (read_at IS NULL OR NOT sqlc.arg(unread_only)::bool)
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetNotificationActors :many
+SELECT a.notification_id, sqlc.embed(u) FROM (
+    SELECT na.notification_id, na.actor_id, na.created_at,
+        row_number() OVER (PARTITION BY na.notification_id ORDER BY na.created_at DESC) AS rank
+    FROM notification_actors na
+    WHERE na.notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+) a INNER JOIN users u ON u.id = a.actor_id
+WHERE a.rank <= sqlc.arg(per_notification)::int
+ORDER BY a.notification_id, a.created_at DESC;
+
+-- name: CountUnreadNotifications :many
+SELECT type, COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+GROUP BY type;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications SET read_at = NOW()
+WHERE id = $1 AND user_id = $2 AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND read_at IS NULL
+    AND (sqlc.narg(type)::text IS NULL OR type = sqlc.narg(type)::text);
@@ -0,0 +1,8 @@
+-- name: RecordPolkaWebhook :exec
+INSERT INTO polka_webhooks (id, received_at, event_id, event, signature, body, result, status_code, error)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ClaimPolkaEvent :execrows
+INSERT INTO polka_events (event_id, created_at, event)
+VALUES ($1, NOW(), $2)
+ON CONFLICT (event_id) DO NOTHING;
@@ -0,0 +1,14 @@
+-- name: Rechirp :execrows
+INSERT INTO rechirps (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UndoRechirp :execrows
+DELETE FROM rechirps WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetRechirpedChirpIDs :many
+SELECT chirp_id FROM rechirps WHERE user_id = sqlc.arg(user_id) AND chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
\ No newline at end of file
@@ -0,0 +1,57 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: ListReports :many
+SELECT * FROM reports WHERE status = sqlc.arg(status)
+ORDER BY created_at ASC
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: ResolveReport :one
+UPDATE reports SET status = 'resolved', resolved_by = $2, resolved_at = NOW(), resolution = $3, updated_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, report_id, chirp_id, target_user_id, action, note, chirp_body)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+RETURNING *;
+
+-- name: GetReportActions :many
+SELECT * FROM moderation_actions WHERE report_id = $1 ORDER BY created_at ASC;
+
+-- name: HideChirp :exec
+UPDATE chirps SET hidden_at = NOW() WHERE id = $1;
+
+-- name: UnhideChirp :exec
+UPDATE chirps SET hidden_at = NULL WHERE id = $1;
+
+-- name: ApproveChirp :exec
+UPDATE chirps
+SET status = CASE WHEN publish_at > NOW() THEN 'scheduled' ELSE 'published' END, updated_at = NOW()
+WHERE id = $1 AND status = 'held';
+
+-- name: SuspendUser :exec
+UPDATE users SET suspended_until = $2, updated_at = NOW() WHERE id = $1;
\ No newline at end of file
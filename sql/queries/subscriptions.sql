@@ -0,0 +1,41 @@
+-- name: GetSubscription :one
+SELECT * FROM subscriptions WHERE user_id = $1;
+
+-- name: GetSubscriptionForUpdate :one
+SELECT * FROM subscriptions WHERE user_id = $1 FOR UPDATE;
+
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (user_id, created_at, updated_at, status, current_period_end)
+VALUES ($1, NOW(), NOW(), $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET
+    updated_at = NOW(),
+    status = EXCLUDED.status,
+    current_period_end = EXCLUDED.current_period_end
+RETURNING *;
+
+-- name: CreateSubscriptionEvent :exec
+INSERT INTO subscription_events (id, created_at, user_id, event, status, current_period_end, polka_event_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5);
+
+-- name: GetSubscriptionEvents :many
+SELECT * FROM subscription_events WHERE user_id = $1 ORDER BY created_at DESC, id;
+
+-- name: ExpireSubscriptions :many
+WITH locked AS (
+    SELECT u.id FROM users u INNER JOIN subscriptions s ON s.user_id = u.id
+    WHERE s.status IN ('active', 'past_due') AND s.current_period_end <= sqlc.arg(now)
+    ORDER BY u.id
+    FOR UPDATE OF u
+), expired AS (
+    UPDATE subscriptions SET status = 'expired', updated_at = NOW()
+    FROM locked
+    WHERE subscriptions.user_id = locked.id
+        AND subscriptions.status IN ('active', 'past_due') AND subscriptions.current_period_end <= sqlc.arg(now)
+    RETURNING subscriptions.user_id, subscriptions.current_period_end
+), downgraded AS (
+    UPDATE users SET is_chirpy_red = false, updated_at = NOW()
+    FROM expired WHERE users.id = expired.user_id
+)
+INSERT INTO subscription_events (id, created_at, user_id, event, status, current_period_end)
+SELECT gen_random_uuid(), NOW(), user_id, 'subscription.expired', 'expired', current_period_end FROM expired
+RETURNING user_id;
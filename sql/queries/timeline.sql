@@ -0,0 +1,42 @@
+-- name: GetTimelineEntries :many
+WITH followed AS (
+    SELECT f.followee_id AS user_id FROM follows f
+    WHERE f.follower_id = sqlc.arg(viewer_id) AND f.status = 'accepted'
+    UNION ALL
+    SELECT sqlc.arg(viewer_id)::uuid
+)
+SELECT entry.chirp_id, entry.sort_at, entry.rechirped_by FROM (
+    (
+        SELECT c.id AS chirp_id, c.created_at AS sort_at, '00000000-0000-0000-0000-000000000000'::uuid AS rechirped_by
+        FROM chirps c
+        WHERE c.user_id IN (SELECT user_id FROM followed)
+            AND c.status = 'published' AND c.hidden_at IS NULL AND c.deleted_at IS NULL AND c.tombstoned_at IS NULL
+            AND c.visibility IN ('public', 'followers')
+            AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.arg(viewer_id) AND m.muted_id = c.user_id
+                AND (m.expires_at IS NULL OR m.expires_at > NOW()))
+            AND (sqlc.narg(before_at)::timestamp IS NULL
+                OR (c.created_at, c.id, '00000000-0000-0000-0000-000000000000'::uuid)
+                    < (sqlc.narg(before_at)::timestamp, sqlc.narg(before_chirp_id)::uuid, sqlc.narg(before_rechirped_by)::uuid))
+        ORDER BY c.created_at DESC, c.id DESC
+        LIMIT sqlc.arg(page_limit)::int
+    )
+    UNION ALL
+    (
+        SELECT r.chirp_id, r.created_at, r.user_id
+        FROM rechirps r INNER JOIN chirps c ON c.id = r.chirp_id
+        WHERE r.user_id IN (SELECT user_id FROM followed)
+            AND c.status = 'published' AND c.hidden_at IS NULL AND c.deleted_at IS NULL AND c.tombstoned_at IS NULL
+            AND c.visibility = 'public'
+            AND NOT EXISTS (SELECT 1 FROM blocks b WHERE (b.blocker_id = sqlc.arg(viewer_id) AND b.blocked_id = c.user_id)
+                OR (b.blocker_id = c.user_id AND b.blocked_id = sqlc.arg(viewer_id)))
+            AND NOT EXISTS (SELECT 1 FROM mutes m WHERE m.muter_id = sqlc.arg(viewer_id) AND m.muted_id IN (r.user_id, c.user_id)
+                AND (m.expires_at IS NULL OR m.expires_at > NOW()))
+            AND (sqlc.narg(before_at)::timestamp IS NULL
+                OR (r.created_at, r.chirp_id, r.user_id)
+                    < (sqlc.narg(before_at)::timestamp, sqlc.narg(before_chirp_id)::uuid, sqlc.narg(before_rechirped_by)::uuid))
+        ORDER BY r.created_at DESC, r.chirp_id DESC, r.user_id DESC
+        LIMIT sqlc.arg(page_limit)::int
+    )
+) AS entry
+ORDER BY entry.sort_at DESC, entry.chirp_id DESC, entry.rechirped_by DESC
+LIMIT sqlc.arg(page_limit)::int;
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -18,5 +19,23 @@ SELECT * FROM users WHERE email = $1;
 -- name: UpdateUser :one
 UPDATE users SET email = $2, hashed_password = $3 WHERE id = $1 RETURNING *;
 
--- name: RedChirpyUser :exec
-UPDATE users SET is_chirpy_red = true WHERE id = $1;
\ No newline at end of file
+-- name: LockUser :one
+SELECT id FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: LockUsers :many
+SELECT id FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]) ORDER BY id FOR UPDATE;
+
+-- name: SetChirpyRed :execrows
+UPDATE users SET is_chirpy_red = $2, updated_at = NOW() WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE LOWER(handle) = LOWER(sqlc.arg(handle));
+
+-- name: UpdateUserProfile :one
+UPDATE users SET handle = $2, display_name = $3, bio = $4, avatar_url = $5, is_private = $6, updated_at = NOW() WHERE id = $1 RETURNING *;
\ No newline at end of file
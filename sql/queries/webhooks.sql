@@ -0,0 +1,70 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, created_at, updated_at, user_id, url, events, secret)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints WHERE id = $1;
+
+-- name: GetUserWebhookEndpoints :many
+SELECT * FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at, id;
+
+-- name: VerifyWebhookEndpoint :one
+UPDATE webhook_endpoints SET verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountUserWebhookEndpoints :one
+SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (id, created_at, updated_at, endpoint_id, event_id, event, payload, next_attempt_at)
+SELECT gen_random_uuid(), NOW(), NOW(), id, sqlc.arg(event_id)::uuid, sqlc.arg(event)::text, sqlc.arg(payload)::text, NOW()
+FROM webhook_endpoints
+WHERE user_id = sqlc.arg(user_id) AND sqlc.arg(event)::text = ANY(events);
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = sqlc.arg(lease_until), updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(batch_size)::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordWebhookAttempt :exec
+INSERT INTO webhook_attempts (id, created_at, delivery_id, attempt, status_code, response_body, error, duration_ms)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6);
+
+-- name: FinishWebhookAttempt :exec
+UPDATE webhook_deliveries SET
+    status = sqlc.arg(status),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    last_status_code = sqlc.arg(last_status_code),
+    last_error = sqlc.arg(last_error),
+    delivered_at = CASE WHEN sqlc.arg(status)::text = 'delivered' THEN NOW() END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id = $1 AND endpoint_id = $2;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg(endpoint_id) AND (sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text)
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg(page_limit)::int OFFSET sqlc.arg(page_offset)::int;
+
+-- name: GetWebhookAttempts :many
+SELECT * FROM webhook_attempts WHERE delivery_id = $1 ORDER BY attempt, created_at;
+
+-- name: RedeliverWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, created_at, updated_at, endpoint_id, event_id, event, payload, next_attempt_at, redelivery_of)
+SELECT gen_random_uuid(), NOW(), NOW(), endpoint_id, event_id, event, payload, NOW(), id
+FROM webhook_deliveries WHERE webhook_deliveries.id = $1
+RETURNING *;
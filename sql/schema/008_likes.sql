@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE likes(
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX likes_chirp_id_idx ON likes (chirp_id, created_at DESC);
+CREATE INDEX likes_user_id_idx ON likes (user_id, created_at DESC);
+
+ALTER TABLE chirps ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+
+-- The counter is kept by a trigger so that likes removed by cascades
+-- (e.g. a deleted user) are accounted for too.
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_like_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET like_count = like_count + 1 WHERE id = NEW.chirp_id;
+    ELSE
+        UPDATE chirps SET like_count = like_count - 1 WHERE id = OLD.chirp_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER likes_count_trigger AFTER INSERT OR DELETE ON likes
+    FOR EACH ROW EXECUTE FUNCTION update_chirp_like_count();
+
+-- +goose Down
+DROP TRIGGER likes_count_trigger ON likes;
+DROP FUNCTION update_chirp_like_count();
+ALTER TABLE chirps DROP COLUMN like_count;
+DROP TABLE likes;
@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE rechirps(
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE INDEX rechirps_chirp_id_idx ON rechirps (chirp_id);
+CREATE INDEX rechirps_user_id_idx ON rechirps (user_id, created_at DESC);
+
+ALTER TABLE chirps ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0;
+
+-- No foreign key on purpose: a quote outlives the chirp it quotes, and
+-- keeping the ID lets clients show that the original is gone.
+ALTER TABLE chirps ADD COLUMN quote_of UUID;
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of);
+
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_rechirp_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE id = NEW.chirp_id;
+    ELSE
+        UPDATE chirps SET rechirp_count = rechirp_count - 1 WHERE id = OLD.chirp_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER rechirps_count_trigger AFTER INSERT OR DELETE ON rechirps
+    FOR EACH ROW EXECUTE FUNCTION update_chirp_rechirp_count();
+
+-- +goose Down
+DROP TRIGGER rechirps_count_trigger ON rechirps;
+DROP FUNCTION update_chirp_rechirp_count();
+DROP INDEX chirps_quote_of_idx;
+ALTER TABLE chirps DROP COLUMN quote_of;
+ALTER TABLE chirps DROP COLUMN rechirp_count;
+DROP TABLE rechirps;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+ALTER TABLE chirps ADD COLUMN moderation_reasons TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX chirps_status_idx ON chirps (status) WHERE status <> 'published';
+
+-- +goose Down
+DROP INDEX chirps_status_idx;
+ALTER TABLE chirps DROP COLUMN moderation_reasons;
+ALTER TABLE chirps DROP COLUMN status;
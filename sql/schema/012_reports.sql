@@ -0,0 +1,48 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMP;
+ALTER TABLE chirps ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE reports(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    reporter_id UUID,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolved_by UUID,
+    resolved_at TIMESTAMP,
+    resolution TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX reports_status_idx ON reports (status, created_at);
+CREATE UNIQUE INDEX reports_open_per_reporter_idx ON reports (chirp_id, reporter_id) WHERE status = 'open';
+
+-- Every moderator action is kept, along with the chirp text it applied to,
+-- so the record survives the chirp being deleted.
+CREATE TABLE moderation_actions(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID,
+    report_id UUID,
+    chirp_id UUID,
+    target_user_id UUID NOT NULL,
+    action TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    chirp_body TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (report_id) REFERENCES reports(id) ON DELETE SET NULL,
+    FOREIGN KEY (target_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX moderation_actions_report_id_idx ON moderation_actions (report_id);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE chirps DROP COLUMN hidden_at;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN is_moderator;
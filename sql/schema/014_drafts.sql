@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN publish_at TIMESTAMP;
+CREATE INDEX chirps_scheduled_idx ON chirps (publish_at) WHERE status = 'scheduled';
+
+CREATE TABLE drafts(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    in_reply_to UUID,
+    quote_of UUID,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (in_reply_to) REFERENCES chirps(id) ON DELETE SET NULL,
+    FOREIGN KEY (quote_of) REFERENCES chirps(id) ON DELETE SET NULL
+);
+CREATE INDEX drafts_user_id_idx ON drafts (user_id, updated_at);
+
+-- +goose Down
+DROP TABLE drafts;
+DROP INDEX chirps_scheduled_idx;
+ALTER TABLE chirps DROP COLUMN publish_at;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT false;
+
+-- Follows of private accounts start out pending until the followee
+-- approves them; only accepted follows count.
+CREATE TABLE follows(
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'accepted',
+    created_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (follower_id <> followee_id)
+);
+CREATE INDEX follows_followee_id_idx ON follows (followee_id, status, created_at);
+
+-- +goose Down
+DROP TABLE follows;
+ALTER TABLE users DROP COLUMN is_private;
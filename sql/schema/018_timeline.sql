@@ -0,0 +1,7 @@
+-- +goose Up
+-- The home timeline merges the newest chirps of every followed user; this
+-- lets each of those lookups walk an index backwards from the cursor.
+CREATE INDEX chirps_user_id_created_at_idx ON chirps (user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX chirps_user_id_created_at_idx;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- Similar events are grouped into one notification per group_key (for
+-- example every like of a chirp) until the recipient reads it; after that
+-- the next event starts a new group.
+CREATE TABLE notifications(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    group_key TEXT NOT NULL,
+    chirp_id UUID,
+    actor_count INTEGER NOT NULL DEFAULT 0,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX notifications_unread_group_idx ON notifications (user_id, group_key) WHERE read_at IS NULL;
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, updated_at DESC);
+
+CREATE TABLE notification_actors(
+    notification_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (notification_id, actor_id),
+    FOREIGN KEY (notification_id) REFERENCES notifications(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE notification_actors;
+DROP TABLE notifications;
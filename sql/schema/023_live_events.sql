@@ -0,0 +1,10 @@
+-- +goose Up
+-- Chirp events now cover every published chirp, not just public ones, so
+-- WebSocket clients hear about followers-only chirps and unlisted replies
+-- they are allowed to see. The public stream still only sends public ones.
+ALTER TABLE chirp_events ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+ALTER TABLE chirp_events ADD COLUMN conversation_id UUID;
+
+-- +goose Down
+ALTER TABLE chirp_events DROP COLUMN conversation_id;
+ALTER TABLE chirp_events DROP COLUMN visibility;
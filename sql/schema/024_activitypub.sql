@@ -0,0 +1,40 @@
+-- +goose Up
+-- Each local user signs the activities they send with their own key pair,
+-- created the first time it is needed.
+CREATE TABLE actor_keys(
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- remote_actors caches the fediverse accounts that have sent us signed
+-- activities, keyed by the URI of the key they sign with.
+CREATE TABLE remote_actors(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    uri TEXT NOT NULL UNIQUE,
+    key_id TEXT NOT NULL UNIQUE,
+    inbox TEXT NOT NULL,
+    shared_inbox TEXT NOT NULL DEFAULT '',
+    preferred_username TEXT NOT NULL DEFAULT '',
+    public_key_pem TEXT NOT NULL
+);
+
+CREATE TABLE remote_follows(
+    remote_actor_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    activity_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (remote_actor_id, user_id),
+    FOREIGN KEY (remote_actor_id) REFERENCES remote_actors(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX remote_follows_user_id_idx ON remote_follows (user_id);
+
+-- +goose Down
+DROP TABLE remote_follows;
+DROP TABLE remote_actors;
+DROP TABLE actor_keys;
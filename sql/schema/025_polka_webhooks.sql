@@ -0,0 +1,27 @@
+-- +goose Up
+-- Every request to the Polka webhook endpoint is kept for auditing,
+-- including those whose signature did not check out.
+CREATE TABLE polka_webhooks(
+    id UUID PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL,
+    event_id TEXT NOT NULL DEFAULT '',
+    event TEXT NOT NULL DEFAULT '',
+    signature TEXT NOT NULL DEFAULT '',
+    body BYTEA NOT NULL,
+    result TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX polka_webhooks_event_id_idx ON polka_webhooks (event_id);
+
+-- polka_events holds the ids of the events that have been applied, so
+-- Polka retrying an event is a no-op.
+CREATE TABLE polka_events(
+    event_id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    event TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE polka_events;
+DROP TABLE polka_webhooks;
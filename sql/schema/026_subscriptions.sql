@@ -0,0 +1,37 @@
+-- +goose Up
+-- subscriptions holds the current state of each user's Chirpy Red
+-- membership. users.is_chirpy_red is kept in step with it. A NULL
+-- current_period_end means the membership does not run out.
+CREATE TABLE subscriptions(
+    user_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX subscriptions_period_end_idx ON subscriptions (current_period_end)
+    WHERE status IN ('active', 'past_due');
+
+-- subscription_events is the history of each membership: every event
+-- applied to it and the state it left the membership in.
+CREATE TABLE subscription_events(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    event TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP,
+    polka_event_id TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX subscription_events_user_id_idx ON subscription_events (user_id, created_at);
+
+-- Members who upgraded before subscriptions were tracked have no known
+-- period, so they keep Red until Polka says otherwise.
+INSERT INTO subscriptions (user_id, created_at, updated_at, status, current_period_end)
+SELECT id, NOW(), NOW(), 'active', NULL FROM users WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscription_events;
+DROP TABLE subscriptions;
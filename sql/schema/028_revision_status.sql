@@ -0,0 +1,8 @@
+-- +goose Up
+-- status is the status the chirp had while a revision's body was current,
+-- so bodies that were held or scheduled are not shown to the public.
+-- Revisions from before it was recorded count as published.
+ALTER TABLE chirp_revisions ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+
+-- +goose Down
+ALTER TABLE chirp_revisions DROP COLUMN status;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Rechirps are logged as chirp events too, so WebSocket timelines can show
+-- them like GET /api/timeline does. rechirped_by is who rechirped the
+-- chirp; user_id is still its author. The public stream leaves them out.
+ALTER TABLE chirp_events ADD COLUMN rechirped_by UUID;
+
+-- +goose Down
+ALTER TABLE chirp_events DROP COLUMN rechirped_by;
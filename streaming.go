@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"server/internal/database"
+	"server/internal/stream"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	chirpEventCreated = "chirp.created"
+	chirpEventUpdated = "chirp.updated"
+	chirpEventDeleted = "chirp.deleted"
+	// chirpEventRechirped is only sent to WebSocket timelines.
+	chirpEventRechirped = "chirp.rechirped"
+
+	// chirpEventsChannel is the Postgres NOTIFY channel the chirp_events
+	// trigger announces new rows on; notificationsChannel carries the ids
+	// of notifications as they are created or grouped.
+	chirpEventsChannel   = "chirp_events"
+	notificationsChannel = "notifications"
+
+	streamBufferSize    = 64
+	streamDedupeWindow  = 1024
+	streamReplayBatch   = 500
+	streamResumeOverlap = 100
+	heartbeatInterval   = 15 * time.Second
+	chirpEventRetention = 24 * time.Hour
+)
+
+// streamable reports whether ev belongs in the public stream. Only events
+// about public chirps are streamed, so they never reveal anything an
+// anonymous reader could not already see. WebSocket clients also receive
+// events about other chirps they are allowed to see, and rechirps.
+func streamable(ev stream.Event) bool {
+	return ev.Visibility == visibilityPublic && ev.Type != chirpEventRechirped
+}
+
+// recordChirpEvent logs that a published chirp was created, edited or
+// deleted and
+// pushes the event to this instance's subscribers. The insert also
+// notifies the other instances. Like notifications, events are best effort.
+func (cfg *apiConfig) recordChirpEvent(ctx context.Context, typ string, chirp database.Chirp) {
+	cfg.logChirpEvent(ctx, typ, chirp, uuid.NullUUID{})
+}
+
+// recordRechirpEvent logs that userID rechirped chirp, for the WebSocket
+// timelines of their followers.
+func (cfg *apiConfig) recordRechirpEvent(ctx context.Context, chirp database.Chirp, userID uuid.UUID) {
+	cfg.logChirpEvent(ctx, chirpEventRechirped, chirp, uuid.NullUUID{UUID: userID, Valid: true})
+}
+
+func (cfg *apiConfig) logChirpEvent(ctx context.Context, typ string, chirp database.Chirp, rechirpedBy uuid.NullUUID) {
+	if chirp.Status != chirpStatusPublished {
+		return
+	}
+
+	event, err := cfg.dbQueries.CreateChirpEvent(ctx, database.CreateChirpEventParams{
+		Type:           typ,
+		ChirpID:        chirp.ID,
+		UserID:         chirp.UserID,
+		Tags:           hashtags(chirp.Body),
+		Visibility:     chirp.Visibility,
+		ConversationID: uuid.NullUUID{UUID: chirp.ConversationID, Valid: true},
+		RechirpedBy:    rechirpedBy,
+	})
+	if err != nil {
+		fmt.Println("recording chirp event:", err)
+		return
+	}
+
+	cfg.publishChirpEvents(ctx, []database.ChirpEvent{event})
+}
+
+func (cfg *apiConfig) publishChirpEvents(ctx context.Context, events []database.ChirpEvent) {
+	streamEvents, err := cfg.streamEvents(ctx, events)
+	if err != nil {
+		fmt.Println("publishing chirp events:", err)
+		return
+	}
+	for _, ev := range streamEvents {
+		cfg.hub.Publish(ev)
+	}
+}
+
+// streamEvents renders logged events for clients. A created or updated
+// event carries the chirp as an anonymous reader would see it and is
+// skipped once the chirp is no longer visible; a deleted event carries
+// only the chirp's id. Events about chirps anonymous readers cannot see
+// have no data, as each WebSocket client renders those for itself.
+func (cfg *apiConfig) streamEvents(ctx context.Context, events []database.ChirpEvent) ([]stream.Event, error) {
+	chirpIDs := []uuid.UUID{}
+	for _, event := range events {
+		if event.Type != chirpEventDeleted {
+			chirpIDs = append(chirpIDs, event.ChirpID)
+		}
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	visible := []database.Chirp{}
+	for _, chirp := range chirps {
+		ok, err := cfg.canViewChirp(ctx, uuid.Nil, chirp)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, chirp)
+		}
+	}
+	respChirps, err := cfg.chirpResponses(ctx, uuid.Nil, visible)
+	if err != nil {
+		return nil, err
+	}
+	chirpsByID := map[uuid.UUID]returnChirp{}
+	for _, chirp := range respChirps {
+		chirpsByID[chirp.ID] = chirp
+	}
+
+	streamEvents := []stream.Event{}
+	for _, event := range events {
+		var payload any = map[string]uuid.UUID{"id": event.ChirpID}
+		if event.Type != chirpEventDeleted {
+			chirp, ok := chirpsByID[event.ChirpID]
+			if !ok && event.Visibility == visibilityPublic {
+				continue
+			}
+			payload = nil
+			if ok {
+				payload = chirp
+			}
+		}
+		var data []byte
+		if payload != nil {
+			data, err = json.Marshal(payload)
+			if err != nil {
+				return nil, err
+			}
+		}
+		streamEvents = append(streamEvents, stream.Event{
+			ID:             event.ID,
+			CreatedAt:      event.CreatedAt,
+			Type:           event.Type,
+			ChirpID:        event.ChirpID,
+			UserID:         event.UserID,
+			RechirpedBy:    event.RechirpedBy.UUID,
+			ConversationID: event.ConversationID.UUID,
+			Visibility:     event.Visibility,
+			Tags:           event.Tags,
+			Data:           data,
+		})
+	}
+	return streamEvents, nil
+}
+
+// listenForEvents feeds the hubs with chirp events logged and
+// notifications sent by every server instance. Chirp events from this
+// instance arrive here as well and are dropped by the hub as duplicates;
+// notifications only ever reach their hub this way.
+func (cfg *apiConfig) listenForEvents(dbURL string) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			fmt.Println("listening for events:", err)
+		}
+	})
+	for _, channel := range []string{chirpEventsChannel, notificationsChannel} {
+		err := listener.Listen(channel)
+		if err != nil {
+			fmt.Println("listening for events:", err)
+			return
+		}
+	}
+
+	for {
+		select {
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established.
+			// Anything missed meanwhile reaches clients when they resume.
+			if n == nil {
+				continue
+			}
+			switch n.Channel {
+			case chirpEventsChannel:
+				id, err := strconv.ParseInt(n.Extra, 10, 64)
+				if err != nil {
+					continue
+				}
+				event, err := cfg.dbQueries.GetChirpEvent(context.Background(), id)
+				if err != nil {
+					fmt.Println("listening for events:", err)
+					continue
+				}
+				cfg.publishChirpEvents(context.Background(), []database.ChirpEvent{event})
+			case notificationsChannel:
+				id, err := uuid.Parse(n.Extra)
+				if err != nil {
+					continue
+				}
+				cfg.publishNotification(context.Background(), id)
+			}
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
+
+// handlerStream streams chirp events as Server-Sent Events, optionally
+// only those by author_id or tagged with tag. Clients that reconnect with
+// a Last-Event-ID header (or last_event_id parameter) first receive the
+// events they missed, as far back as the event log goes.
+//
+// Event ids come from a sequence, so an event can commit after one with a
+// higher id. Resuming therefore replays the streamResumeOverlap ids before
+// Last-Event-ID as well, and the connection drops repeats by id rather
+// than by comparing against the last id sent; clients may see a few
+// events again after reconnecting and should ignore ids they know.
+func (cfg *apiConfig) handlerStream(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		msg = "Streaming is not supported"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	authorID := uuid.Nil
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			msg = "Invalid author ID"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		authorID = id
+	}
+
+	tag := normalizeTag(r.URL.Query().Get("tag"))
+
+	lastID := int64(0)
+	resume := r.Header.Get("Last-Event-ID")
+	if resume == "" {
+		resume = r.URL.Query().Get("last_event_id")
+	}
+	if resume != "" {
+		id, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || id < 0 {
+			msg = "Invalid Last-Event-ID"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		lastID = id
+	}
+
+	// Signed-in users do not see users they have blocked, been blocked by
+	// or muted. Changes take effect on the next connection.
+	hidden := map[uuid.UUID]bool{}
+	if viewer := cfg.viewerID(r); viewer != uuid.Nil {
+		ids, err := cfg.dbQueries.GetHiddenAuthorIDs(r.Context(), viewer)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		for _, id := range ids {
+			hidden[id] = true
+		}
+	}
+
+	filter := func(ev stream.Event) bool {
+		if !streamable(ev) {
+			return false
+		}
+		if authorID != uuid.Nil && ev.UserID != authorID {
+			return false
+		}
+		if tag != "" && !slices.Contains(ev.Tags, tag) {
+			return false
+		}
+		return !hidden[ev.UserID]
+	}
+
+	// Subscribe before replaying so nothing published in between is lost.
+	sub := cfg.hub.Subscribe(filter)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(code)
+
+	sent := stream.NewWindow(streamDedupeWindow)
+	if lastID > 0 {
+		cursor := max(lastID-streamResumeOverlap, 0)
+		for {
+			events, err := cfg.dbQueries.GetChirpEventsAfter(r.Context(), database.GetChirpEventsAfterParams{
+				AfterID:   cursor,
+				BatchSize: streamReplayBatch,
+			})
+			if err != nil {
+				return
+			}
+			streamEvents, err := cfg.streamEvents(r.Context(), events)
+			if err != nil {
+				return
+			}
+			for _, ev := range streamEvents {
+				if !filter(ev) || !sent.Add(ev.ID) {
+					continue
+				}
+				if stream.WriteEvent(w, ev) != nil {
+					return
+				}
+			}
+			if len(events) > 0 {
+				cursor = events[len(events)-1].ID
+			}
+			if len(events) < streamReplayBatch {
+				break
+			}
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C:
+			// A closed channel means we fell too far behind; the client
+			// reconnects and resumes from the last id it saw.
+			if !ok {
+				return
+			}
+			if !sent.Add(ev.ID) {
+				continue
+			}
+			if stream.WriteEvent(w, ev) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if stream.WriteComment(w, "heartbeat") != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"server/internal/database"
+	"server/internal/subscription"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const subscriptionExpiryInterval = 5 * time.Minute
+
+var errSubscriberNotFound = errors.New("user not found")
+
+// applySubscriptionEvent moves a user's membership on by one event with q,
+// keeping is_chirpy_red in step and adding the event to the membership's
+// history. It reports whether the user has just joined Chirpy Red, so the
+// caller can tell them once its transaction commits.
+//
+// q must be bound to a transaction. The user's row is locked before the
+// membership is read, so events for the same user are applied one after
+// another, each seeing the state the last one left, even when the user has
+// no membership row yet to lock.
+func applySubscriptionEvent(ctx context.Context, q *database.Queries, userID uuid.UUID, event subscription.Event, periodEnd time.Time, polkaEventID string) (bool, error) {
+	now := time.Now()
+
+	_, err := q.LockUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, errSubscriberNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	current := subscription.State{}
+	sub, err := q.GetSubscriptionForUpdate(ctx, userID)
+	if err == nil {
+		current = subscription.State{Status: subscription.Status(sub.Status), PeriodEnd: sub.CurrentPeriodEnd.Time}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	next := subscription.Apply(current, event, periodEnd, now)
+	nextPeriodEnd := sql.NullTime{Time: next.PeriodEnd, Valid: !next.PeriodEnd.IsZero()}
+
+	n, err := q.SetChirpyRed(ctx, database.SetChirpyRedParams{
+		ID:          userID,
+		IsChirpyRed: next.Entitled(now),
+	})
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, errSubscriberNotFound
+	}
+
+	_, err = q.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		UserID:           userID,
+		Status:           string(next.Status),
+		CurrentPeriodEnd: nextPeriodEnd,
+	})
+	if err != nil {
+		return false, err
+	}
+	err = q.CreateSubscriptionEvent(ctx, database.CreateSubscriptionEventParams{
+		UserID:           userID,
+		Event:            string(event),
+		Status:           string(next.Status),
+		CurrentPeriodEnd: nextPeriodEnd,
+		PolkaEventID:     polkaEventID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return event == subscription.Upgraded && !current.Entitled(now), nil
+}
+
+// runSubscriptionExpiry ends memberships whose period ran out without a
+// renewal. ExpireSubscriptions updates the membership, the user and the
+// history in one statement, so instances running it at the same time never
+// expire a membership twice. Memberships with no end are never expired.
+//
+// Like applySubscriptionEvent it locks the user before the membership, so
+// a renewal arriving at the same moment waits for it or is waited for,
+// and a membership renewed meanwhile is no longer expired.
+func (cfg *apiConfig) runSubscriptionExpiry() {
+	ticker := time.NewTicker(subscriptionExpiryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_, err := cfg.dbQueries.ExpireSubscriptions(context.Background(), time.Now())
+		if err != nil {
+			fmt.Println("expiring subscriptions:", err)
+		}
+	}
+}
+
+func (cfg *apiConfig) handlerGetSubscription(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnSubscription{
+		Status:      "none",
+		IsChirpyRed: user.IsChirpyRed,
+		History:     []returnSubscriptionEvent{},
+	}
+
+	sub, err := cfg.dbQueries.GetSubscription(r.Context(), userID)
+	if err == nil {
+		respBody.Status = sub.Status
+		if sub.CurrentPeriodEnd.Valid {
+			respBody.CurrentPeriodEnd = &sub.CurrentPeriodEnd.Time
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	events, err := cfg.dbQueries.GetSubscriptionEvents(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	for _, event := range events {
+		respEvent := returnSubscriptionEvent{
+			Event:     event.Event,
+			Status:    event.Status,
+			CreatedAt: event.CreatedAt,
+		}
+		if event.CurrentPeriodEnd.Valid {
+			respEvent.CurrentPeriodEnd = &event.CurrentPeriodEnd.Time
+		}
+		respBody.History = append(respBody.History, respEvent)
+	}
+
+	respondWithJSON(w, code, respBody)
+}
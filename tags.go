@@ -0,0 +1,40 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hashtagPattern matches #tags: letters, digits and underscores after a
+// hash that does not follow another word character.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_#&])#([A-Za-z0-9_]{1,50})\b`)
+
+// hashtags returns the distinct tags in body, lowercased, in the order
+// they first appear.
+func hashtags(body string) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, m := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(m[1])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// tagPattern is what a tag looks like once normalized.
+var tagPattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// validTag reports whether a normalized tag could appear in a chirp. Tags
+// are also matched inside SQL patterns, so nothing else may get through.
+func validTag(tag string) bool {
+	return tagPattern.MatchString(tag)
+}
+
+// normalizeTag turns a tag from a query string into the form hashtags
+// returns.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"net/http"
+	"server/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func (cfg *apiConfig) handlerGetThread(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		msg = "Invalid chirp ID"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	viewer := cfg.viewerID(r)
+
+	// Tombstones still anchor a thread, so they are allowed here even
+	// though getVisibleChirp would refuse them. So is any other chirp the
+	// viewer may not see, such as one deleted but not yet purged, as long
+	// as a reply below it is visible; GetChirpDescendants keeps such
+	// replies in the tree the same way.
+	chirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		msg = "Chirp not found"
+		code = 404
+		respondWithError(w, code, msg)
+		return
+	}
+
+	visible, err := cfg.canViewChirp(r.Context(), viewer, chirp)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if !visible && !chirp.TombstonedAt.Valid {
+		replies, err := cfg.dbQueries.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+			ChirpID:   chirp.ID,
+			ViewerID:  viewer,
+			PageLimit: 1,
+		})
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		if len(replies) == 0 {
+			msg = "Chirp not found"
+			code = 404
+			respondWithError(w, code, msg)
+			return
+		}
+	}
+
+	ancestors, err := cfg.dbQueries.GetChirpAncestors(r.Context(), chirp.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, offset := paginationParams(r)
+
+	// Ask for one extra row so we know whether another page exists.
+	descendants, err := cfg.dbQueries.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+		ChirpID:    chirp.ID,
+		ViewerID:   viewer,
+		PageLimit:  limit + 1,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnThread{
+		Ancestors: []returnChirp{},
+		Replies:   []*returnThreadNode{},
+	}
+
+	if len(descendants) > int(limit) {
+		descendants = descendants[:limit]
+		next := offset + limit
+		respBody.NextOffset = &next
+	}
+
+	// Convert the whole thread in one batch: ancestors, then the chirp
+	// itself, then the page of descendants.
+	chirps := append(ancestors, chirp)
+	for _, d := range descendants {
+		chirps = append(chirps, d.Chirp)
+	}
+	respChirps, err := cfg.chirpResponses(r.Context(), viewer, chirps)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Ancestors the viewer may not see (e.g. held after an edit) are shown
+	// as tombstones so the chain stays intact.
+	for i, ancestor := range ancestors {
+		visible, err := cfg.canViewChirp(r.Context(), viewer, ancestor)
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		if !visible {
+			respChirps[i] = tombstoneResponse(ancestor)
+		}
+	}
+
+	respBody.Ancestors = append(respBody.Ancestors, respChirps[:len(ancestors)]...)
+	respBody.Chirp = respChirps[len(ancestors)]
+	if !visible {
+		respBody.Chirp = tombstoneResponse(chirp)
+	}
+
+	// Descendants arrive in depth-first order, so every parent on this page
+	// is seen before its replies. Replies the viewer may not see only come
+	// back when something below them is visible, and are shown as
+	// tombstones like ancestors. Replies whose parent fell on an earlier
+	// page are attached at the top level; their in_reply_to still says
+	// where they belong.
+	nodes := map[uuid.UUID]*returnThreadNode{}
+	for i, d := range descendants {
+		respChirp := respChirps[len(ancestors)+1+i]
+		if !d.Visible {
+			respChirp = tombstoneResponse(d.Chirp)
+		}
+		node := &returnThreadNode{
+			returnChirp: respChirp,
+			Depth:       d.Depth,
+			Replies:     []*returnThreadNode{},
+		}
+		nodes[d.Chirp.ID] = node
+
+		parent, ok := nodes[d.Chirp.InReplyTo.UUID]
+		if d.Chirp.InReplyTo.Valid && ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			respBody.Replies = append(respBody.Replies, node)
+		}
+	}
+
+	respondWithJSON(w, code, respBody)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestThreadOfDeletedChirpWithReplies(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	replier := createTestUser(t, cfg)
+	reader := createTestUser(t, cfg)
+
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	reply := createTestChirp(t, cfg, replier.ID, chirpInput{InReplyTo: &chirp.ID})
+	deleteChirp(t, cfg, author.ID, chirp)
+
+	r := testRequest(t, cfg, "GET", "/api/chirps/"+chirp.ID.String()+"/thread", reader.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	w := serve(t, cfg.handlerGetThread, r, 200)
+
+	var thread returnThread
+	err := json.NewDecoder(w.Body).Decode(&thread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thread.Chirp.Tombstone || thread.Chirp.Body != "" {
+		t.Errorf("deleted chirp shown as %+v, want a tombstone", thread.Chirp)
+	}
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != reply.ID {
+		t.Errorf("got %d replies, want the visible reply", len(thread.Replies))
+	}
+}
+
+func TestThreadOfDeletedChirpWithoutReplies(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+	deleteChirp(t, cfg, author.ID, chirp)
+
+	for _, viewer := range []uuid.UUID{author.ID, uuid.Nil} {
+		r := testRequest(t, cfg, "GET", "/api/chirps/"+chirp.ID.String()+"/thread", viewer, nil)
+		r.SetPathValue("chirpID", chirp.ID.String())
+		serve(t, cfg.handlerGetThread, r, 404)
+	}
+}
+
+// getThread fetches the thread around chirpID as viewer, with query
+// appended to the URL.
+func getThread(t *testing.T, cfg *apiConfig, viewer, chirpID uuid.UUID, query string) returnThread {
+	t.Helper()
+	r := testRequest(t, cfg, "GET", "/api/chirps/"+chirpID.String()+"/thread"+query, viewer, nil)
+	r.SetPathValue("chirpID", chirpID.String())
+	w := serve(t, cfg.handlerGetThread, r, 200)
+
+	var thread returnThread
+	err := json.NewDecoder(w.Body).Decode(&thread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return thread
+}
+
+func TestThreadShowsAncestorsAndReplyTree(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	replier := createTestUser(t, cfg)
+
+	root := createTestChirp(t, cfg, author.ID, chirpInput{})
+	middle := createTestChirp(t, cfg, replier.ID, chirpInput{InReplyTo: &root.ID})
+	leaf := createTestChirp(t, cfg, author.ID, chirpInput{InReplyTo: &middle.ID})
+	if middle.ConversationID != root.ID || leaf.ConversationID != root.ID {
+		t.Fatalf("got conversations %v and %v, want the root %v", middle.ConversationID, leaf.ConversationID, root.ID)
+	}
+
+	thread := getThread(t, cfg, replier.ID, middle.ID, "")
+	if len(thread.Ancestors) != 1 || thread.Ancestors[0].ID != root.ID {
+		t.Errorf("got ancestors %+v, want the root", thread.Ancestors)
+	}
+	if thread.Chirp.ID != middle.ID {
+		t.Errorf("got chirp %v, want %v", thread.Chirp.ID, middle.ID)
+	}
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != leaf.ID {
+		t.Fatalf("got replies %+v, want the leaf", thread.Replies)
+	}
+
+	thread = getThread(t, cfg, replier.ID, root.ID, "")
+	if len(thread.Ancestors) != 0 {
+		t.Errorf("root has ancestors %+v", thread.Ancestors)
+	}
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != middle.ID ||
+		len(thread.Replies[0].Replies) != 1 || thread.Replies[0].Replies[0].ID != leaf.ID {
+		t.Errorf("got reply tree %+v, want root > middle > leaf", thread.Replies)
+	}
+}
+
+func TestThreadRepliesArePaginated(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	root := createTestChirp(t, cfg, author.ID, chirpInput{})
+	for range 3 {
+		createTestChirp(t, cfg, author.ID, chirpInput{InReplyTo: &root.ID})
+	}
+
+	first := getThread(t, cfg, author.ID, root.ID, "?limit=2")
+	if len(first.Replies) != 2 || first.NextOffset == nil || *first.NextOffset != 2 {
+		t.Fatalf("first page: got %d replies, next offset %v", len(first.Replies), first.NextOffset)
+	}
+	second := getThread(t, cfg, author.ID, root.ID, "?limit=2&offset=2")
+	if len(second.Replies) != 1 || second.NextOffset != nil {
+		t.Fatalf("second page: got %d replies, next offset %v", len(second.Replies), second.NextOffset)
+	}
+	for _, reply := range first.Replies {
+		if reply.ID == second.Replies[0].ID {
+			t.Errorf("reply %v appears on both pages", reply.ID)
+		}
+	}
+}
+
+func TestReplyingToMissingChirpFails(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	missing := uuid.New()
+
+	_, err := cfg.createChirp(context.Background(), author.ID, chirpInput{Body: "hello", InReplyTo: &missing})
+	if requestErrorCode(err) != 404 {
+		t.Errorf("got %v, want a 404", err)
+	}
+}
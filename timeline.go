@@ -0,0 +1,154 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"server/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// timelineCursor marks the last entry of a timeline page. Entries are
+// ordered by (time, chirp ID, rechirper ID), with uuid.Nil standing in for
+// the rechirper of original chirps, so the three together are unique.
+type timelineCursor struct {
+	At          time.Time
+	ChirpID     uuid.UUID
+	RechirpedBy uuid.UUID
+}
+
+func (c timelineCursor) encode() string {
+	raw := c.At.UTC().Format(time.RFC3339Nano) + "|" + c.ChirpID.String() + "|" + c.RechirpedBy.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTimelineCursor(s string) (timelineCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return timelineCursor{}, err
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return timelineCursor{}, errors.New("malformed cursor")
+	}
+
+	c := timelineCursor{}
+	c.At, err = time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return timelineCursor{}, err
+	}
+	c.ChirpID, err = uuid.Parse(parts[1])
+	if err != nil {
+		return timelineCursor{}, err
+	}
+	c.RechirpedBy, err = uuid.Parse(parts[2])
+	if err != nil {
+		return timelineCursor{}, err
+	}
+	return c, nil
+}
+
+// handlerGetTimeline returns the viewer's home timeline: their own chirps
+// and those of everyone they follow, plus chirps those users rechirped,
+// newest first. It is assembled at read time from the follow graph.
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	limit, _ := paginationParams(r)
+
+	args := database.GetTimelineEntriesParams{
+		ViewerID:  userID,
+		PageLimit: limit + 1,
+	}
+
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		cursor, err := decodeTimelineCursor(s)
+		if err != nil {
+			msg = "Invalid cursor"
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		args.BeforeAt = sql.NullTime{Time: cursor.At, Valid: true}
+		args.BeforeChirpID = uuid.NullUUID{UUID: cursor.ChirpID, Valid: true}
+		args.BeforeRechirpedBy = uuid.NullUUID{UUID: cursor.RechirpedBy, Valid: true}
+	}
+
+	// Ask for one extra entry so we know whether another page exists.
+	entries, err := cfg.dbQueries.GetTimelineEntries(r.Context(), args)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnTimeline{
+		Entries: []returnTimelineEntry{},
+	}
+
+	if len(entries) > int(limit) {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		respBody.NextCursor = timelineCursor{
+			At:          last.SortAt,
+			ChirpID:     last.ChirpID,
+			RechirpedBy: last.RechirpedBy,
+		}.encode()
+	}
+
+	ids := []uuid.UUID{}
+	for _, entry := range entries {
+		ids = append(ids, entry.ChirpID)
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsByIDs(r.Context(), ids)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respChirps, err := cfg.chirpResponses(r.Context(), userID, chirps)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	chirpsByID := map[uuid.UUID]returnChirp{}
+	for _, respChirp := range respChirps {
+		chirpsByID[respChirp.ID] = respChirp
+	}
+
+	for _, entry := range entries {
+		respChirp, ok := chirpsByID[entry.ChirpID]
+		if !ok {
+			// Deleted between the two queries.
+			continue
+		}
+		respEntry := returnTimelineEntry{Chirp: respChirp}
+		if entry.RechirpedBy != uuid.Nil {
+			respEntry.RechirpedBy = &entry.RechirpedBy
+			respEntry.RechirpedAt = &entry.SortAt
+		}
+		respBody.Entries = append(respBody.Entries, respEntry)
+	}
+
+	respondWithJSON(w, code, respBody)
+}
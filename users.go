@@ -1,14 +1,13 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"server/internal/auth"
 	"server/internal/database"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +31,7 @@ func (cfg *apiConfig) handlerMakeUser(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 	msg := ""
 	code := 201
@@ -46,6 +46,13 @@ func (cfg *apiConfig) handlerMakeUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if params.Handle != "" && !validHandle(params.Handle) {
+		msg = "Handle must be 3-30 letters, digits or underscores"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
 	hash, err := auth.HashPassword(params.Password)
 	if err != nil {
 		msg = "Something went wrong with password"
@@ -57,9 +64,16 @@ func (cfg *apiConfig) handlerMakeUser(w http.ResponseWriter, r *http.Request) {
 	args := database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hash,
+		Handle:         sql.NullString{String: params.Handle, Valid: params.Handle != ""},
 	}
 
 	user, err := cfg.dbQueries.CreateUser(r.Context(), args)
+	if isUniqueViolation(err) {
+		msg = "Email or handle already taken"
+		code = 409
+		respondWithError(w, code, msg)
+		return
+	}
 	if err != nil {
 		msg = "Something went wrong"
 		code = 500
@@ -67,13 +81,7 @@ func (cfg *apiConfig) handlerMakeUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respBody := returnUser{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
-	}
+	respBody := privateUser(user)
 
 	data, _ := json.Marshal(respBody)
 
@@ -146,15 +154,9 @@ func (cfg *apiConfig) handlerUserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respBody := returnUser{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		Token:        jwtToken,
-		RefreshToken: refresh_token,
-		IsChirpyRed:  user.IsChirpyRed,
-	}
+	respBody := privateUser(user)
+	respBody.Token = jwtToken
+	respBody.RefreshToken = refresh_token
 
 	data, _ := json.Marshal(respBody)
 
@@ -218,13 +220,7 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	respBody := returnUser{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
-	}
+	respBody := privateUser(user)
 
 	data, _ := json.Marshal(respBody)
 
@@ -233,54 +229,3 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, r *http.Request)
 	w.Write(data)
 
 }
-
-func (cfg *apiConfig) handlerRedUser(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
-	}
-	msg := ""
-	code := 204
-
-	apiKey, err := auth.GetAPIKey(r.Header)
-	if err != nil || apiKey != cfg.polkaKey {
-		code = 401
-		respondWithError(w, code, msg)
-		return
-	}
-
-	decoder := json.NewDecoder(r.Body)
-	params := parameters{}
-	err = decoder.Decode(&params)
-	if err != nil {
-		msg = "Something went wrong"
-		code = 500
-		respondWithError(w, code, msg)
-		return
-	}
-
-	if params.Event != "user.upgraded" {
-		respondWithError(w, code, msg)
-		return
-	}
-
-	id, err := uuid.Parse(params.Data.UserID)
-	if err != nil {
-		msg = "Something went wrong"
-		code = 500
-		respondWithError(w, code, msg)
-		return
-	}
-
-	err = cfg.dbQueries.RedChirpyUser(r.Context(), id)
-	if err != nil {
-		code = 404
-		respondWithError(w, code, msg)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-}
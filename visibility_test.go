@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// listAuthorChirps returns the IDs of author's chirps that viewer sees in
+// GET /api/chirps?author_id=.
+func listAuthorChirps(t *testing.T, cfg *apiConfig, viewer, author uuid.UUID) map[uuid.UUID]bool {
+	t.Helper()
+	return listChirps(t, cfg, viewer, "?author_id="+author.String())
+}
+
+// listChirps returns the IDs of the chirps viewer sees in GET /api/chirps
+// with query.
+func listChirps(t *testing.T, cfg *apiConfig, viewer uuid.UUID, query string) map[uuid.UUID]bool {
+	t.Helper()
+	r := testRequest(t, cfg, "GET", "/api/chirps"+query, viewer, nil)
+	w := serve(t, cfg.handlerGetChirps, r, 200)
+
+	var chirps []returnChirp
+	err := json.NewDecoder(w.Body).Decode(&chirps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[uuid.UUID]bool{}
+	for _, chirp := range chirps {
+		ids[chirp.ID] = true
+	}
+	return ids
+}
+
+func TestChirpVisibility(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	follower := createTestUser(t, cfg)
+	stranger := createTestUser(t, cfg)
+	if w := followRequest(t, cfg, follower.ID, author.ID); w.Code != 200 {
+		t.Fatalf("following: got status %d, want 200", w.Code)
+	}
+
+	public := createTestChirp(t, cfg, author.ID, chirpInput{Visibility: visibilityPublic})
+	followers := createTestChirp(t, cfg, author.ID, chirpInput{Visibility: visibilityFollowers})
+	unlisted := createTestChirp(t, cfg, author.ID, chirpInput{Visibility: visibilityUnlisted})
+
+	tests := []struct {
+		name   string
+		viewer uuid.UUID
+		// fetch is the chirps the viewer can open by ID; list is those
+		// shown when listing the author's chirps.
+		fetch []uuid.UUID
+		list  []uuid.UUID
+	}{
+		{"author", author.ID, []uuid.UUID{public.ID, followers.ID, unlisted.ID}, []uuid.UUID{public.ID, followers.ID, unlisted.ID}},
+		{"follower", follower.ID, []uuid.UUID{public.ID, followers.ID, unlisted.ID}, []uuid.UUID{public.ID, followers.ID}},
+		{"stranger", stranger.ID, []uuid.UUID{public.ID, unlisted.ID}, []uuid.UUID{public.ID}},
+		{"anonymous", uuid.Nil, []uuid.UUID{public.ID, unlisted.ID}, []uuid.UUID{public.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, id := range []uuid.UUID{public.ID, followers.ID, unlisted.ID} {
+				want := 404
+				for _, visible := range tt.fetch {
+					if visible == id {
+						want = 200
+					}
+				}
+				r := testRequest(t, cfg, "GET", "/api/chirps/"+id.String(), tt.viewer, nil)
+				r.SetPathValue("chirpID", id.String())
+				serve(t, cfg.handlerGetChirp, r, want)
+			}
+
+			listed := listAuthorChirps(t, cfg, tt.viewer, author.ID)
+			if len(listed) != len(tt.list) {
+				t.Errorf("listed %d chirps, want %d", len(listed), len(tt.list))
+			}
+			for _, id := range tt.list {
+				if !listed[id] {
+					t.Errorf("chirp %v not listed", id)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownVisibilityIsRefused(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+
+	r := testRequest(t, cfg, "POST", "/api/chirps", author.ID, map[string]any{"body": "hi", "visibility": "friends"})
+	serve(t, cfg.handlerPostChirp, r, 400)
+}
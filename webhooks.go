@@ -0,0 +1,710 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"server/internal/database"
+	"server/internal/netguard"
+	"server/internal/webhook"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxWebhookEndpoints    = 10
+	minWebhookSecretLength = 16
+
+	webhookPollInterval = 5 * time.Second
+	webhookBatchSize    = 20
+	webhookTimeout      = 10 * time.Second
+	// webhookLease is how long a claimed delivery is left alone before it
+	// is due again, in case the worker that claimed it died. It must
+	// outlast an attempt.
+	webhookLease = time.Minute
+
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusDead      = "dead"
+
+	// webhookEventVerify is sent only by handlerVerifyWebhook; endpoints
+	// cannot subscribe to it.
+	webhookEventVerify = "webhook.verify"
+)
+
+// webhookEvents are the events endpoints can subscribe to.
+var webhookEvents = []string{chirpEventCreated, chirpEventUpdated, chirpEventDeleted}
+
+// queueWebhooks queues a delivery of a chirp event to each of the author's
+// endpoints that subscribe to it, using q so that a transaction can queue
+// deliveries along with the change they report. The payload carries the
+// chirp as its author sees it. Events are queued when the author
+// publishes, edits, deletes or restores a chirp, and when an edit holds or
+// releases it; moderation sends nothing. It reports whether anything was
+// queued, so the caller can wake the worker once the deliveries are
+// committed.
+func (cfg *apiConfig) queueWebhooks(ctx context.Context, q *database.Queries, typ string, chirp database.Chirp) (bool, error) {
+	if chirp.Status != chirpStatusPublished {
+		return false, nil
+	}
+	count, err := q.CountUserWebhookEndpoints(ctx, chirp.UserID)
+	if err != nil || count == 0 {
+		return false, err
+	}
+
+	respChirps, err := cfg.chirpResponsesWith(ctx, q, chirp.UserID, []database.Chirp{chirp})
+	if err != nil {
+		return false, err
+	}
+	eventID := uuid.New()
+	payload, err := json.Marshal(returnWebhookEvent{
+		ID:        eventID,
+		Event:     typ,
+		CreatedAt: time.Now().UTC(),
+		Data:      respChirps[0],
+	})
+	if err != nil {
+		return false, err
+	}
+
+	queued, err := q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID: eventID,
+		Event:   typ,
+		Payload: string(payload),
+		UserID:  chirp.UserID,
+	})
+	return queued > 0, err
+}
+
+// enqueueWebhooks is queueWebhooks outside a transaction, for changes
+// whose webhooks may be lost if queueing fails.
+func (cfg *apiConfig) enqueueWebhooks(ctx context.Context, typ string, chirp database.Chirp) {
+	queued, err := cfg.queueWebhooks(ctx, cfg.dbQueries, typ, chirp)
+	if err != nil {
+		fmt.Println("queueing webhooks:", err)
+		return
+	}
+	if queued {
+		cfg.wakeWebhookWorker()
+	}
+}
+
+// wakeWebhookWorker has this instance's worker look for due deliveries
+// now rather than at its next poll.
+func (cfg *apiConfig) wakeWebhookWorker() {
+	select {
+	case cfg.webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// runWebhookDeliveries sends queued deliveries as they fall due. Every
+// server instance runs one; ClaimDueWebhookDeliveries locks rows with FOR
+// UPDATE SKIP LOCKED and leases them in the same statement, so each
+// attempt is made by one instance.
+func (cfg *apiConfig) runWebhookDeliveries() {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-cfg.webhookWake:
+		}
+		cfg.deliverDueWebhooks(context.Background())
+	}
+}
+
+func (cfg *apiConfig) deliverDueWebhooks(ctx context.Context) {
+	for {
+		deliveries, err := cfg.dbQueries.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+			LeaseUntil: time.Now().Add(webhookLease),
+			BatchSize:  webhookBatchSize,
+		})
+		if err != nil {
+			fmt.Println("delivering webhooks:", err)
+			return
+		}
+
+		// One slow endpoint should not hold up the rest of the batch.
+		wg := sync.WaitGroup{}
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cfg.attemptWebhookDelivery(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attemptWebhookDelivery makes one attempt at a claimed delivery and logs
+// it. Failed deliveries are retried with exponential backoff until they
+// run out of attempts and are marked dead.
+func (cfg *apiConfig) attemptWebhookDelivery(ctx context.Context, delivery database.WebhookDelivery) {
+	endpoint, err := cfg.dbQueries.GetWebhookEndpoint(ctx, delivery.EndpointID)
+	if err != nil {
+		// The delivery goes when its endpoint is deleted; anything else
+		// is retried once the lease runs out.
+		if !errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("delivering webhooks:", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	result, sendErr := cfg.webhookClient.Send(ctx, webhook.Delivery{
+		ID:     delivery.ID.String(),
+		URL:    endpoint.Url,
+		Secret: endpoint.Secret,
+		Event:  delivery.Event,
+		Body:   []byte(delivery.Payload),
+	}, now)
+	errText := ""
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+	// Until its owner has shown they control the endpoint, what it answers
+	// could be anyone's, so only the status code is kept.
+	responseBody := ""
+	if endpoint.VerifiedAt.Valid {
+		responseBody = result.Body
+	}
+
+	err = cfg.dbQueries.RecordWebhookAttempt(ctx, database.RecordWebhookAttemptParams{
+		DeliveryID:   delivery.ID,
+		Attempt:      delivery.Attempts,
+		StatusCode:   int32(result.StatusCode),
+		ResponseBody: responseBody,
+		Error:        errText,
+		DurationMs:   int32(result.Duration.Milliseconds()),
+	})
+	if err != nil {
+		fmt.Println("logging webhook attempt:", err)
+	}
+
+	finish := database.FinishWebhookAttemptParams{
+		ID:             delivery.ID,
+		Status:         webhookStatusDelivered,
+		NextAttemptAt:  now,
+		LastStatusCode: int32(result.StatusCode),
+		LastError:      errText,
+	}
+	if sendErr != nil || !result.OK() {
+		finish.Status = webhookStatusPending
+		finish.NextAttemptAt = now.Add(webhook.Backoff(int(delivery.Attempts)))
+		if delivery.Attempts >= webhook.MaxAttempts {
+			finish.Status = webhookStatusDead
+		}
+	}
+	err = cfg.dbQueries.FinishWebhookAttempt(ctx, finish)
+	if err != nil {
+		fmt.Println("delivering webhooks:", err)
+	}
+}
+
+func webhookEndpointResponse(endpoint database.WebhookEndpoint) returnWebhookEndpoint {
+	respEndpoint := returnWebhookEndpoint{
+		ID:        endpoint.ID,
+		CreatedAt: endpoint.CreatedAt,
+		UpdatedAt: endpoint.UpdatedAt,
+		URL:       endpoint.Url,
+		Events:    endpoint.Events,
+	}
+	if endpoint.VerifiedAt.Valid {
+		respEndpoint.VerifiedAt = &endpoint.VerifiedAt.Time
+	}
+	return respEndpoint
+}
+
+// checkWebhookURL refuses endpoint URLs that are not https or whose host
+// resolves to a private address. The delivery client checks the address
+// again when it connects, in case the name has been repointed since.
+func (cfg *apiConfig) checkWebhookURL(ctx context.Context, rawURL string) error {
+	if !cfg.allowedRemoteURL(rawURL) {
+		return &requestError{code: 400, msg: "URL must be an absolute https URL"}
+	}
+	if cfg.platform == "dev" {
+		return nil
+	}
+	u, _ := url.Parse(rawURL)
+	err := netguard.CheckHost(ctx, u.Hostname())
+	if errors.Is(err, netguard.ErrForbiddenAddress) {
+		return &requestError{code: 400, msg: "URL must point to a public address"}
+	}
+	if err != nil {
+		return &requestError{code: 400, msg: "URL host could not be resolved"}
+	}
+	return nil
+}
+
+// webhookToken returns 32 random bytes, hex encoded.
+func webhookToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func webhookDeliveryResponse(delivery database.WebhookDelivery) returnWebhookDelivery {
+	respDelivery := returnWebhookDelivery{
+		ID:             delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		EventID:        delivery.EventID,
+		Event:          delivery.Event,
+		Status:         delivery.Status,
+		AttemptCount:   delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+	}
+	if delivery.Status == webhookStatusPending {
+		respDelivery.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		respDelivery.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	if delivery.RedeliveryOf.Valid {
+		respDelivery.RedeliveryOf = &delivery.RedeliveryOf.UUID
+	}
+	return respDelivery
+}
+
+// ownWebhookEndpoint loads the endpoint named in the path, as long as it
+// belongs to userID.
+func (cfg *apiConfig) ownWebhookEndpoint(r *http.Request, userID uuid.UUID) (database.WebhookEndpoint, error) {
+	notFound := &requestError{code: 404, msg: "Webhook not found"}
+	id, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		return database.WebhookEndpoint{}, notFound
+	}
+	endpoint, err := cfg.dbQueries.GetWebhookEndpoint(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && endpoint.UserID != userID) {
+		return database.WebhookEndpoint{}, notFound
+	}
+	return endpoint, err
+}
+
+// ownWebhookDelivery loads the delivery named in the path, as long as it
+// was made to one of userID's endpoints.
+func (cfg *apiConfig) ownWebhookDelivery(r *http.Request, userID uuid.UUID) (database.WebhookDelivery, error) {
+	endpoint, err := cfg.ownWebhookEndpoint(r, userID)
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+	notFound := &requestError{code: 404, msg: "Delivery not found"}
+	id, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		return database.WebhookDelivery{}, notFound
+	}
+	delivery, err := cfg.dbQueries.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{
+		ID:         id,
+		EndpointID: endpoint.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.WebhookDelivery{}, notFound
+	}
+	return delivery, err
+}
+
+// handlerCreateWebhook registers an endpoint for some of the user's chirp
+// events. The secret deliveries are signed with is generated when none is
+// given, and only ever returned here.
+func (cfg *apiConfig) handlerCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+	msg := ""
+	code := 201
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		msg = "Invalid request body"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	err = cfg.checkWebhookURL(r.Context(), params.URL)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	events := []string{}
+	for _, event := range params.Events {
+		if !slices.Contains(webhookEvents, event) {
+			msg = "Unknown event " + event
+			code = 400
+			respondWithError(w, code, msg)
+			return
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		msg = "At least one event is required"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	secret := params.Secret
+	if secret == "" {
+		token, err := webhookToken()
+		if err != nil {
+			msg = "Something went wrong"
+			code = 500
+			respondWithError(w, code, msg)
+			return
+		}
+		secret = "whsec_" + token
+	}
+	if len(secret) < minWebhookSecretLength {
+		msg = fmt.Sprintf("Secret must be at least %d characters", minWebhookSecretLength)
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+
+	count, err := cfg.dbQueries.CountUserWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	if count >= maxWebhookEndpoints {
+		msg = fmt.Sprintf("You can register at most %d webhooks", maxWebhookEndpoints)
+		code = 409
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoint, err := cfg.dbQueries.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID: userID,
+		Url:    params.URL,
+		Events: events,
+		Secret: secret,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := webhookEndpointResponse(endpoint)
+	respBody.Secret = endpoint.Secret
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoints, err := cfg.dbQueries.GetUserWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnWebhookEndpoint{}
+	for _, endpoint := range endpoints {
+		respBody = append(respBody, webhookEndpointResponse(endpoint))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+func (cfg *apiConfig) handlerGetWebhook(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhookEndpoint(r, userID)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	respondWithJSON(w, code, webhookEndpointResponse(endpoint))
+}
+
+// handlerVerifyWebhook sends the endpoint a signed webhook.verify event
+// carrying a random challenge. The endpoint is marked verified if it
+// answers with a 2xx status and the challenge as its body. Nothing the
+// endpoint sends back is shown to the caller.
+func (cfg *apiConfig) handlerVerifyWebhook(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhookEndpoint(r, userID)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+	if endpoint.VerifiedAt.Valid {
+		respondWithJSON(w, code, webhookEndpointResponse(endpoint))
+		return
+	}
+
+	challenge, err := webhookToken()
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	payload, err := json.Marshal(map[string]string{
+		"event":     webhookEventVerify,
+		"challenge": challenge,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	result, err := cfg.webhookClient.Send(r.Context(), webhook.Delivery{
+		ID:     uuid.NewString(),
+		URL:    endpoint.Url,
+		Secret: endpoint.Secret,
+		Event:  webhookEventVerify,
+		Body:   payload,
+	}, time.Now())
+	if err != nil || !result.OK() || strings.TrimSpace(result.Body) != challenge {
+		msg = "Endpoint did not answer with the challenge"
+		code = 422
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoint, err = cfg.dbQueries.VerifyWebhookEndpoint(r.Context(), endpoint.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respondWithJSON(w, code, webhookEndpointResponse(endpoint))
+}
+
+// handlerDeleteWebhook removes an endpoint along with its queued
+// deliveries and their logs.
+func (cfg *apiConfig) handlerDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 204
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhookEndpoint(r, userID)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	_, err = cfg.dbQueries.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+		ID:     endpoint.ID,
+		UserID: userID,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	w.WriteHeader(code)
+}
+
+// handlerGetWebhookDeliveries lists an endpoint's deliveries, newest
+// first, optionally only those with the given status.
+func (cfg *apiConfig) handlerGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	endpoint, err := cfg.ownWebhookEndpoint(r, userID)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != webhookStatusPending && status != webhookStatusDelivered && status != webhookStatusDead {
+		msg = "status must be pending, delivered or dead"
+		code = 400
+		respondWithError(w, code, msg)
+		return
+	}
+	limit, offset := paginationParams(r)
+
+	deliveries, err := cfg.dbQueries.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		Status:     status,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := []returnWebhookDelivery{}
+	for _, delivery := range deliveries {
+		respBody = append(respBody, webhookDeliveryResponse(delivery))
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+// handlerGetWebhookDelivery returns a delivery with its payload and the
+// log of every attempt at it.
+func (cfg *apiConfig) handlerGetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	delivery, err := cfg.ownWebhookDelivery(r, userID)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	attempts, err := cfg.dbQueries.GetWebhookAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	respBody := returnWebhookDeliveryDetail{
+		returnWebhookDelivery: webhookDeliveryResponse(delivery),
+		Payload:               json.RawMessage(delivery.Payload),
+		Attempts:              []returnWebhookAttempt{},
+	}
+	for _, attempt := range attempts {
+		respBody.Attempts = append(respBody.Attempts, returnWebhookAttempt{
+			Attempt:      attempt.Attempt,
+			CreatedAt:    attempt.CreatedAt,
+			StatusCode:   attempt.StatusCode,
+			ResponseBody: attempt.ResponseBody,
+			Error:        attempt.Error,
+			DurationMs:   attempt.DurationMs,
+		})
+	}
+
+	respondWithJSON(w, code, respBody)
+}
+
+// handlerRedeliverWebhook queues a fresh copy of a delivery, with the same
+// event id and payload, to be sent straight away. It works whatever the
+// original's status, so dead deliveries can be revived once the endpoint
+// is fixed.
+func (cfg *apiConfig) handlerRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 202
+
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	delivery, err := cfg.ownWebhookDelivery(r, userID)
+	if err != nil {
+		respondWithRequestError(w, err)
+		return
+	}
+
+	redelivery, err := cfg.dbQueries.RedeliverWebhookDelivery(r.Context(), delivery.ID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+	cfg.wakeWebhookWorker()
+
+	respondWithJSON(w, code, webhookDeliveryResponse(redelivery))
+}
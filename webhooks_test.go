@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"server/internal/database"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChirpWebhookCarriesItsMedia(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	endpoint := createTestWebhookEndpoint(t, cfg, author.ID)
+	ctx := context.Background()
+
+	media, err := cfg.dbQueries.CreateMediaFile(ctx, database.CreateMediaFileParams{
+		ID:          uuid.New(),
+		UserID:      author.ID,
+		ContentType: "image/png",
+		SizeBytes:   1,
+		Width:       1,
+		Height:      1,
+		StorageKey:  "test.png",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{MediaIDs: []uuid.UUID{media.ID}})
+
+	deliveries, err := cfg.dbQueries.GetWebhookDeliveries(ctx, database.GetWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		PageLimit:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+
+	var event returnWebhookEvent
+	err = json.Unmarshal([]byte(deliveries[0].Payload), &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Event != chirpEventCreated || event.Data.ID != chirp.ID {
+		t.Fatalf("got %s for %v, want %s for %v", event.Event, event.Data.ID, chirpEventCreated, chirp.ID)
+	}
+	if len(event.Data.Media) != 1 || event.Data.Media[0].ID != media.ID {
+		t.Errorf("got media %+v, want the attached image", event.Data.Media)
+	}
+}
+
+func TestDeletingChirpQueuesWebhook(t *testing.T) {
+	cfg := newTestConfig(t)
+	author := createTestUser(t, cfg)
+	endpoint := createTestWebhookEndpoint(t, cfg, author.ID)
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+
+	deleteChirp(t, cfg, author.ID, chirp)
+
+	queued := webhookEventsQueued(t, cfg, endpoint)
+	if len(queued) != 2 || queued[0] != chirpEventCreated || queued[1] != chirpEventDeleted {
+		t.Errorf("got webhooks %v, want [%s %s]", queued, chirpEventCreated, chirpEventDeleted)
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"server/internal/auth"
+	"server/internal/database"
+	"server/internal/stream"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelThread        = "thread"
+	wsChannelNotifications = "notifications"
+
+	wsReadLimit     = 4096
+	wsWriteWait     = 10 * time.Second
+	wsPingInterval  = 30 * time.Second
+	wsIdleTimeout   = 75 * time.Second
+	wsCloseWait     = 5 * time.Second
+	wsTokenWarning  = time.Minute
+	wsMaxThreadSubs = 50
+
+	// Application close codes. Clients should reconnect after
+	// wsCloseTooSlow, and fetch a fresh token first after
+	// wsCloseTokenExpired.
+	wsCloseTokenExpired = 4001
+	wsCloseTooSlow      = 4008
+)
+
+// wsUpgrader accepts WebSockets from any origin. Clients authenticate with
+// an access token rather than a cookie, so a page on another site cannot
+// open a connection on a visitor's behalf.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsMessage is the JSON shape of every WebSocket message, in both
+// directions. Fields that do not apply to a message type are left out.
+type wsMessage struct {
+	Type      string          `json:"type"`
+	Channel   string          `json:"channel,omitempty"`
+	ChirpID   *uuid.UUID      `json:"chirp_id,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Token     string          `json:"token,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// wsSession is one authenticated WebSocket connection and what it is
+// subscribed to. The hubs consult the subscriptions from their own
+// goroutines, so they are guarded by mu.
+type wsSession struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+
+	mu            sync.Mutex
+	timeline      bool
+	following     map[uuid.UUID]bool
+	threads       map[uuid.UUID]bool
+	notifications bool
+	hidden        map[uuid.UUID]bool
+}
+
+// wantsChirpEvent reports whether ev concerns the timeline or one of the
+// threads the session follows. Whether the user may actually see the
+// chirp is checked when it is sent.
+func (s *wsSession) wantsChirpEvent(ev stream.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.channelsFor(ev)) > 0
+}
+
+// channelsFor must be called with s.mu held. Rechirps only go to the
+// timeline, when the user or someone they follow made them, as on
+// GET /api/timeline.
+func (s *wsSession) channelsFor(ev stream.Event) []string {
+	if s.hidden[ev.UserID] {
+		return nil
+	}
+	channels := []string{}
+	if ev.Type == chirpEventRechirped {
+		by := ev.RechirpedBy
+		if s.timeline && !s.hidden[by] && (by == s.userID || s.following[by]) {
+			channels = append(channels, wsChannelTimeline)
+		}
+		return channels
+	}
+	if s.timeline && ev.Visibility != visibilityUnlisted && (ev.UserID == s.userID || s.following[ev.UserID]) {
+		channels = append(channels, wsChannelTimeline)
+	}
+	if s.threads[ev.ConversationID] {
+		channels = append(channels, wsChannelThread)
+	}
+	return channels
+}
+
+func (s *wsSession) wantsNotification(ev stream.Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notifications && ev.UserID == s.userID
+}
+
+func (s *wsSession) send(msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (s *wsSession) sendError(text string) error {
+	return s.send(wsMessage{Type: "error", Message: text})
+}
+
+// subscribe handles subscribe and unsubscribe messages. Errors the client
+// caused are reported to it; only failures to write end the session.
+func (s *wsSession) subscribe(ctx context.Context, msg wsMessage) error {
+	on := msg.Type == "subscribe"
+	reply := wsMessage{Type: "unsubscribed", Channel: msg.Channel}
+	if on {
+		reply.Type = "subscribed"
+	}
+
+	switch msg.Channel {
+	case wsChannelTimeline:
+		following := map[uuid.UUID]bool{}
+		if on {
+			// Follows made or ended later take effect on the next
+			// subscribe.
+			ids, err := s.cfg.dbQueries.GetFollowingIDs(ctx, s.userID)
+			if err != nil {
+				return s.sendError("Something went wrong")
+			}
+			for _, id := range ids {
+				following[id] = true
+			}
+		}
+		s.mu.Lock()
+		s.timeline = on
+		s.following = following
+		s.mu.Unlock()
+	case wsChannelThread:
+		if msg.ChirpID == nil {
+			return s.sendError("chirp_id is required")
+		}
+		// Any chirp in a thread subscribes to the whole thread, which is
+		// identified by its root chirp.
+		chirp, err := s.cfg.getVisibleChirp(ctx, s.userID, *msg.ChirpID)
+		if err != nil {
+			return s.sendError("Chirp not found")
+		}
+		root := chirp.ConversationID
+		s.mu.Lock()
+		full := on && !s.threads[root] && len(s.threads) >= wsMaxThreadSubs
+		if !full {
+			if on {
+				s.threads[root] = true
+			} else {
+				delete(s.threads, root)
+			}
+		}
+		s.mu.Unlock()
+		if full {
+			return s.sendError("Too many thread subscriptions")
+		}
+		reply.ChirpID = &root
+	case wsChannelNotifications:
+		s.mu.Lock()
+		s.notifications = on
+		s.mu.Unlock()
+	default:
+		return s.sendError("Unknown channel")
+	}
+
+	return s.send(reply)
+}
+
+// sendChirpEvent passes a chirp event on to each matching channel. Created
+// and updated chirps are rendered for this user, and skipped if they may
+// not see them. Rechirps are sent as timeline entries.
+func (s *wsSession) sendChirpEvent(ctx context.Context, ev stream.Event) error {
+	s.mu.Lock()
+	channels := s.channelsFor(ev)
+	s.mu.Unlock()
+	if len(channels) == 0 {
+		return nil
+	}
+
+	data := ev.Data
+	if ev.Type != chirpEventDeleted {
+		chirp, err := s.cfg.getVisibleChirp(ctx, s.userID, ev.ChirpID)
+		if err != nil {
+			return nil
+		}
+		respChirps, err := s.cfg.chirpResponses(ctx, s.userID, []database.Chirp{chirp})
+		if err != nil {
+			return nil
+		}
+		var payload any = respChirps[0]
+		if ev.Type == chirpEventRechirped {
+			payload = returnTimelineEntry{
+				Chirp:       respChirps[0],
+				RechirpedBy: &ev.RechirpedBy,
+				RechirpedAt: &ev.CreatedAt,
+			}
+		}
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, channel := range channels {
+		msg := wsMessage{Type: "event", Channel: channel, Event: ev.Type, Data: data}
+		if channel == wsChannelThread {
+			msg.ChirpID = &ev.ConversationID
+		}
+		err := s.send(msg)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close starts the closing handshake and waits briefly for the client to
+// answer it.
+func (s *wsSession) close(code int, text string, readErr <-chan error) {
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteWait))
+	select {
+	case <-readErr:
+	case <-time.After(wsCloseWait):
+	}
+}
+
+// handlerWebSocket upgrades to a WebSocket that pushes timeline, thread and
+// notification events as they happen. Browsers cannot set headers on
+// WebSocket requests, so the access token may also be passed as the
+// access_token query parameter. The connection lasts as long as the token:
+// clients are warned a minute before it expires and can send a fresh one in
+// an auth message, or are disconnected with close code 4001.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	msg := ""
+	code := 200
+
+	token := r.URL.Query().Get("access_token")
+	if token == "" {
+		token, _ = auth.GetBearerToken(r.Header)
+	}
+	userID, expiresAt, err := auth.ParseJWT(token, cfg.secret)
+	if err != nil {
+		msg = "Unauthorized"
+		code = 401
+		respondWithError(w, code, msg)
+		return
+	}
+
+	hiddenIDs, err := cfg.dbQueries.GetHiddenAuthorIDs(r.Context(), userID)
+	if err != nil {
+		msg = "Something went wrong"
+		code = 500
+		respondWithError(w, code, msg)
+		return
+	}
+
+	// Upgrade responds with an error itself if the handshake is invalid.
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsReadLimit)
+
+	// Reads fail once the client has sent nothing, not even a pong, for
+	// wsIdleTimeout. Pinging every wsPingInterval keeps a healthy
+	// connection open.
+	conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+	})
+
+	// Blocks and mutes made later take effect on the next connection, as
+	// with the event stream.
+	s := &wsSession{
+		cfg:     cfg,
+		conn:    conn,
+		userID:  userID,
+		threads: map[uuid.UUID]bool{},
+		hidden:  map[uuid.UUID]bool{},
+	}
+	for _, id := range hiddenIDs {
+		s.hidden[id] = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	incoming := make(chan wsMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(wsIdleTimeout))
+			msg := wsMessage{}
+			if json.Unmarshal(data, &msg) != nil {
+				msg = wsMessage{Type: "invalid"}
+			}
+			select {
+			case incoming <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	chirpSub := cfg.hub.Subscribe(s.wantsChirpEvent)
+	defer chirpSub.Close()
+	notificationSub := cfg.notificationHub.Subscribe(s.wantsNotification)
+	defer notificationSub.Close()
+
+	expiry := time.NewTimer(time.Until(expiresAt))
+	defer expiry.Stop()
+	warning := time.NewTimer(time.Until(expiresAt.Add(-wsTokenWarning)))
+	defer warning.Stop()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		err = nil
+		select {
+		case <-readErr:
+			return
+		case m := <-incoming:
+			switch m.Type {
+			case "subscribe", "unsubscribe":
+				err = s.subscribe(ctx, m)
+			case "auth":
+				newUserID, newExpiresAt, parseErr := auth.ParseJWT(m.Token, cfg.secret)
+				if parseErr != nil || newUserID != userID {
+					err = s.sendError("Invalid token")
+					break
+				}
+				expiresAt = newExpiresAt
+				expiry.Reset(time.Until(expiresAt))
+				warning.Reset(time.Until(expiresAt.Add(-wsTokenWarning)))
+				err = s.send(wsMessage{Type: "authenticated", ExpiresAt: &expiresAt})
+			default:
+				err = s.sendError("Unknown message type")
+			}
+		case ev, ok := <-chirpSub.C:
+			// The hub drops subscribers that fall too far behind rather
+			// than letting them hold everyone else up.
+			if !ok {
+				s.close(wsCloseTooSlow, "too slow", readErr)
+				return
+			}
+			err = s.sendChirpEvent(ctx, ev)
+		case ev, ok := <-notificationSub.C:
+			if !ok {
+				s.close(wsCloseTooSlow, "too slow", readErr)
+				return
+			}
+			err = s.send(wsMessage{Type: "event", Channel: wsChannelNotifications, Event: ev.Type, Data: ev.Data})
+		case <-warning.C:
+			err = s.send(wsMessage{Type: "token_expiring", ExpiresAt: &expiresAt})
+		case <-expiry.C:
+			s.close(wsCloseTokenExpired, "token expired", readErr)
+			return
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		}
+		// A failed write means the client is gone or cannot keep up.
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"server/internal/auth"
+	"server/internal/stream"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// dialWebSocket connects userID to handlerWebSocket on a test server.
+func dialWebSocket(t *testing.T, cfg *apiConfig, userID uuid.UUID) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(cfg.handlerWebSocket))
+	t.Cleanup(server.Close)
+
+	token, err := auth.MakeJWT(userID, cfg.secret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws?access_token=" + token
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readWSMessage waits briefly for the next message on conn.
+func readWSMessage(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	msg := wsMessage{}
+	err := conn.ReadJSON(&msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func subscribeWS(t *testing.T, conn *websocket.Conn, channel string) {
+	t.Helper()
+	err := conn.WriteJSON(wsMessage{Type: "subscribe", Channel: channel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := readWSMessage(t, conn); msg.Type != "subscribed" || msg.Channel != channel {
+		t.Fatalf("got %+v, want a subscribed reply", msg)
+	}
+}
+
+func TestWebSocketTimelineReceivesFollowedChirps(t *testing.T) {
+	cfg := newTestConfig(t)
+	reader := createTestUser(t, cfg)
+	author := createTestUser(t, cfg)
+	if w := followRequest(t, cfg, reader.ID, author.ID); w.Code != 200 {
+		t.Fatalf("following: got status %d, want 200", w.Code)
+	}
+
+	conn := dialWebSocket(t, cfg, reader.ID)
+	subscribeWS(t, conn, wsChannelTimeline)
+
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+
+	msg := readWSMessage(t, conn)
+	if msg.Type != "event" || msg.Channel != wsChannelTimeline || msg.Event != chirpEventCreated {
+		t.Fatalf("got %+v, want a chirp.created timeline event", msg)
+	}
+	if !strings.Contains(string(msg.Data), chirp.ID.String()) {
+		t.Errorf("event data %s does not carry the chirp", msg.Data)
+	}
+}
+
+func TestWebSocketRejectsUnknownMessages(t *testing.T) {
+	cfg := newTestConfig(t)
+	user := createTestUser(t, cfg)
+	conn := dialWebSocket(t, cfg, user.ID)
+
+	err := conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := readWSMessage(t, conn); msg.Type != "error" {
+		t.Fatalf("got %+v, want an error", msg)
+	}
+
+	// The session carries on after a bad message.
+	subscribeWS(t, conn, wsChannelNotifications)
+}
+
+func TestWebSocketNeedsToken(t *testing.T) {
+	cfg := &apiConfig{secret: "test-secret"}
+	r := httptest.NewRequest("GET", "/api/ws", nil)
+	serve(t, cfg.handlerWebSocket, r, 401)
+}
+
+func TestWebSocketTimelineReceivesRechirps(t *testing.T) {
+	cfg := newTestConfig(t)
+	reader := createTestUser(t, cfg)
+	rechirper := createTestUser(t, cfg)
+	author := createTestUser(t, cfg)
+	if w := followRequest(t, cfg, reader.ID, rechirper.ID); w.Code != 200 {
+		t.Fatalf("following: got status %d, want 200", w.Code)
+	}
+	chirp := createTestChirp(t, cfg, author.ID, chirpInput{})
+
+	conn := dialWebSocket(t, cfg, reader.ID)
+	subscribeWS(t, conn, wsChannelTimeline)
+
+	r := testRequest(t, cfg, "POST", "/api/chirps/"+chirp.ID.String()+"/rechirp", rechirper.ID, nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	serve(t, cfg.handlerRechirp, r, 204)
+
+	msg := readWSMessage(t, conn)
+	if msg.Type != "event" || msg.Channel != wsChannelTimeline || msg.Event != chirpEventRechirped {
+		t.Fatalf("got %+v, want a chirp.rechirped timeline event", msg)
+	}
+	var entry returnTimelineEntry
+	err := json.Unmarshal(msg.Data, &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Chirp.ID != chirp.ID || entry.RechirpedBy == nil || *entry.RechirpedBy != rechirper.ID {
+		t.Errorf("got entry %+v, want %v rechirped by %v", entry, chirp.ID, rechirper.ID)
+	}
+}
+
+func TestWebSocketRechirpChannels(t *testing.T) {
+	viewer, followed, stranger, muted := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	s := &wsSession{
+		userID:    viewer,
+		timeline:  true,
+		following: map[uuid.UUID]bool{followed: true, muted: true},
+		threads:   map[uuid.UUID]bool{},
+		hidden:    map[uuid.UUID]bool{muted: true},
+	}
+	rechirp := func(by uuid.UUID) stream.Event {
+		return stream.Event{Type: chirpEventRechirped, UserID: stranger, RechirpedBy: by, Visibility: visibilityPublic}
+	}
+
+	for _, by := range []uuid.UUID{viewer, followed} {
+		if got := s.channelsFor(rechirp(by)); len(got) != 1 || got[0] != wsChannelTimeline {
+			t.Errorf("rechirp by %v: got channels %v, want the timeline", by, got)
+		}
+	}
+	for _, by := range []uuid.UUID{stranger, muted} {
+		if got := s.channelsFor(rechirp(by)); len(got) != 0 {
+			t.Errorf("rechirp by %v: got channels %v, want none", by, got)
+		}
+	}
+
+	// Rechirps are not replies, so thread subscribers do not get them.
+	ev := rechirp(followed)
+	ev.ConversationID = uuid.New()
+	s.threads[ev.ConversationID] = true
+	s.timeline = false
+	if got := s.channelsFor(ev); len(got) != 0 {
+		t.Errorf("rechirp with only a thread subscription: got channels %v", got)
+	}
+}